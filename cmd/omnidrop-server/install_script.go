@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"omnidrop/internal/scripts"
+)
+
+// runInstallScript handles "omnidrop-server install-script [-dir DIR]", writing the scripts
+// embedded in this binary to the directory the production server loads them from
+func runInstallScript(args []string) error {
+	flags := flag.NewFlagSet("install-script", flag.ContinueOnError)
+	dir := flags.String("dir", defaultScriptDir(), "directory to install the scripts into")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths, err := scripts.Install(*dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		script, err := scripts.Lookup(filepath.Base(path))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s (version %s)\n", path, script.Version)
+	}
+	return nil
+}
+
+func defaultScriptDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(homeDir, ".local", "share", "omnidrop")
+}
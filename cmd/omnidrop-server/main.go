@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 
@@ -13,6 +14,15 @@ var (
 )
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "install-script" {
+		if err := runInstallScript(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "install-script: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Logger is configured inside application.Run() (first thing it does),
 	// so any error returned below logs through the configured slog default.
 	application := app.NewWithVersion(Version, BuildTime)
@@ -0,0 +1,10 @@
+// Package omnidrop embeds the OmniFocus scripts that ship with the server, so the binary can
+// install them and notice installed copies that do not match
+package omnidrop
+
+import "embed"
+
+// Scripts holds omnidrop.applescript, omnidrop.js and omnidrop-query.applescript
+//
+//go:embed omnidrop.applescript omnidrop.js omnidrop-query.applescript
+var Scripts embed.FS
@@ -2,20 +2,26 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"omnidrop/internal/auth"
 	"omnidrop/internal/config"
 	"omnidrop/internal/handlers"
+	"omnidrop/internal/idempotency"
 	"omnidrop/internal/middleware"
 	"omnidrop/internal/observability"
+	"omnidrop/internal/outbox"
+	"omnidrop/internal/scripts"
 	"omnidrop/internal/server"
 	"omnidrop/internal/services"
+	"omnidrop/internal/templates"
 )
 
 // Application manages the complete application lifecycle
@@ -23,6 +29,9 @@ type Application struct {
 	config           *config.Config
 	healthService    services.HealthService
 	omniFocusService services.OmniFocusServiceInterface
+	taskBackends     *services.TaskBackendRegistry
+	outbox           *outbox.Outbox
+	healthMonitor    *healthMonitor // nil when OMNIDROP_HEALTH_CHECK_INTERVAL is 0
 	server           *server.Server
 	logger           *slog.Logger
 	version          string
@@ -51,7 +60,9 @@ func (a *Application) Run() error {
 	a.displayStartupInfo()
 
 	// Perform health checks
-	a.performHealthChecks()
+	if err := a.performHealthChecks(); err != nil {
+		return err
+	}
 
 	// Start the server
 	return a.startAndWait()
@@ -73,19 +84,23 @@ func (a *Application) initialize() error {
 	var legacyAuthMiddleware *middleware.LegacyAuthMiddleware
 	var tokenHandler *auth.TokenHandler
 
+	oauthClients := services.ComponentDisabled
 	if cfg.JWTSecret != "" {
 		// Initialize OAuth client repository
 		oauthRepo, err = auth.NewRepository(cfg.OAuthClientsFile)
 		if err != nil {
+			oauthClients = services.ComponentFailed
 			a.logger.Warn("Failed to initialize OAuth repository",
 				slog.String("error", err.Error()),
 				slog.String("clients_file", cfg.OAuthClientsFile))
 		} else {
+			oauthClients = services.ComponentLoaded
+
 			// Initialize JWT manager
 			jwtManager = auth.NewJWTManager(cfg.JWTSecret)
 
 			// Initialize OAuth middleware (hybrid legacy fallback is wired from config)
-			authMiddleware = auth.NewMiddleware(jwtManager, a.logger, cfg.LegacyAuthEnabled, cfg.Token)
+			authMiddleware = auth.NewMiddleware(jwtManager, oauthRepo, a.logger, cfg.LegacyAuthEnabled, cfg.Token)
 
 			// Initialize token handler
 			tokenHandler = auth.NewTokenHandler(oauthRepo, jwtManager, cfg.TokenExpiry, a.logger)
@@ -109,14 +124,87 @@ func (a *Application) initialize() error {
 		return config.ErrNoAuthConfigured
 	}
 
-	// Initialize services
-	a.healthService = services.NewHealthService(cfg)
-	a.omniFocusService = services.NewOmniFocusService(cfg)
+	// Initialize services. OmniFocus scripts go through a bounded pool; the health check
+	// only talks to System Events and must keep answering while the pool is saturated.
+	executor := &services.DefaultAppleScriptExecutor{}
+	pooledExecutor := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{
+		MaxConcurrent: cfg.AppleScriptConcurrency,
+		MaxQueued:     cfg.AppleScriptQueueSize,
+		QueueTimeout:  cfg.AppleScriptQueueTimeout,
+	})
+	a.healthService = services.NewHealthServiceWithExecutor(cfg, executor)
+	if cfg.HealthCheckInterval > 0 {
+		a.healthMonitor = newHealthMonitor(a.healthService, cfg.HealthCheckInterval, a.logger)
+	}
+
+	// The circuit breaker sits in front of the pool so requests fail fast instead of queueing while OmniFocus is hung
+	var omniFocusExecutor services.AppleScriptExecutor = pooledExecutor
+	if cfg.CircuitFailureThreshold > 0 {
+		omniFocusExecutor = services.NewCircuitBreakerExecutor(pooledExecutor, services.CircuitBreakerOptions{
+			FailureThreshold: cfg.CircuitFailureThreshold,
+			Cooldown:         cfg.CircuitCooldown,
+			Probe:            a.healthService.CheckOmniFocusStatus,
+		})
+	}
+	a.omniFocusService = services.NewOmniFocusServiceWithExecutor(cfg, omniFocusExecutor)
 	filesService := services.NewFilesService(cfg)
+	a.taskBackends = a.buildTaskBackends(cfg, filesService)
+
+	// Initialize the durable task queue (failed deliveries are retried in the background)
+	var taskQueue services.TaskQueue
+	readinessOpts := services.ReadinessOptions{
+		TTL:          cfg.HealthCacheTTL,
+		OAuthClients: oauthClients,
+		Queue:        services.ComponentDisabled,
+		TaskBackends: a.taskBackends,
+	}
+	if oauthRepo != nil {
+		readinessOpts.ClientBackends = oauthRepo.Backends
+	}
+	if cfg.QueueEnabled {
+		a.outbox, err = outbox.New(cfg.QueueDir, a.taskBackends, outbox.Options{
+			MaxAttempts: cfg.QueueMaxAttempts,
+		}, a.logger)
+		if err != nil {
+			readinessOpts.Queue = services.ComponentFailed
+			a.logger.Warn("Failed to initialize task queue; failed tasks will not be retried",
+				slog.String("error", err.Error()),
+				slog.String("queue_dir", cfg.QueueDir))
+		} else {
+			taskQueue = a.outbox
+			readinessOpts.Queue = services.ComponentEnabled
+			readinessOpts.QueueStats = func() services.QueueStats {
+				stats := a.outbox.Stats()
+				return services.QueueStats{Depth: stats.Depth, OldestAge: stats.OldestAge}
+			}
+		}
+	}
+
+	// Initialize task templates (the file is reloaded whenever it changes)
+	templateRepo, err := templates.NewRepository(cfg.TemplatesFile)
+	if err != nil {
+		a.logger.Warn("Failed to load task templates; template endpoint disabled",
+			slog.String("error", err.Error()),
+			slog.String("templates_file", cfg.TemplatesFile))
+	}
 
 	// Initialize handlers and server
-	h := handlers.New(a.version, a.omniFocusService, filesService)
-	srv, err := server.NewServer(cfg, h, authMiddleware, legacyAuthMiddleware, tokenHandler, a.logger)
+	readiness := services.NewReadinessService(cfg, a.healthService, readinessOpts)
+	h := handlers.New(cfg, a.version, a.taskBackends, filesService, taskQueue, templateRepo, readiness)
+	// Initialize Idempotency-Key support for task and file creation
+	var idempotencyMiddleware *idempotency.Middleware
+	if cfg.IdempotencyTTL > 0 {
+		store, err := idempotency.NewStore(cfg.IdempotencyDir, cfg.IdempotencyTTL)
+		if err != nil {
+			a.logger.Warn("Failed to initialize idempotency store; Idempotency-Key will be ignored",
+				slog.String("error", err.Error()),
+				slog.String("idempotency_dir", cfg.IdempotencyDir))
+		} else {
+			idempotencyMiddleware = idempotency.NewMiddleware(store, a.logger)
+		}
+	}
+
+	srv, err := server.NewServer(cfg, h, authMiddleware, legacyAuthMiddleware, tokenHandler, idempotencyMiddleware, a.logger)
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
@@ -125,6 +213,48 @@ func (a *Application) initialize() error {
 	return nil
 }
 
+// buildTaskBackends registers every configured task backend. OmniFocus and the file
+// backends are always available; CalDAV and the webhook need a URL.
+func (a *Application) buildTaskBackends(cfg *config.Config, files *services.FilesService) *services.TaskBackendRegistry {
+	defaultBackend := cfg.TaskBackend
+	backends := services.NewTaskBackendRegistry(defaultBackend)
+	backends.Register(services.BackendOmniFocus, a.omniFocusService)
+
+	fileBackends := map[string]string{
+		services.BackendMarkdown: cfg.MarkdownTasksFile,
+		services.BackendTodoTxt:  cfg.TodoTxtFile,
+	}
+	for name, file := range fileBackends {
+		backend, err := services.NewFileTaskBackend(files, name, file)
+		if err != nil {
+			a.logger.Warn("Failed to initialize file task backend",
+				slog.String("backend", name),
+				slog.String("error", err.Error()))
+			continue
+		}
+		backends.Register(name, backend)
+	}
+
+	if cfg.CalDAVURL != "" {
+		backends.Register(services.BackendCalDAV, services.NewCalDAVTaskBackend(cfg.CalDAVURL, cfg.CalDAVUsername, cfg.CalDAVPassword, nil))
+	}
+	if cfg.WebhookURL != "" {
+		backends.Register(services.BackendWebhook, services.NewWebhookTaskBackend(cfg.WebhookURL, cfg.WebhookSecret, nil))
+	}
+
+	if !backends.Has(defaultBackend) {
+		a.logger.Warn("Unknown or unconfigured OMNIDROP_TASK_BACKEND; defaulting to omnifocus",
+			slog.String("value", defaultBackend),
+			slog.Any("available", backends.Names()))
+		backends.SetDefault(services.BackendOmniFocus)
+	}
+
+	a.logger.Info("✅ Task backends initialized",
+		slog.String("default", backends.Default()),
+		slog.Any("available", backends.Names()))
+	return backends
+}
+
 // displayStartupInfo shows application startup information
 func (a *Application) displayStartupInfo() {
 	a.logger.Info("🚀 OmniDrop Server starting",
@@ -136,8 +266,9 @@ func (a *Application) displayStartupInfo() {
 	)
 }
 
-// performHealthChecks runs system health checks
-func (a *Application) performHealthChecks() {
+// performHealthChecks runs system health checks. It only fails when OMNIDROP_SCRIPT_CHECK=strict
+// and an installed script does not match the copy embedded in the binary.
+func (a *Application) performHealthChecks() error {
 	healthResult := a.healthService.CheckAppleScriptHealth()
 	if !healthResult.AppleScriptAccessible {
 		a.logger.Warn("⚠️ AppleScript health check failed",
@@ -145,10 +276,72 @@ func (a *Application) performHealthChecks() {
 	} else {
 		a.logger.Info("✅ AppleScript health check passed")
 	}
+	if a.healthMonitor != nil {
+		a.healthMonitor.Observe(healthResult)
+	}
+
+	return a.checkScriptDrift()
+}
+
+// checkScriptDrift compares the installed task and query scripts with the embedded copies
+func (a *Application) checkScriptDrift() error {
+	if a.config.ScriptCheck == config.ScriptCheckOff {
+		return nil
+	}
+
+	targets := []struct {
+		name    string
+		resolve func() (string, error)
+	}{
+		{a.config.TaskScriptFile(), a.config.GetAppleScriptPath},
+		{a.config.QueryScriptFile, a.config.GetQueryScriptPath},
+	}
+
+	var problems []string
+	for _, target := range targets {
+		path, err := target.resolve()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", target.name, err))
+			continue
+		}
+		drift, err := scripts.Check(target.name, path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", target.name, err))
+			continue
+		}
+		if !drift.Matches() {
+			problems = append(problems, drift.String())
+			continue
+		}
+		a.logger.Info("✅ Installed script matches the server",
+			slog.String("script_path", path),
+			slog.String("version", drift.EmbeddedVersion))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	for _, problem := range problems {
+		a.logger.Warn("⚠️ Installed script does not match the server", slog.String("problem", problem))
+	}
+	if a.config.ScriptCheck == config.ScriptCheckStrict {
+		return fmt.Errorf("installed scripts do not match the server (run 'omnidrop-server install-script'): %s",
+			strings.Join(problems, "; "))
+	}
+	a.logger.Warn("⚠️ Run 'omnidrop-server install-script' to install the scripts bundled with this server")
+	return nil
 }
 
 // startAndWait starts the server and waits for shutdown signals
 func (a *Application) startAndWait() error {
+	// Start the task queue worker
+	if a.outbox != nil {
+		a.outbox.Start()
+	}
+	if a.healthMonitor != nil {
+		a.healthMonitor.Start()
+	}
+
 	// Start server in goroutine
 	serverErr := make(chan error, 1)
 	go func() {
@@ -177,12 +370,33 @@ func (a *Application) shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Every component is stopped even if an earlier one fails
+	var errs []error
+
 	// Shutdown server
 	if err := a.server.Shutdown(ctx); err != nil {
 		a.logger.Error("Error during server shutdown", slog.String("error", err.Error()))
-		return err
+		errs = append(errs, err)
 	}
 
+	// Drain the task queue worker after the server stops accepting new tasks
+	if a.outbox != nil {
+		if err := a.outbox.Shutdown(ctx); err != nil {
+			a.logger.Error("Error during task queue shutdown", slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+
+	if a.healthMonitor != nil {
+		if err := a.healthMonitor.Shutdown(ctx); err != nil {
+			a.logger.Error("Error during health monitor shutdown", slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 	a.logger.Info("✅ Application gracefully stopped")
 	return nil
 }
@@ -2,10 +2,14 @@ package app
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"omnidrop/internal/config"
 	"omnidrop/internal/observability"
+	"omnidrop/internal/scripts"
 )
 
 func TestNewWithVersion(t *testing.T) {
@@ -32,6 +36,9 @@ func TestApplication_Initialize(t *testing.T) {
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -53,6 +60,10 @@ func TestApplication_Initialize(t *testing.T) {
 		t.Error("OmniFocus service not initialized")
 	}
 
+	if app.outbox == nil {
+		t.Error("Task queue not initialized")
+	}
+
 	if app.server == nil {
 		t.Error("Server not initialized")
 	}
@@ -62,6 +73,9 @@ func TestApplication_Initialize_ConfigError(t *testing.T) {
 	// Clear TOKEN environment variable to trigger config error
 	// Enable legacy auth to make TOKEN required
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 	t.Setenv("TOKEN", "")
 
 	app := NewWithVersion("dev", "unknown")
@@ -79,6 +93,9 @@ func TestApplication_GetMethods(t *testing.T) {
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -108,6 +125,9 @@ func TestApplication_DisplayStartupInfo(t *testing.T) {
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("1.0.0", "2025-09-15")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -127,6 +147,9 @@ func TestApplication_PerformHealthChecks(t *testing.T) {
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -140,12 +163,58 @@ func TestApplication_PerformHealthChecks(t *testing.T) {
 	app.performHealthChecks()
 }
 
+func TestApplication_PerformHealthChecks_ScriptDrift(t *testing.T) {
+	scriptDir := t.TempDir()
+	if _, err := scripts.Install(scriptDir); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+	scriptPath := filepath.Join(scriptDir, "omnidrop.applescript")
+
+	t.Setenv("TOKEN", "test-token")
+	t.Setenv("PORT", "8788")
+	t.Setenv("OMNIDROP_ENV", "test")
+	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
+	t.Setenv("OMNIDROP_SCRIPT", scriptPath)
+	t.Setenv("OMNIDROP_SCRIPT_CHECK", "strict")
+
+	app := NewWithVersion("dev", "unknown")
+	app.logger = observability.SetupLogger()
+	if err := app.initialize(); err != nil {
+		t.Fatalf("initialize() failed: %v", err)
+	}
+
+	// Freshly installed scripts match the binary
+	if err := app.performHealthChecks(); err != nil {
+		t.Errorf("performHealthChecks() failed for matching scripts: %v", err)
+	}
+
+	// An outdated script refuses startup in strict mode and only warns otherwise
+	if err := os.WriteFile(scriptPath, []byte("-- omnidrop-script-version: 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := app.performHealthChecks()
+	if err == nil || !strings.Contains(err.Error(), "install-script") {
+		t.Errorf("Expected strict script check to fail with an install hint, got %v", err)
+	}
+
+	app.config.ScriptCheck = config.ScriptCheckWarn
+	if err := app.performHealthChecks(); err != nil {
+		t.Errorf("Expected warn mode to start anyway, got %v", err)
+	}
+}
+
 func TestApplication_Shutdown(t *testing.T) {
 	// Set required environment variables for testing
 	t.Setenv("TOKEN", "test-token")
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -169,6 +238,9 @@ func TestApplication_Lifecycle(t *testing.T) {
 	t.Setenv("PORT", "8788")
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 	app.logger = observability.SetupLogger() // Setup logger before initialize
@@ -204,6 +276,9 @@ func TestApplication_RunBasicFlow(t *testing.T) {
 	t.Setenv("PORT", "8789") // Use different port to avoid conflicts
 	t.Setenv("OMNIDROP_ENV", "test")
 	t.Setenv("OMNIDROP_LEGACY_AUTH_ENABLED", "true")
+	t.Setenv("OMNIDROP_QUEUE_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_IDEMPOTENCY_DIR", t.TempDir())
+	t.Setenv("OMNIDROP_TEMPLATES_FILE", filepath.Join(t.TempDir(), "task-templates.yaml"))
 
 	app := NewWithVersion("dev", "unknown")
 
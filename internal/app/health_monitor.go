@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"omnidrop/internal/observability"
+	"omnidrop/internal/services"
+)
+
+// healthMonitor re-runs the AppleScript health check in the background, exporting the result
+// as Prometheus gauges and logging when AppleScript or OmniFocus become unavailable or recover
+type healthMonitor struct {
+	health   services.HealthService
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last *services.HealthResult // nil until the first check
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newHealthMonitor(health services.HealthService, interval time.Duration, logger *slog.Logger) *healthMonitor {
+	return &healthMonitor{
+		health:   health,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Start launches the monitor goroutine; the first check runs after one interval
+func (m *healthMonitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Observe(m.health.CheckAppleScriptHealth())
+			}
+		}
+	}()
+
+	m.logger.Info("🩺 Health monitor started", slog.Duration("interval", m.interval))
+}
+
+// Shutdown stops the monitor, waiting for a running check to finish
+func (m *healthMonitor) Shutdown(ctx context.Context) error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe records a health check result, exporting it and logging state transitions
+func (m *healthMonitor) Observe(result services.HealthResult) {
+	observability.AppleScriptAccessible.Set(boolGauge(result.AppleScriptAccessible))
+	observability.OmniFocusUp.Set(boolGauge(result.OmniFocusRunning))
+	observability.HealthLastCheckTimestamp.Set(float64(m.now().Unix()))
+
+	m.mu.Lock()
+	previous := m.last
+	m.last = &result
+	m.mu.Unlock()
+
+	if previous == nil {
+		return
+	}
+	m.logTransition("AppleScript", previous.AppleScriptAccessible, result.AppleScriptAccessible, result.Errors)
+	m.logTransition("OmniFocus", previous.OmniFocusRunning, result.OmniFocusRunning, result.Errors)
+}
+
+func (m *healthMonitor) logTransition(component string, was, is bool, errors []string) {
+	switch {
+	case was && !is:
+		m.logger.Warn("⚠️ Health check: component became unavailable",
+			slog.String("component", component),
+			slog.Any("errors", errors))
+	case !was && is:
+		m.logger.Info("✅ Health check: component recovered", slog.String("component", component))
+	}
+}
+
+func boolGauge(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
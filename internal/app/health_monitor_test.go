@@ -0,0 +1,84 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"omnidrop/internal/observability"
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+func TestHealthMonitor_ObserveExportsGauges(t *testing.T) {
+	monitor := newHealthMonitor(&mocks.MockHealthService{}, time.Minute, observability.SetupLogger())
+	checkedAt := time.Unix(1700000000, 0)
+	monitor.now = func() time.Time { return checkedAt }
+
+	monitor.Observe(services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: false})
+
+	if got := testutil.ToFloat64(observability.AppleScriptAccessible); got != 1 {
+		t.Errorf("applescript_accessible = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(observability.OmniFocusUp); got != 0 {
+		t.Errorf("omnifocus_up = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(observability.HealthLastCheckTimestamp); got != float64(checkedAt.Unix()) {
+		t.Errorf("last check timestamp = %v, want %d", got, checkedAt.Unix())
+	}
+}
+
+func TestHealthMonitor_LogsTransitions(t *testing.T) {
+	var logs bytes.Buffer
+	monitor := newHealthMonitor(&mocks.MockHealthService{}, time.Minute, slog.New(slog.NewTextHandler(&logs, nil)))
+	up := services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: true}
+	down := services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: false}
+
+	monitor.Observe(up)
+	monitor.Observe(up)
+	if logs.Len() != 0 {
+		t.Errorf("Expected no log without a transition, got %s", logs.String())
+	}
+
+	monitor.Observe(down)
+	if !strings.Contains(logs.String(), "became unavailable") || !strings.Contains(logs.String(), "component=OmniFocus") {
+		t.Errorf("Expected OmniFocus outage to be logged, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "component=AppleScript") {
+		t.Errorf("Expected no AppleScript transition, got %s", logs.String())
+	}
+
+	logs.Reset()
+	monitor.Observe(up)
+	if !strings.Contains(logs.String(), "recovered") || !strings.Contains(logs.String(), "component=OmniFocus") {
+		t.Errorf("Expected OmniFocus recovery to be logged, got %s", logs.String())
+	}
+}
+
+func TestHealthMonitor_StartAndShutdown(t *testing.T) {
+	health := &mocks.MockHealthService{Result: services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: true}}
+	monitor := newHealthMonitor(health, 5*time.Millisecond, observability.SetupLogger())
+
+	monitor.Start()
+	deadline := time.Now().Add(time.Second)
+	for health.CheckCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := monitor.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() failed: %v", err)
+	}
+
+	checks := health.CheckCount()
+	if checks < 2 {
+		t.Fatalf("Expected periodic checks, got %d", checks)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if health.CheckCount() != checks {
+		t.Error("Expected no checks after Shutdown")
+	}
+}
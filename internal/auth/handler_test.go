@@ -454,6 +454,9 @@ func marshalOAuthConfig(config OAuthConfig) []byte {
 		if client.Disabled {
 			b.WriteString("    disabled: true\n")
 		}
+		if client.Backend != "" {
+			b.WriteString("    backend: " + client.Backend + "\n")
+		}
 	}
 	return []byte(b.String())
 }
@@ -137,6 +137,21 @@ func TestJWTManager_GenerateToken(t *testing.T) {
 
 		assert.NotEqual(t, jti1, jti2, "each token should have unique JTI")
 	})
+
+	t.Run("leaves the client's policy out of the token", func(t *testing.T) {
+		clientWithPolicy := newTestOAuthClient("files-client", []string{"files:write"})
+		clientWithPolicy.Backend = "markdown"
+		clientWithPolicy.Files = FilesPolicy{Namespace: true, MaxBytes: 10 << 20, MaxFiles: 500}
+
+		tokenString, err := jm.GenerateToken(clientWithPolicy, 1*time.Hour)
+		require.NoError(t, err)
+		token, _ := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return jm.secret, nil
+		})
+		mapClaims := token.Claims.(jwt.MapClaims)
+		assert.NotContains(t, mapClaims, "backend", "the backend is looked up per request")
+		assert.NotContains(t, mapClaims, "files", "the files policy is looked up per request")
+	})
 }
 
 func TestJWTManager_ValidateToken(t *testing.T) {
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/subtle"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -13,6 +14,7 @@ import (
 // Middleware provides OAuth authentication middleware
 type Middleware struct {
 	jwtManager        *JWTManager
+	clients           *Repository // optional; nil leaves OAuth clients without a backend or files policy
 	logger            *slog.Logger
 	legacyAuthEnabled bool
 	legacyToken       string
@@ -20,14 +22,16 @@ type Middleware struct {
 
 // NewMiddleware creates a new OAuth middleware. Legacy hybrid mode (accept
 // both OAuth tokens and a fixed legacy bearer token) is activated only when
-// the caller passes legacyEnabled=true with a non-empty legacyToken.
-func NewMiddleware(jwtManager *JWTManager, logger *slog.Logger, legacyEnabled bool, legacyToken string) *Middleware {
+// the caller passes legacyEnabled=true with a non-empty legacyToken. The backend and files
+// policy of OAuth clients are looked up in clients on every request.
+func NewMiddleware(jwtManager *JWTManager, clients *Repository, logger *slog.Logger, legacyEnabled bool, legacyToken string) *Middleware {
 	if legacyEnabled && legacyToken != "" {
 		logger.Warn("Legacy authentication is enabled - this should only be used during migration")
 	}
 
 	return &Middleware{
 		jwtManager:        jwtManager,
+		clients:           clients,
 		logger:            logger,
 		legacyAuthEnabled: legacyEnabled,
 		legacyToken:       legacyToken,
@@ -56,6 +60,9 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 
 		// Try OAuth JWT authentication first
 		claims, err := m.jwtManager.ValidateToken(tokenString)
+		if err == nil {
+			err = m.applyClientPolicy(claims)
+		}
 		if err == nil {
 			// Valid OAuth token
 			observability.TokenValidationTotal.WithLabelValues("success").Inc()
@@ -97,6 +104,22 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// applyClientPolicy sets the backend and files policy of the token's client from its current
+// configuration, so a change to the clients file applies to tokens already issued. A token of
+// a client that was removed or disabled since it was issued is refused.
+func (m *Middleware) applyClientPolicy(claims *Claims) error {
+	if m.clients == nil {
+		return nil
+	}
+	client, err := m.clients.GetByClientID(claims.ClientID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	claims.Backend = client.Backend
+	claims.Files = client.Files
+	return nil
+}
+
 // RequireScopes creates a middleware that requires specific scopes
 func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
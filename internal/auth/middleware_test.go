@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,7 +47,7 @@ func TestNewMiddleware(t *testing.T) {
 			jm := newTestJWTManager()
 			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
 
-			m := NewMiddleware(jm, logger, tt.legacyEnabled, tt.legacyToken)
+			m := NewMiddleware(jm, nil, logger, tt.legacyEnabled, tt.legacyToken)
 
 			assert.NotNil(t, m)
 			assert.NotNil(t, m.jwtManager)
@@ -65,7 +67,7 @@ func TestNewMiddleware(t *testing.T) {
 func TestMiddleware_Authenticate_OAuth(t *testing.T) {
 	jm := newTestJWTManager()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-	m := NewMiddleware(jm, logger, false, "")
+	m := NewMiddleware(jm, nil, logger, false, "")
 
 	client := newTestOAuthClient("test-client", []string{"tasks:write", "files:read"})
 	validToken := generateValidToken(t, jm, client)
@@ -155,7 +157,7 @@ func TestMiddleware_Authenticate_OAuth(t *testing.T) {
 func TestMiddleware_Authenticate_ExpiredToken(t *testing.T) {
 	jm := newTestJWTManager()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-	m := NewMiddleware(jm, logger, false, "")
+	m := NewMiddleware(jm, nil, logger, false, "")
 
 	client := newTestOAuthClient("test-client", []string{"tasks:write"})
 	expiredToken := generateExpiredToken(t, jm, client)
@@ -174,6 +176,97 @@ func TestMiddleware_Authenticate_ExpiredToken(t *testing.T) {
 	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
 }
 
+// TestMiddleware_Authenticate_ClientPolicy tests that the backend and files policy come from
+// the client's current configuration rather than the token
+func TestMiddleware_Authenticate_ClientPolicy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "oauth-clients.yaml")
+	writeConfig := func(client string, modTime time.Time) {
+		t.Helper()
+		config := "clients:\n  - client_id: files-client\n    client_secret_hash: $2a$10$test\n    name: Files Client\n    scopes:\n      - files:write\n" + client
+		require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+		// Load() compares modification times at second granularity
+		require.NoError(t, os.Chtimes(configPath, modTime, modTime))
+	}
+	writeConfig("    backend: markdown\n    files:\n      namespace: true\n      max_files: 100\n", time.Now())
+	repo, err := NewRepository(configPath)
+	require.NoError(t, err)
+
+	jm := newTestJWTManager()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	m := NewMiddleware(jm, repo, logger, false, "")
+	token := generateValidToken(t, jm, newTestOAuthClient("files-client", []string{"files:write"}))
+
+	authenticate := func() (*Claims, int) {
+		var claims *Claims
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ = r.Context().Value(ContextKeyClaims).(*Claims)
+			w.WriteHeader(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/files", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		m.Authenticate(next).ServeHTTP(rec, req)
+		return claims, rec.Code
+	}
+
+	claims, status := authenticate()
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "markdown", claims.Backend)
+	assert.Equal(t, FilesPolicy{Namespace: true, MaxFiles: 100}, claims.Files)
+
+	// A changed policy applies to the token already issued
+	writeConfig("    files:\n      namespace: true\n      max_files: 10\n", time.Now().Add(2*time.Second))
+	claims, status = authenticate()
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, claims.Backend)
+	assert.Equal(t, FilesPolicy{Namespace: true, MaxFiles: 10}, claims.Files)
+}
+
+// TestMiddleware_Authenticate_RevokedClient tests that tokens of a client stop working as soon
+// as the client is disabled or removed from the clients file
+func TestMiddleware_Authenticate_RevokedClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"disabled client", "clients:\n  - client_id: revoked-client\n    client_secret_hash: $2a$10$test\n    disabled: true\n"},
+		{"removed client", "clients: []\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "oauth-clients.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte("clients:\n  - client_id: revoked-client\n    client_secret_hash: $2a$10$test\n"), 0600))
+			repo, err := NewRepository(configPath)
+			require.NoError(t, err)
+
+			jm := newTestJWTManager()
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+			m := NewMiddleware(jm, repo, logger, false, "")
+			token := generateValidToken(t, jm, newTestOAuthClient("revoked-client", []string{"tasks:write"}))
+
+			authenticate := func() int {
+				next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+				req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				rec := httptest.NewRecorder()
+				m.Authenticate(next).ServeHTTP(rec, req)
+				return rec.Code
+			}
+			require.Equal(t, http.StatusOK, authenticate())
+
+			// Load() compares modification times at second granularity
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.config), 0600))
+			later := time.Now().Add(2 * time.Second)
+			require.NoError(t, os.Chtimes(configPath, later, later))
+
+			assert.Equal(t, http.StatusUnauthorized, authenticate())
+		})
+	}
+}
+
 // TestMiddleware_Authenticate_LegacyAuth tests legacy authentication fallback
 func TestMiddleware_Authenticate_LegacyAuth(t *testing.T) {
 	tests := []struct {
@@ -214,7 +307,7 @@ func TestMiddleware_Authenticate_LegacyAuth(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			jm := newTestJWTManager()
 			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-			m := NewMiddleware(jm, logger, tt.legacyEnabled, tt.legacyToken)
+			m := NewMiddleware(jm, nil, logger, tt.legacyEnabled, tt.legacyToken)
 
 			var capturedClaims *Claims
 
@@ -247,7 +340,7 @@ func TestMiddleware_Authenticate_LegacyAuth(t *testing.T) {
 func TestMiddleware_Authenticate_LegacyIsolation(t *testing.T) {
 	jm := newTestJWTManager()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-	m := NewMiddleware(jm, logger, true, testLegacyToken)
+	m := NewMiddleware(jm, nil, logger, true, testLegacyToken)
 
 	// Create a valid OAuth token
 	client := newTestOAuthClient("oauth-client", []string{"admin:*"})
@@ -278,7 +371,7 @@ func TestMiddleware_Authenticate_LegacyIsolation(t *testing.T) {
 func TestMiddleware_Authenticate_HeaderInjection(t *testing.T) {
 	jm := newTestJWTManager()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-	m := NewMiddleware(jm, logger, false, "")
+	m := NewMiddleware(jm, nil, logger, false, "")
 
 	tests := []struct {
 		name       string
@@ -320,7 +413,7 @@ func TestMiddleware_Authenticate_HeaderInjection(t *testing.T) {
 func TestMiddleware_Authenticate_ContextIntegrity(t *testing.T) {
 	jm := newTestJWTManager()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
-	m := NewMiddleware(jm, logger, false, "")
+	m := NewMiddleware(jm, nil, logger, false, "")
 
 	// Pre-populate context with existing claims (simulating a previous request)
 	existingClaims := &Claims{ClientID: "existing-client", Scopes: []string{"old:scope"}}
@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
@@ -87,6 +88,9 @@ func (r *Repository) Load() error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Remember the version even when it is invalid so an unchanged broken file is reported once
+	r.lastModified = modTime
+
 	// Parse YAML
 	var config OAuthConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
@@ -97,21 +101,33 @@ func (r *Repository) Load() error {
 	clients := make(map[string]*OAuthClient)
 	for i := range config.Clients {
 		client := &config.Clients[i]
+		if err := client.Files.validate(); err != nil {
+			return fmt.Errorf("client %q: %w", client.ClientID, err)
+		}
 		clients[client.ClientID] = client
 	}
 
 	r.clients = clients
-	r.lastModified = modTime
 
 	return nil
 }
 
-// GetByClientID retrieves a client by client ID
-func (r *Repository) GetByClientID(clientID string) (*OAuthClient, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// validate rejects quotas that cannot be enforced. Usage is measured in the client's own
+// namespace, so a quota requires one.
+func (p FilesPolicy) validate() error {
+	if p.MaxBytes < 0 || p.MaxFiles < 0 {
+		return errors.New("files quotas cannot be negative")
+	}
+	if (p.MaxBytes > 0 || p.MaxFiles > 0) && !p.Namespace {
+		return errors.New("files quotas require namespace: true")
+	}
+	return nil
+}
 
-	client, ok := r.clients[clientID]
+// GetByClientID retrieves a client by client ID, picking up any changes to the configuration
+// file first
+func (r *Repository) GetByClientID(clientID string) (*OAuthClient, error) {
+	client, ok := r.Lookup(clientID)
 	if !ok {
 		return nil, ErrClientNotFound
 	}
@@ -123,6 +139,54 @@ func (r *Repository) GetByClientID(clientID string) (*OAuthClient, error) {
 	return client, nil
 }
 
+// Lookup retrieves a client by client ID whether or not it is disabled, picking up any changes to
+// the configuration file first
+func (r *Repository) Lookup(clientID string) (*OAuthClient, bool) {
+	r.reload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[clientID]
+	return client, ok
+}
+
+// Backends returns the task backends configured for enabled clients
+func (r *Repository) Backends() []string {
+	r.reload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var backends []string
+	for _, client := range r.clients {
+		if !client.Disabled && client.Backend != "" {
+			backends = append(backends, client.Backend)
+		}
+	}
+	return backends
+}
+
+// reload picks up changes to the configuration file. A failed reload keeps serving the last
+// good configuration. An unchanged file only costs a stat and a read lock, so concurrent
+// requests do not queue up behind Load's write lock.
+func (r *Repository) reload() {
+	if info, err := os.Stat(r.configPath); err == nil {
+		r.mu.RLock()
+		unchanged := info.ModTime().Unix() == r.lastModified
+		r.mu.RUnlock()
+		if unchanged {
+			return
+		}
+	}
+
+	if err := r.Load(); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to reload OAuth clients; using previous version",
+			slog.String("config_file", r.configPath),
+			slog.String("error", err.Error()))
+	}
+}
+
 // Authenticate validates client credentials
 func (r *Repository) Authenticate(clientID, clientSecret string) (*OAuthClient, error) {
 	client, err := r.GetByClientID(clientID)
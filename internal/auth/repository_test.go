@@ -274,6 +274,106 @@ func TestRepository_Load(t *testing.T) {
 	assert.Equal(t, "new-client", newClient.ClientID)
 }
 
+func TestRepository_FilesPolicy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "oauth-clients.yaml")
+	config := `clients:
+  - client_id: scoped-client
+    client_secret_hash: $2a$10$test
+    name: Scoped Client
+    scopes:
+      - files:write
+    files:
+      namespace: true
+      max_bytes: 1048576
+      max_files: 100
+  - client_id: shared-client
+    client_secret_hash: $2a$10$test
+    name: Shared Client
+    scopes:
+      - files:write
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+
+	repo, err := NewRepository(configPath)
+	require.NoError(t, err)
+
+	client, err := repo.GetByClientID("scoped-client")
+	require.NoError(t, err)
+	assert.Equal(t, FilesPolicy{Namespace: true, MaxBytes: 1048576, MaxFiles: 100}, client.Files)
+
+	client, err = repo.GetByClientID("shared-client")
+	require.NoError(t, err)
+	assert.Zero(t, client.Files)
+}
+
+func TestRepository_FilesPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		files string
+	}{
+		{"max_bytes without namespace", "      max_bytes: 1048576\n"},
+		{"max_files without namespace", "      max_files: 100\n"},
+		{"negative quota", "      namespace: true\n      max_files: -1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "oauth-clients.yaml")
+			config := `clients:
+  - client_id: scoped-client
+    client_secret_hash: $2a$10$test
+    name: Scoped Client
+    scopes:
+      - files:write
+    files:
+` + tt.files
+			require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+
+			_, err := NewRepository(configPath)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "scoped-client")
+		})
+	}
+}
+
+func TestRepository_Backends(t *testing.T) {
+	hashedSecret := hashPassword(t, "test-secret")
+	clients := []OAuthClient{
+		{ClientID: "caldav-client", ClientSecretHash: hashedSecret, Backend: "caldav"},
+		{ClientID: "default-client", ClientSecretHash: hashedSecret},
+		{ClientID: "disabled-client", ClientSecretHash: hashedSecret, Backend: "webhook", Disabled: true},
+	}
+
+	repo, cleanup := createTestRepository(t, clients)
+	defer cleanup()
+
+	assert.Equal(t, []string{"caldav"}, repo.Backends())
+}
+
+// TestRepository_Lookup_ConcurrentReaders tests that lookups of an unchanged file only take the
+// read lock, so they do not wait for each other
+func TestRepository_Lookup_ConcurrentReaders(t *testing.T) {
+	repo, cleanup := createTestRepository(t, []OAuthClient{
+		{ClientID: "test-client", ClientSecretHash: hashPassword(t, "test-secret")},
+	})
+	defer cleanup()
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	done := make(chan bool)
+	go func() {
+		_, ok := repo.Lookup("test-client")
+		done <- ok
+	}()
+	select {
+	case ok := <-done:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("Lookup waited for the write lock")
+	}
+}
+
 // TestRepository_Load_FileNotChanged tests that reload is skipped when file hasn't changed
 func TestRepository_Load_FileNotChanged(t *testing.T) {
 	tmpDir := t.TempDir()
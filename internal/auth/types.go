@@ -4,13 +4,23 @@ import "time"
 
 // OAuthClient represents an OAuth 2.0 client
 type OAuthClient struct {
-	ClientID         string    `yaml:"client_id"`
-	ClientSecretHash string    `yaml:"client_secret_hash"`
-	Name             string    `yaml:"name"`
-	Scopes           []string  `yaml:"scopes"`
-	CreatedAt        time.Time `yaml:"created_at"`
-	UpdatedAt        time.Time `yaml:"updated_at,omitempty"`
-	Disabled         bool      `yaml:"disabled,omitempty"`
+	ClientID         string      `yaml:"client_id"`
+	ClientSecretHash string      `yaml:"client_secret_hash"`
+	Name             string      `yaml:"name"`
+	Scopes           []string    `yaml:"scopes"`
+	CreatedAt        time.Time   `yaml:"created_at"`
+	UpdatedAt        time.Time   `yaml:"updated_at,omitempty"`
+	Disabled         bool        `yaml:"disabled,omitempty"`
+	Backend          string      `yaml:"backend,omitempty"` // default task backend for this client
+	Files            FilesPolicy `yaml:"files,omitempty"`   // file namespace and quotas for this client
+}
+
+// FilesPolicy confines a client's files to its own directory and limits their total size and number.
+// The zero value shares the whole files directory without limits.
+type FilesPolicy struct {
+	Namespace bool  `yaml:"namespace,omitempty" json:"namespace,omitempty"` // read and write only below clients/<client_id>
+	MaxBytes  int64 `yaml:"max_bytes,omitempty" json:"max_bytes,omitempty"` // total size of the client's files; 0 for no limit
+	MaxFiles  int64 `yaml:"max_files,omitempty" json:"max_files,omitempty"` // number of the client's files; 0 for no limit
 }
 
 // OAuthConfig represents the OAuth clients configuration file structure
@@ -43,6 +53,10 @@ type ErrorResponse struct {
 type Claims struct {
 	ClientID string   `json:"client_id"`
 	Scopes   []string `json:"scopes"`
+	// Backend and Files are not part of the token; the middleware sets them from the client's
+	// current configuration
+	Backend string      `json:"-"` // default task backend for the client
+	Files   FilesPolicy `json:"-"` // file namespace and quotas for the client
 	// Standard JWT claims
 	Issuer    string `json:"iss"`
 	Subject   string `json:"sub"`
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +16,19 @@ import (
 // ErrNoAuthConfigured is returned when no authentication method is configured
 var ErrNoAuthConfigured = errors.New("no authentication method configured: either TOKEN (with OMNIDROP_LEGACY_AUTH_ENABLED=true) or OMNIDROP_JWT_SECRET must be set")
 
+// Script languages for the task script (OMNIDROP_SCRIPT_LANGUAGE)
+const (
+	ScriptLanguageAppleScript = "applescript"
+	ScriptLanguageJavaScript  = "javascript" // JavaScript for Automation (JXA)
+)
+
+// Startup checks of installed scripts against the copies embedded in the binary (OMNIDROP_SCRIPT_CHECK)
+const (
+	ScriptCheckWarn   = "warn"   // Log mismatches and start anyway
+	ScriptCheckStrict = "strict" // Refuse to start on a mismatch
+	ScriptCheckOff    = "off"
+)
+
 type Config struct {
 	// Server configuration
 	Port  string
@@ -24,16 +39,60 @@ type Config struct {
 	ScriptPath  string
 
 	// AppleScript configuration
+	ScriptLanguage  string // Task script language: applescript (default) or javascript
 	AppleScriptFile string
+	JXAScriptFile   string // JXA equivalent of AppleScriptFile, used when ScriptLanguage is javascript
+	QueryScriptFile string // Read-only query script, installed next to the task script
+	ScriptCheck     string // Startup drift check of installed scripts: warn (default), strict or off
+
+	// AppleScript executor pool configuration
+	AppleScriptConcurrency  int           // Scripts run at the same time
+	AppleScriptQueueSize    int           // Requests waiting for a free slot before new ones get 503
+	AppleScriptQueueTimeout time.Duration // Longest a request waits for a free slot before it gets 503
+
+	// OmniFocus circuit breaker configuration
+	CircuitFailureThreshold int           // Consecutive script failures that open the circuit (0 disables the breaker)
+	CircuitCooldown         time.Duration // Time the circuit stays open before OmniFocus is probed again
+
+	// Health check configuration
+	HealthCacheTTL      time.Duration // How long /health/ready reuses its last result (0 checks on every request)
+	HealthCheckInterval time.Duration // Interval of the background health monitor (0 disables it)
 
 	// Files configuration
-	FilesDir string // Base directory for file operations
+	FilesDir           string        // Base directory for file operations
+	FilesMaxUploadSize int64         // Size limit in bytes of streamed multipart and octet-stream uploads
+	FilesUploadTimeout time.Duration // Read/write deadline of streamed uploads and downloads
+
+	// Task configuration
+	Timezone *time.Location // Location used to interpret zone-less and relative task dates
+
+	// Task backend configuration
+	TaskBackend       string // Backend for requests that do not select one (omnifocus, markdown, todotxt, caldav, webhook)
+	MarkdownTasksFile string // Markdown checklist path relative to FilesDir
+	TodoTxtFile       string // todo.txt path relative to FilesDir
+	CalDAVURL         string // CalDAV calendar collection URL; empty disables the caldav backend
+	CalDAVUsername    string
+	CalDAVPassword    string
+	WebhookURL        string // Webhook URL; empty disables the webhook backend
+	WebhookSecret     string // Optional HMAC-SHA256 signing secret for webhook requests
+
+	// Task queue configuration
+	QueueEnabled     bool   // Queue tasks for retry when OmniFocus delivery fails
+	QueueDir         string // Directory holding queued tasks
+	QueueMaxAttempts int    // Retry attempts before a task moves to the failed/ directory (0 = unlimited)
+
+	// Idempotency configuration
+	IdempotencyDir string        // Directory holding stored Idempotency-Key responses
+	IdempotencyTTL time.Duration // Replay window for Idempotency-Key (0 disables)
 
 	// OAuth configuration
 	JWTSecret         string
 	TokenExpiry       time.Duration
 	OAuthClientsFile  string
 	LegacyAuthEnabled bool
+
+	// Task template configuration
+	TemplatesFile string // YAML file defining templates for POST /tasks/from-template/{name}
 }
 
 func Load() (*Config, error) {
@@ -41,16 +100,44 @@ func Load() (*Config, error) {
 	loadEnvFile()
 
 	cfg := &Config{
-		Port:              getEnvWithDefault("PORT", "8787"),
-		Token:             os.Getenv("TOKEN"),
-		Environment:       getEnvWithDefault("OMNIDROP_ENV", ""),
-		ScriptPath:        os.Getenv("OMNIDROP_SCRIPT"),
-		AppleScriptFile:   "omnidrop.applescript",
-		FilesDir:          getFilesDir(),
-		JWTSecret:         os.Getenv("OMNIDROP_JWT_SECRET"),
-		TokenExpiry:       getTokenExpiry(),
-		OAuthClientsFile:  getOAuthClientsFile(),
-		LegacyAuthEnabled: getEnvWithDefault("OMNIDROP_LEGACY_AUTH_ENABLED", "false") == "true",
+		Port:                    getEnvWithDefault("PORT", "8787"),
+		Token:                   os.Getenv("TOKEN"),
+		Environment:             getEnvWithDefault("OMNIDROP_ENV", ""),
+		ScriptPath:              os.Getenv("OMNIDROP_SCRIPT"),
+		ScriptLanguage:          strings.ToLower(getEnvWithDefault("OMNIDROP_SCRIPT_LANGUAGE", ScriptLanguageAppleScript)),
+		AppleScriptFile:         "omnidrop.applescript",
+		JXAScriptFile:           "omnidrop.js",
+		QueryScriptFile:         "omnidrop-query.applescript",
+		ScriptCheck:             strings.ToLower(getEnvWithDefault("OMNIDROP_SCRIPT_CHECK", ScriptCheckWarn)),
+		AppleScriptConcurrency:  getAppleScriptConcurrency(),
+		AppleScriptQueueSize:    getAppleScriptQueueSize(),
+		AppleScriptQueueTimeout: getAppleScriptQueueTimeout(),
+		CircuitFailureThreshold: getCircuitFailureThreshold(),
+		CircuitCooldown:         getCircuitCooldown(),
+		HealthCacheTTL:          getHealthCacheTTL(),
+		HealthCheckInterval:     getHealthCheckInterval(),
+		FilesDir:                getFilesDir(),
+		FilesMaxUploadSize:      getFilesMaxUploadSize(),
+		FilesUploadTimeout:      getFilesUploadTimeout(),
+		Timezone:                getTimezone(),
+		TaskBackend:             getEnvWithDefault("OMNIDROP_TASK_BACKEND", "omnifocus"),
+		MarkdownTasksFile:       getEnvWithDefault("OMNIDROP_MARKDOWN_TASKS_FILE", "tasks.md"),
+		TodoTxtFile:             getEnvWithDefault("OMNIDROP_TODOTXT_FILE", "todo.txt"),
+		CalDAVURL:               os.Getenv("OMNIDROP_CALDAV_URL"),
+		CalDAVUsername:          os.Getenv("OMNIDROP_CALDAV_USERNAME"),
+		CalDAVPassword:          os.Getenv("OMNIDROP_CALDAV_PASSWORD"),
+		WebhookURL:              os.Getenv("OMNIDROP_WEBHOOK_URL"),
+		WebhookSecret:           os.Getenv("OMNIDROP_WEBHOOK_SECRET"),
+		QueueEnabled:            getEnvWithDefault("OMNIDROP_QUEUE_ENABLED", "true") == "true",
+		QueueDir:                getQueueDir(),
+		QueueMaxAttempts:        getQueueMaxAttempts(),
+		IdempotencyDir:          getIdempotencyDir(),
+		IdempotencyTTL:          getIdempotencyTTL(),
+		JWTSecret:               os.Getenv("OMNIDROP_JWT_SECRET"),
+		TokenExpiry:             getTokenExpiry(),
+		OAuthClientsFile:        getOAuthClientsFile(),
+		LegacyAuthEnabled:       getEnvWithDefault("OMNIDROP_LEGACY_AUTH_ENABLED", "false") == "true",
+		TemplatesFile:           getTemplatesFile(),
 	}
 
 	// Validate required configuration
@@ -81,6 +168,13 @@ func (c *Config) validate() error {
 		return fmt.Errorf("OMNIDROP_JWT_SECRET is required when OAuth is enabled (OMNIDROP_LEGACY_AUTH_ENABLED=false)")
 	}
 
+	if c.ScriptLanguage != ScriptLanguageAppleScript && c.ScriptLanguage != ScriptLanguageJavaScript {
+		return fmt.Errorf("OMNIDROP_SCRIPT_LANGUAGE must be %s or %s", ScriptLanguageAppleScript, ScriptLanguageJavaScript)
+	}
+	if c.ScriptCheck != ScriptCheckWarn && c.ScriptCheck != ScriptCheckStrict && c.ScriptCheck != ScriptCheckOff {
+		return fmt.Errorf("OMNIDROP_SCRIPT_CHECK must be %s, %s or %s", ScriptCheckWarn, ScriptCheckStrict, ScriptCheckOff)
+	}
+
 	// Validate environment-specific rules
 	if err := c.validateEnvironment(); err != nil {
 		return err
@@ -113,7 +207,7 @@ func (c *Config) validateEnvironment() error {
 			slog.Warn("Could not determine home directory; skipping production script path protection check",
 				slog.String("error", err.Error()))
 		} else {
-			prodScriptPath := fmt.Sprintf("%s/.local/share/omnidrop/omnidrop.applescript", homeDir)
+			prodScriptPath := fmt.Sprintf("%s/.local/share/omnidrop/%s", homeDir, c.TaskScriptFile())
 			if c.ScriptPath == prodScriptPath {
 				return fmt.Errorf("❌ FATAL: Cannot use production AppleScript in non-production environment")
 			}
@@ -123,6 +217,16 @@ func (c *Config) validateEnvironment() error {
 	return nil
 }
 
+// TaskScriptFile returns the file name of the task script for the configured script language
+func (c *Config) TaskScriptFile() string {
+	if c.ScriptLanguage == ScriptLanguageJavaScript {
+		return c.JXAScriptFile
+	}
+	return c.AppleScriptFile
+}
+
+// GetAppleScriptPath resolves the task script: OMNIDROP_SCRIPT when set, otherwise
+// TaskScriptFile in the environment's script location
 func (c *Config) GetAppleScriptPath() (string, error) {
 	// Priority 1: Explicit path via OMNIDROP_SCRIPT environment variable
 	if c.ScriptPath != "" {
@@ -143,17 +247,26 @@ func (c *Config) GetAppleScriptPath() (string, error) {
 	}
 }
 
+// GetQueryScriptPath returns the query script that sits next to the resolved task creation script
+func (c *Config) GetQueryScriptPath() (string, error) {
+	scriptPath, err := c.GetAppleScriptPath()
+	if err != nil {
+		return "", err
+	}
+	return validateScriptPath(filepath.Join(filepath.Dir(scriptPath), c.QueryScriptFile))
+}
+
 func (c *Config) getProductionScriptPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("could not get home directory: %v", err)
 	}
-	path := fmt.Sprintf("%s/.local/share/omnidrop/%s", homeDir, c.AppleScriptFile)
+	path := fmt.Sprintf("%s/.local/share/omnidrop/%s", homeDir, c.TaskScriptFile())
 	return validateScriptPath(path)
 }
 
 func (c *Config) getDevelopmentScriptPath() (string, error) {
-	return validateScriptPath(c.AppleScriptFile)
+	return validateScriptPath(c.TaskScriptFile())
 }
 
 func (c *Config) getTestScriptPath() (string, error) {
@@ -170,8 +283,8 @@ func (c *Config) getLegacyScriptPath() (string, error) {
 	}
 
 	scriptPaths := []string{
-		c.AppleScriptFile,
-		fmt.Sprintf("%s/.local/share/omnidrop/%s", homeDir, c.AppleScriptFile),
+		c.TaskScriptFile(),
+		fmt.Sprintf("%s/.local/share/omnidrop/%s", homeDir, c.TaskScriptFile()),
 	}
 
 	for _, path := range scriptPaths {
@@ -220,6 +333,28 @@ func getFilesDir() string {
 	return fmt.Sprintf("%s/.local/share/omnidrop/files", homeDir)
 }
 
+func getFilesMaxUploadSize() int64 {
+	value := getEnvWithDefault("OMNIDROP_FILES_MAX_UPLOAD_SIZE", "104857600")
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 1 {
+		slog.Warn("Invalid OMNIDROP_FILES_MAX_UPLOAD_SIZE value; defaulting to 104857600 (100MB)",
+			slog.String("value", value))
+		return 100 << 20
+	}
+	return size
+}
+
+func getFilesUploadTimeout() time.Duration {
+	timeoutStr := getEnvWithDefault("OMNIDROP_FILES_UPLOAD_TIMEOUT", "10m")
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		slog.Warn("Invalid OMNIDROP_FILES_UPLOAD_TIMEOUT value; defaulting to 10m",
+			slog.String("value", timeoutStr))
+		return 10 * time.Minute
+	}
+	return timeout
+}
+
 func getTokenExpiry() time.Duration {
 	expiryStr := getEnvWithDefault("OMNIDROP_TOKEN_EXPIRY", "24h")
 	expiry, err := time.ParseDuration(expiryStr)
@@ -232,6 +367,156 @@ func getTokenExpiry() time.Duration {
 	return expiry
 }
 
+func getAppleScriptConcurrency() int {
+	value := getEnvWithDefault("OMNIDROP_APPLESCRIPT_CONCURRENCY", "2")
+	concurrency, err := strconv.Atoi(value)
+	if err != nil || concurrency < 1 {
+		slog.Warn("Invalid OMNIDROP_APPLESCRIPT_CONCURRENCY value; defaulting to 2",
+			slog.String("value", value))
+		return 2
+	}
+	return concurrency
+}
+
+func getAppleScriptQueueSize() int {
+	value := getEnvWithDefault("OMNIDROP_APPLESCRIPT_QUEUE_SIZE", "32")
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		slog.Warn("Invalid OMNIDROP_APPLESCRIPT_QUEUE_SIZE value; defaulting to 32",
+			slog.String("value", value))
+		return 32
+	}
+	return size
+}
+
+func getAppleScriptQueueTimeout() time.Duration {
+	timeoutStr := getEnvWithDefault("OMNIDROP_APPLESCRIPT_QUEUE_TIMEOUT", "10s")
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		slog.Warn("Invalid OMNIDROP_APPLESCRIPT_QUEUE_TIMEOUT value; defaulting to 10s",
+			slog.String("value", timeoutStr))
+		return 10 * time.Second
+	}
+	return timeout
+}
+
+func getCircuitFailureThreshold() int {
+	value := getEnvWithDefault("OMNIDROP_CIRCUIT_FAILURE_THRESHOLD", "5")
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 0 {
+		slog.Warn("Invalid OMNIDROP_CIRCUIT_FAILURE_THRESHOLD value; defaulting to 5",
+			slog.String("value", value))
+		return 5
+	}
+	return threshold
+}
+
+func getCircuitCooldown() time.Duration {
+	cooldownStr := getEnvWithDefault("OMNIDROP_CIRCUIT_COOLDOWN", "30s")
+	cooldown, err := time.ParseDuration(cooldownStr)
+	if err != nil || cooldown <= 0 {
+		slog.Warn("Invalid OMNIDROP_CIRCUIT_COOLDOWN value; defaulting to 30s",
+			slog.String("value", cooldownStr))
+		return 30 * time.Second
+	}
+	return cooldown
+}
+
+func getHealthCacheTTL() time.Duration {
+	ttlStr := getEnvWithDefault("OMNIDROP_HEALTH_CACHE_TTL", "15s")
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil || ttl < 0 {
+		slog.Warn("Invalid OMNIDROP_HEALTH_CACHE_TTL value; defaulting to 15s",
+			slog.String("value", ttlStr))
+		return 15 * time.Second
+	}
+	return ttl
+}
+
+func getHealthCheckInterval() time.Duration {
+	intervalStr := getEnvWithDefault("OMNIDROP_HEALTH_CHECK_INTERVAL", "1m")
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval < 0 {
+		slog.Warn("Invalid OMNIDROP_HEALTH_CHECK_INTERVAL value; defaulting to 1m",
+			slog.String("value", intervalStr))
+		return time.Minute
+	}
+	return interval
+}
+
+func getQueueDir() string {
+	if dir := os.Getenv("OMNIDROP_QUEUE_DIR"); dir != "" {
+		return dir
+	}
+
+	// Default location
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "./queue" // Fallback to relative directory
+	}
+
+	return fmt.Sprintf("%s/.local/share/omnidrop/queue", homeDir)
+}
+
+func getQueueMaxAttempts() int {
+	value := getEnvWithDefault("OMNIDROP_QUEUE_MAX_ATTEMPTS", "0")
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 0 {
+		slog.Warn("Invalid OMNIDROP_QUEUE_MAX_ATTEMPTS value; defaulting to unlimited retries",
+			slog.String("value", value))
+		return 0
+	}
+	return attempts
+}
+
+func getIdempotencyDir() string {
+	if dir := os.Getenv("OMNIDROP_IDEMPOTENCY_DIR"); dir != "" {
+		return dir
+	}
+
+	// Default location
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "./idempotency" // Fallback to relative directory
+	}
+
+	return fmt.Sprintf("%s/.local/share/omnidrop/idempotency", homeDir)
+}
+
+func getIdempotencyTTL() time.Duration {
+	ttlStr := getEnvWithDefault("OMNIDROP_IDEMPOTENCY_TTL", "24h")
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil || ttl < 0 {
+		slog.Warn("Invalid OMNIDROP_IDEMPOTENCY_TTL value; defaulting to 24h",
+			slog.String("value", ttlStr))
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+func getTimezone() *time.Location {
+	name := os.Getenv("OMNIDROP_TIMEZONE")
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("Invalid OMNIDROP_TIMEZONE value; defaulting to system local time",
+			slog.String("value", name),
+			slog.String("error", err.Error()))
+		return time.Local
+	}
+	return loc
+}
+
+// Location returns the configured task timezone, falling back to the system local time
+func (c *Config) Location() *time.Location {
+	if c.Timezone == nil {
+		return time.Local
+	}
+	return c.Timezone
+}
+
 func getOAuthClientsFile() string {
 	if file := os.Getenv("OMNIDROP_OAUTH_CLIENTS_FILE"); file != "" {
 		return file
@@ -245,3 +530,17 @@ func getOAuthClientsFile() string {
 
 	return fmt.Sprintf("%s/.local/share/omnidrop/oauth-clients.yaml", homeDir)
 }
+
+func getTemplatesFile() string {
+	if file := os.Getenv("OMNIDROP_TEMPLATES_FILE"); file != "" {
+		return file
+	}
+
+	// Default location, next to the OAuth clients file
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "./task-templates.yaml" // Fallback to relative path
+	}
+
+	return fmt.Sprintf("%s/.local/share/omnidrop/task-templates.yaml", homeDir)
+}
@@ -0,0 +1,162 @@
+// Package dateparse converts client-supplied task dates into absolute times.
+//
+// Inputs are either RFC 3339 timestamps or a small natural shorthand such as
+// "today", "tomorrow 9am", "friday at 17:00", "in 3 days" or "2025-10-01 14:30".
+// Relative and zone-less inputs are interpreted in the location of the
+// reference time, which lets the server resolve dates in a configured timezone
+// regardless of where the client runs.
+package dateparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnrecognized is returned when the input matches none of the supported formats
+var ErrUnrecognized = errors.New("unrecognized date format")
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	relativePattern = regexp.MustCompile(`^in\s+(\d+)\s+(minute|hour|day|week)s?$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Parse resolves input relative to ref. Date-only inputs are placed at defaultHour:00
+// in ref's location. Weekday names refer to the next such day strictly after ref.
+func Parse(input string, ref time.Time, defaultHour int) (time.Time, error) {
+	s := strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	if s == "" {
+		return time.Time{}, ErrUnrecognized
+	}
+
+	// Absolute timestamps carry their own offset
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+
+	// "in N minutes|hours|days|weeks"
+	if m := relativePattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "minute":
+			return ref.Add(time.Duration(n) * time.Minute), nil
+		case "hour":
+			return ref.Add(time.Duration(n) * time.Hour), nil
+		case "day":
+			return atHour(ref.AddDate(0, 0, n), defaultHour), nil
+		default:
+			return atHour(ref.AddDate(0, 0, 7*n), defaultHour), nil
+		}
+	}
+
+	dayPart, clockPart := splitDayAndClock(s)
+
+	day, err := parseDay(dayPart, ref)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if clockPart == "" {
+		return atHour(day, defaultHour), nil
+	}
+
+	hour, minute, err := parseClock(clockPart)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, ref.Location()), nil
+}
+
+// splitDayAndClock separates "tomorrow at 9am" into ("tomorrow", "9am").
+// A bare clock such as "9am" is treated as today.
+func splitDayAndClock(s string) (string, string) {
+	s = strings.Replace(s, " at ", " ", 1)
+	if clockPattern.MatchString(s) || s == "noon" || s == "midnight" {
+		return "today", s
+	}
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+func parseDay(s string, ref time.Time) (time.Time, error) {
+	switch s {
+	case "today":
+		return ref, nil
+	case "tomorrow":
+		return ref.AddDate(0, 0, 1), nil
+	}
+
+	if wd, ok := weekdays[s]; ok {
+		delta := (int(wd) - int(ref.Weekday()) + 7) % 7
+		if delta == 0 {
+			delta = 7
+		}
+		return ref.AddDate(0, 0, delta), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, ref.Location()); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+}
+
+func parseClock(s string) (int, int, error) {
+	switch s {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, fmt.Errorf("%w: invalid time %q", ErrUnrecognized, s)
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+
+	switch m[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("%w: invalid hour in %q", ErrUnrecognized, s)
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	default:
+		// A bare number without a colon is ambiguous (e.g. "tomorrow 9" vs a day of month)
+		if m[2] == "" || hour > 23 {
+			return 0, 0, fmt.Errorf("%w: invalid time %q", ErrUnrecognized, s)
+		}
+	}
+
+	if minute > 59 {
+		return 0, 0, fmt.Errorf("%w: invalid minute in %q", ErrUnrecognized, s)
+	}
+	return hour, minute, nil
+}
+
+func atHour(t time.Time, hour int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,74 @@
+package dateparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// Wednesday 2025-10-15 10:30 in Tokyo
+	ref := time.Date(2025, 10, 15, 10, 30, 0, 0, tokyo)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{"rfc3339 with offset", "2025-10-20T09:00:00-07:00", time.Date(2025, 10, 20, 16, 0, 0, 0, time.UTC)},
+		{"rfc3339 utc", "2025-10-20T09:00:00Z", time.Date(2025, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"date only", "2025-10-20", time.Date(2025, 10, 20, 18, 0, 0, 0, tokyo)},
+		{"date with clock", "2025-10-20 14:30", time.Date(2025, 10, 20, 14, 30, 0, 0, tokyo)},
+		{"today", "today", time.Date(2025, 10, 15, 18, 0, 0, 0, tokyo)},
+		{"tomorrow", "Tomorrow", time.Date(2025, 10, 16, 18, 0, 0, 0, tokyo)},
+		{"tomorrow 9am", "tomorrow 9am", time.Date(2025, 10, 16, 9, 0, 0, 0, tokyo)},
+		{"tomorrow at 9:15 pm", "tomorrow at 9:15 pm", time.Date(2025, 10, 16, 21, 15, 0, 0, tokyo)},
+		{"bare clock", "5pm", time.Date(2025, 10, 15, 17, 0, 0, 0, tokyo)},
+		{"noon", "friday noon", time.Date(2025, 10, 17, 12, 0, 0, 0, tokyo)},
+		{"12am is midnight", "today 12am", time.Date(2025, 10, 15, 0, 0, 0, 0, tokyo)},
+		{"weekday later this week", "friday", time.Date(2025, 10, 17, 18, 0, 0, 0, tokyo)},
+		{"same weekday is next week", "wednesday 08:00", time.Date(2025, 10, 22, 8, 0, 0, 0, tokyo)},
+		{"in hours", "in 2 hours", time.Date(2025, 10, 15, 12, 30, 0, 0, tokyo)},
+		{"in days", "in 3 days", time.Date(2025, 10, 18, 18, 0, 0, 0, tokyo)},
+		{"in weeks", "in 1 week", time.Date(2025, 10, 22, 18, 0, 0, 0, tokyo)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, ref, 18)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	ref := time.Date(2025, 10, 15, 10, 30, 0, 0, time.UTC)
+
+	inputs := []string{
+		"",
+		"someday",
+		"tomorrow 25:00",
+		"tomorrow 13pm",
+		"tomorrow 9",
+		"2025-13-01",
+		"next tuesday",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if _, err := Parse(input, ref, 18); !errors.Is(err, ErrUnrecognized) {
+				t.Errorf("Parse(%q) error = %v, want ErrUnrecognized", input, err)
+			}
+		})
+	}
+}
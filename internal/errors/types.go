@@ -20,10 +20,17 @@ type DomainError struct {
 type ErrorCode string
 
 const (
-	ErrorCodeValidation       ErrorCode = "validation_error"
-	ErrorCodeAppleScript      ErrorCode = "applescript_error"
-	ErrorCodeInternal         ErrorCode = "internal_error"
-	ErrorCodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	ErrorCodeValidation           ErrorCode = "validation_error"
+	ErrorCodeAppleScript          ErrorCode = "applescript_error"
+	ErrorCodeInternal             ErrorCode = "internal_error"
+	ErrorCodeMethodNotAllowed     ErrorCode = "method_not_allowed"
+	ErrorCodeNotFound             ErrorCode = "not_found"
+	ErrorCodeNotSupported         ErrorCode = "not_supported"
+	ErrorCodeUnavailable          ErrorCode = "service_unavailable"
+	ErrorCodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+
+	ErrorCodeIdempotencyMismatch   ErrorCode = "idempotency_key_reused"
+	ErrorCodeIdempotencyInProgress ErrorCode = "idempotency_key_in_progress"
 )
 
 // StackFrame represents a single frame in the stack trace
@@ -3,7 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"omnidrop/internal/errors"
 )
@@ -50,12 +53,56 @@ func writeValidationError(w http.ResponseWriter, message string) {
 	writeErrorResponse(w, http.StatusBadRequest, errors.ErrorCodeValidation, message, nil)
 }
 
+// writeUnprocessableError writes a 422 response for a well-formed request that refers to
+// something the backend does not have, such as an unknown project
+func writeUnprocessableError(w http.ResponseWriter, message string) {
+	writeErrorResponse(w, http.StatusUnprocessableEntity, errors.ErrorCodeValidation, message, nil)
+}
+
 // writeMethodNotAllowedError writes a method not allowed error response
 func writeMethodNotAllowedError(w http.ResponseWriter, message string) {
 	writeErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrorCodeMethodNotAllowed, message, nil)
 }
 
+// writeNotFoundError writes a not found error response
+func writeNotFoundError(w http.ResponseWriter, message string) {
+	writeErrorResponse(w, http.StatusNotFound, errors.ErrorCodeNotFound, message, nil)
+}
+
+// writeInternalError writes an internal server error response
+func writeInternalError(w http.ResponseWriter, message string, err error) {
+	writeErrorResponse(w, http.StatusInternalServerError, errors.ErrorCodeInternal, message, err)
+}
+
 // writeAppleScriptError writes an AppleScript-specific error response
 func writeAppleScriptError(w http.ResponseWriter, message string, err error) {
 	writeErrorResponse(w, http.StatusInternalServerError, errors.ErrorCodeAppleScript, message, err)
 }
+
+// writeOutcomeUnknownError writes a 504 response for a backend call that may have taken effect
+// before it failed, so the client checks the result instead of blindly retrying
+func writeOutcomeUnknownError(w http.ResponseWriter, message string, err error) {
+	writeErrorResponse(w, http.StatusGatewayTimeout, errors.ErrorCodeAppleScript, message, err)
+}
+
+// writeRequestTooLargeError writes a request entity too large error response
+func writeRequestTooLargeError(w http.ResponseWriter, message string) {
+	writeErrorResponse(w, http.StatusRequestEntityTooLarge, errors.ErrorCodeValidation, message, nil)
+}
+
+// writeUnsupportedMediaTypeError writes an unsupported media type error response
+func writeUnsupportedMediaTypeError(w http.ResponseWriter, message string) {
+	writeErrorResponse(w, http.StatusUnsupportedMediaType, errors.ErrorCodeUnsupportedMediaType, message, nil)
+}
+
+// writeNotSupportedError writes an error response for operations the selected backend does not provide
+func writeNotSupportedError(w http.ResponseWriter, message string) {
+	writeErrorResponse(w, http.StatusNotImplemented, errors.ErrorCodeNotSupported, message, nil)
+}
+
+// writeUnavailableError writes a 503 response asking the client to retry after retryAfter
+func writeUnavailableError(w http.ResponseWriter, message string, retryAfter time.Duration, err error) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeErrorResponse(w, http.StatusServiceUnavailable, errors.ErrorCodeUnavailable, message, err)
+}
@@ -5,16 +5,21 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"omnidrop/internal/auth"
 	"omnidrop/internal/services"
 )
 
 // FileRequest represents the JSON request for file creation
 type FileRequest struct {
-	Filename  string `json:"filename"`
-	Content   string `json:"content"`
-	Directory string `json:"directory,omitempty"`
+	Filename      string `json:"filename"`
+	Content       string `json:"content"`
+	Directory     string `json:"directory,omitempty"`
+	Encoding      string `json:"encoding,omitempty"`       // utf8 (default), base64 or hex
+	ContentSHA256 string `json:"content_sha256,omitempty"` // expected hex SHA-256 of the decoded content
+	Mode          string `json:"mode,omitempty"`           // create (default), overwrite, append or version
 }
 
 // FileResponse represents the JSON response for file creation
@@ -22,14 +27,15 @@ type FileResponse struct {
 	Status  string `json:"status"`
 	Created bool   `json:"created"`
 	Path    string `json:"path,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
 	Reason  string `json:"reason,omitempty"`
+	Code    string `json:"code,omitempty"` // error kind: validation, conflict, precondition_failed, too_large, checksum_mismatch, quota_exceeded or internal
 }
 
-// CreateFile handles POST requests to create files
+// CreateFile handles POST requests to create files. JSON bodies carry the content inline;
+// multipart/form-data and application/octet-stream bodies are streamed to disk.
 func (h *Handlers) CreateFile(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
-
 	// Method validation
 	if r.Method != http.MethodPost {
 		writeMethodNotAllowedError(w, "Only POST method is allowed for file creation")
@@ -38,6 +44,30 @@ func (h *Handlers) CreateFile(w http.ResponseWriter, r *http.Request) {
 
 	// Authentication is handled by middleware - no need to re-authenticate here
 
+	mediaType, params, err := parseContentType(r)
+	if err != nil {
+		writeValidationError(w, "Invalid Content-Type header")
+		return
+	}
+
+	switch mediaType {
+	case "", "application/json":
+		h.createFileFromJSON(w, r)
+	case "multipart/form-data":
+		h.createFileFromMultipart(w, r, params["boundary"])
+	case "application/octet-stream":
+		h.createFileFromStream(w, r)
+	default:
+		writeUnsupportedMediaTypeError(w,
+			"Content-Type must be application/json, multipart/form-data or application/octet-stream")
+	}
+}
+
+// createFileFromJSON creates a file from a JSON FileRequest
+func (h *Handlers) createFileFromJSON(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(fileContext(r), 10*time.Second)
+	defer cancel()
+
 	// Limit request body size to prevent memory exhaustion
 	r.Body = http.MaxBytesReader(w, r.Body, MaxFileRequestSize)
 
@@ -61,11 +91,20 @@ func (h *Handlers) CreateFile(w http.ResponseWriter, r *http.Request) {
 
 	// Create file via FilesService
 	response := h.filesService.WriteFile(ctx, services.FileWriteRequest{
-		Filename:  fileReq.Filename,
-		Content:   fileReq.Content,
-		Directory: fileReq.Directory,
+		Filename:      fileReq.Filename,
+		Content:       fileReq.Content,
+		Directory:     fileReq.Directory,
+		Encoding:      fileReq.Encoding,
+		ContentSHA256: fileReq.ContentSHA256,
+		Mode:          fileReq.Mode,
+		IfMatch:       ifMatch(r),
 	})
 
+	writeFileResponse(w, response)
+}
+
+// writeFileResponse writes the result of a file write, choosing the status code from its error kind
+func writeFileResponse(w http.ResponseWriter, response services.FileWriteResponse) {
 	// Prepare response
 	w.Header().Set("Content-Type", "application/json")
 
@@ -74,6 +113,14 @@ func (h *Handlers) CreateFile(w http.ResponseWriter, r *http.Request) {
 		switch response.ErrorKind {
 		case "conflict":
 			w.WriteHeader(http.StatusConflict)
+		case "precondition_failed":
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case "too_large":
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case "checksum_mismatch":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "quota_exceeded":
+			w.WriteHeader(http.StatusInsufficientStorage)
 		case "internal":
 			w.WriteHeader(http.StatusInternalServerError)
 		default:
@@ -85,11 +132,37 @@ func (h *Handlers) CreateFile(w http.ResponseWriter, r *http.Request) {
 		Status:  response.Status,
 		Created: response.Created,
 		Path:    response.Path,
+		Size:    response.Size,
+		SHA256:  response.SHA256,
 		Reason:  response.Reason,
+		Code:    response.ErrorKind,
 	}
 
 	if err := json.NewEncoder(w).Encode(fileResponse); err != nil {
 		// Headers already sent by Encode's first Write call; cannot change response status
 		slog.Error("Failed to encode file response", slog.String("error", err.Error()))
 	}
-}
\ No newline at end of file
+}
+
+// fileContext returns the request's context, confined to the namespace and quota the OAuth
+// client's files policy sets. Legacy requests and clients without a namespace share the base
+// directory, but only legacy requests may change the namespaces of other clients.
+func fileContext(r *http.Request) context.Context {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		return r.Context()
+	}
+	return services.WithFileScope(r.Context(), services.FileScope{
+		ClientID:  claims.ClientID,
+		Namespace: claims.Files.Namespace,
+		MaxBytes:  claims.Files.MaxBytes,
+		MaxFiles:  claims.Files.MaxFiles,
+	})
+}
+
+// ifMatch returns the checksum of an If-Match header, which may be quoted like an ETag
+func ifMatch(r *http.Request) string {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	value = strings.TrimPrefix(value, "W/")
+	return strings.Trim(value, `"`)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FileEntryResponse describes a file or subdirectory in GET /files
+type FileEntryResponse struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Type     string    `json:"type"` // "file" or "directory"
+	Size     int64     `json:"size,omitempty"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256,omitempty"`
+}
+
+// FileListResponse is returned by GET /files
+type FileListResponse struct {
+	Status    string              `json:"status"`
+	Directory string              `json:"directory"`
+	Entries   []FileEntryResponse `json:"entries"`
+}
+
+// FileDeleteResponse is returned by DELETE /files/{path}
+type FileDeleteResponse struct {
+	Status string `json:"status"`
+	Path   string `json:"path"`
+}
+
+// ListFiles handles GET /files, listing the directory given by the dir query parameter
+func (h *Handlers) ListFiles(w http.ResponseWriter, r *http.Request) {
+	response := h.filesService.ListFiles(fileContext(r), r.URL.Query().Get("dir"))
+	if response.Status == "error" {
+		writeFileOperationError(w, response.ErrorKind, response.Reason)
+		return
+	}
+
+	entries := make([]FileEntryResponse, 0, len(response.Entries))
+	for _, entry := range response.Entries {
+		entryType := "file"
+		if entry.IsDir {
+			entryType = "directory"
+		}
+		entries = append(entries, FileEntryResponse{
+			Name:     entry.Name,
+			Path:     entry.Path,
+			Type:     entryType,
+			Size:     entry.Size,
+			Modified: entry.ModTime.UTC(),
+			SHA256:   entry.SHA256,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(FileListResponse{
+		Status:    "ok",
+		Directory: response.Directory,
+		Entries:   entries,
+	}); err != nil {
+		// Headers already sent by Encode's first Write call; cannot change response status
+		slog.Error("Failed to encode file list response", slog.String("error", err.Error()))
+	}
+}
+
+// GetFile handles GET /files/{path}, serving the file with Range and conditional request support.
+// The ETag is the file's SHA-256, the same value POST /files accepts in If-Match. A Range request
+// does not wait for a file to be hashed, so it gets no ETag unless the checksum is already known.
+func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
+	response := h.filesService.OpenFile(fileContext(r), chi.URLParam(r, "*"), r.Header.Get("Range") == "")
+	if response.Status == "error" {
+		writeFileOperationError(w, response.ErrorKind, response.Reason)
+		return
+	}
+	defer response.File.Close()
+
+	h.extendTransferDeadlines(w)
+	if response.SHA256 != "" {
+		w.Header().Set("ETag", `"`+response.SHA256+`"`)
+	}
+	http.ServeContent(w, r, path.Base(response.Path), response.ModTime, response.File)
+}
+
+// DeleteFile handles DELETE /files/{path}
+func (h *Handlers) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	response := h.filesService.DeleteFile(fileContext(r), chi.URLParam(r, "*"))
+	if response.Status == "error" {
+		writeFileOperationError(w, response.ErrorKind, response.Reason)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(FileDeleteResponse{Status: "ok", Path: response.Path}); err != nil {
+		// Headers already sent by Encode's first Write call; cannot change response status
+		slog.Error("Failed to encode file delete response", slog.String("error", err.Error()))
+	}
+}
+
+// writeFileOperationError writes the error of a file list, read or delete by its error kind
+func writeFileOperationError(w http.ResponseWriter, kind, reason string) {
+	switch kind {
+	case "validation":
+		writeValidationError(w, reason)
+	case "not_found":
+		writeNotFoundError(w, reason)
+	default:
+		writeInternalError(w, reason, nil)
+	}
+}
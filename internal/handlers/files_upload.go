@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"omnidrop/internal/services"
+)
+
+const (
+	// HeaderFilename and HeaderDirectory name the target of an application/octet-stream upload,
+	// HeaderContentSHA256 its expected checksum and HeaderFileMode its write mode; the filename,
+	// directory, content_sha256 and mode query parameters are accepted as well
+	HeaderFilename      = "X-Filename"
+	HeaderDirectory     = "X-Directory"
+	HeaderContentSHA256 = "X-Content-SHA256"
+	HeaderFileMode      = "X-File-Mode"
+
+	// maxUploadFieldSize limits the form fields of a multipart upload
+	maxUploadFieldSize = 1024
+)
+
+// IsFileTransfer reports whether r streams a file upload or download, which set their own
+// deadlines instead of the server's request timeout
+func IsFileTransfer(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost:
+		return r.URL.Path == "/files" && isStreamedUpload(r)
+	case http.MethodGet:
+		return strings.HasPrefix(r.URL.Path, "/files/")
+	}
+	return false
+}
+
+// isStreamedUpload reports whether r carries a multipart/form-data or application/octet-stream
+// body, which CreateFile streams to disk instead of decoding
+func isStreamedUpload(r *http.Request) bool {
+	mediaType, _, err := parseContentType(r)
+	return err == nil && (mediaType == "multipart/form-data" || mediaType == "application/octet-stream")
+}
+
+// parseContentType returns the request's media type, or "" when it has no Content-Type header
+func parseContentType(r *http.Request) (string, map[string]string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return "", nil, nil
+	}
+	return mime.ParseMediaType(contentType)
+}
+
+// createFileFromStream streams a raw application/octet-stream body into the file named by
+// the X-Filename header or filename query parameter
+func (h *Handlers) createFileFromStream(w http.ResponseWriter, r *http.Request) {
+	filename := headerOrQuery(r, HeaderFilename, "filename")
+	if filename == "" {
+		writeValidationError(w, "Filename is required in the X-Filename header or filename query parameter")
+		return
+	}
+
+	h.prepareUpload(w, r)
+	h.writeUploadedFile(w, r, services.FileWriteRequest{
+		Filename:      filename,
+		Directory:     headerOrQuery(r, HeaderDirectory, "directory"),
+		Body:          r.Body,
+		ContentSHA256: headerOrQuery(r, HeaderContentSHA256, "content_sha256"),
+		Mode:          headerOrQuery(r, HeaderFileMode, "mode"),
+		IfMatch:       ifMatch(r),
+	})
+}
+
+// createFileFromMultipart streams the "file" part of a multipart/form-data body. The optional
+// "filename", "directory", "content_sha256" and "mode" fields must precede it; without a
+// filename field the part's own file name is used.
+func (h *Handlers) createFileFromMultipart(w http.ResponseWriter, r *http.Request, boundary string) {
+	if boundary == "" {
+		writeValidationError(w, "multipart/form-data Content-Type must include a boundary")
+		return
+	}
+
+	h.prepareUpload(w, r)
+	reader := multipart.NewReader(r.Body, boundary)
+
+	fields := services.FileWriteRequest{IfMatch: ifMatch(r)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeValidationError(w, "Multipart body must include a part named 'file'")
+			return
+		}
+		if err != nil {
+			writeUploadReadError(w, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "filename":
+			fields.Filename, err = readUploadField(part)
+		case "directory":
+			fields.Directory, err = readUploadField(part)
+		case "content_sha256":
+			fields.ContentSHA256, err = readUploadField(part)
+		case "mode":
+			fields.Mode, err = readUploadField(part)
+		case "file":
+			if fields.Filename == "" {
+				fields.Filename = part.FileName()
+			}
+			if fields.Filename == "" {
+				writeValidationError(w, "Filename is required in the filename field or the file part")
+				return
+			}
+			fields.Body = part
+			h.writeUploadedFile(w, r, fields)
+			return
+		}
+		// Other parts are skipped by the next call to NextPart
+		if err != nil {
+			writeUploadReadError(w, err)
+			return
+		}
+	}
+}
+
+// prepareUpload limits the body to the configured upload size and extends the connection's deadlines
+func (h *Handlers) prepareUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.FilesMaxUploadSize)
+	h.extendTransferDeadlines(w)
+}
+
+// extendTransferDeadlines extends the connection's read and write deadlines so large uploads
+// and downloads are not cut off by the server timeouts
+func (h *Handlers) extendTransferDeadlines(w http.ResponseWriter) {
+	deadline := time.Now().Add(h.cfg.FilesUploadTimeout)
+	controller := http.NewResponseController(w)
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		slog.Debug("Could not extend file transfer read deadline", slog.String("error", err.Error()))
+	}
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		slog.Debug("Could not extend file transfer write deadline", slog.String("error", err.Error()))
+	}
+}
+
+func (h *Handlers) writeUploadedFile(w http.ResponseWriter, r *http.Request, req services.FileWriteRequest) {
+	writeFileResponse(w, h.filesService.WriteFile(fileContext(r), req))
+}
+
+// readUploadField reads a small multipart form field
+func readUploadField(part *multipart.Part) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxUploadFieldSize {
+		return "", errUploadFieldTooLong
+	}
+	return string(value), nil
+}
+
+var errUploadFieldTooLong = errors.New("multipart field is too long")
+
+// writeUploadReadError reports a failure to read a multipart body
+func writeUploadReadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeRequestTooLargeError(w, "Upload exceeds the configured size limit")
+	case errors.Is(err, errUploadFieldTooLong):
+		writeValidationError(w, "Multipart form fields must be at most 1024 bytes")
+	default:
+		writeValidationError(w, "Invalid multipart body")
+	}
+}
+
+func headerOrQuery(r *http.Request, header, param string) string {
+	if value := strings.TrimSpace(r.Header.Get(header)); value != "" {
+		return value
+	}
+	return r.URL.Query().Get(param)
+}
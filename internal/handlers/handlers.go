@@ -3,11 +3,20 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
+	"omnidrop/internal/auth"
+	"omnidrop/internal/config"
+	"omnidrop/internal/dateparse"
 	"omnidrop/internal/services"
+	"omnidrop/internal/templates"
 )
 
 const (
@@ -15,32 +24,59 @@ const (
 	MaxTaskRequestSize = 1 << 20
 	// MaxFileRequestSize is the maximum allowed request body size for file creation (10MB)
 	MaxFileRequestSize = 10 << 20
+
+	// defaultDueHour is applied to date-only due dates, matching the script's 18:00 default
+	defaultDueHour = 18
+	// defaultDeferHour is applied to date-only defer dates so the task becomes available at the start of the day
+	defaultDeferHour = 0
+
+	// MaxTaskDepth is the maximum nesting depth of a task hierarchy (a task without children has depth 1)
+	MaxTaskDepth = 5
+	// MaxTasksPerRequest is the maximum number of tasks, including subtasks, created by one request
+	MaxTasksPerRequest = 100
 )
 
 type TaskRequest struct {
-	Title   string   `json:"title"`
-	Note    string   `json:"note,omitempty"`
-	Project string   `json:"project,omitempty"`
-	Tags    []string `json:"tags,omitempty"`
+	Title            string        `json:"title"`
+	Note             string        `json:"note,omitempty"`
+	Project          string        `json:"project,omitempty"`
+	Tags             []string      `json:"tags,omitempty"`
+	DueDate          string        `json:"due_date,omitempty"`   // RFC 3339 or shorthand such as "tomorrow 9am"
+	DeferDate        string        `json:"defer_date,omitempty"` // RFC 3339 or shorthand such as "monday"
+	EstimatedMinutes int           `json:"estimated_minutes,omitempty"`
+	Flagged          bool          `json:"flagged,omitempty"`
+	Sequential       bool          `json:"sequential,omitempty"` // children must be completed in order
+	Children         []TaskRequest `json:"children,omitempty"`   // subtasks; makes this task an action group
 }
 
 type TaskResponse struct {
-	Status  string `json:"status"`
-	Created bool   `json:"created"`
-	Reason  string `json:"reason,omitempty"`
+	Status   string   `json:"status"`
+	Created  bool     `json:"created"`
+	ID       string   `json:"id,omitempty"`      // backend's ID of the created task, e.g. the OmniFocus task ID
+	TaskID   string   `json:"task_id,omitempty"` // queue ID when the task was accepted for retry
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"` // e.g. tags that could not be assigned
 }
 
 type Handlers struct {
-	version          string
-	omniFocusService services.OmniFocusServiceInterface
-	filesService     services.FilesServiceInterface
+	cfg          *config.Config
+	version      string
+	taskBackends *services.TaskBackendRegistry
+	filesService services.FilesServiceInterface
+	taskQueue    services.TaskQueue        // optional; nil disables queueing of failed tasks
+	templates    *templates.Repository     // optional; nil disables task templates
+	readiness    services.ReadinessChecker // optional; nil makes /health/ready report not ready
 }
 
-func New(version string, omniFocusService services.OmniFocusServiceInterface, filesService services.FilesServiceInterface) *Handlers {
+func New(cfg *config.Config, version string, taskBackends *services.TaskBackendRegistry, filesService services.FilesServiceInterface, taskQueue services.TaskQueue, templates *templates.Repository, readiness services.ReadinessChecker) *Handlers {
 	return &Handlers{
-		version:          version,
-		omniFocusService: omniFocusService,
-		filesService:     filesService,
+		cfg:          cfg,
+		version:      version,
+		taskBackends: taskBackends,
+		filesService: filesService,
+		taskQueue:    taskQueue,
+		templates:    templates,
+		readiness:    readiness,
 	}
 }
 
@@ -71,25 +107,63 @@ func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create task via OmniFocus service
-	response := h.omniFocusService.CreateTask(ctx, services.TaskCreateRequest{
-		Title:   taskReq.Title,
-		Note:    taskReq.Note,
-		Project: taskReq.Project,
-		Tags:    taskReq.Tags,
-	})
+	backend, err := h.selectBackend(r)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return
+	}
+
+	createReq, err := h.buildTaskCreateRequest(taskReq)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return
+	}
+	createReq.Backend = backend
+
+	h.submitTask(ctx, w, createReq)
+}
+
+// selectBackend picks the task backend for a request: the "backend" query parameter,
+// then the OAuth client's configured backend, then the server default
+func (h *Handlers) selectBackend(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("backend")
+	if name == "" {
+		if claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims); ok {
+			name = claims.Backend
+		}
+	}
+	if name == "" {
+		name = h.taskBackends.Default()
+	}
+
+	if !h.taskBackends.Has(name) {
+		return "", fmt.Errorf("unknown task backend '%s'; available backends: %s", name, strings.Join(h.taskBackends.Names(), ", "))
+	}
+	return name, nil
+}
+
+// submitTask creates a validated task and writes the response, queueing it for retry on failure
+func (h *Handlers) submitTask(ctx context.Context, w http.ResponseWriter, createReq services.TaskCreateRequest) {
+	// Create task via its backend
+	response := h.taskBackends.CreateTask(ctx, createReq)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	if response.Status == "error" {
-		writeAppleScriptError(w, response.Reason, nil)
+		if !h.queueable(response.Err) {
+			h.writeBackendError(w, response.Reason, response.Err)
+			return
+		}
+		h.queueTask(w, createReq, response.Reason)
 		return
 	}
 
 	taskResponse := TaskResponse{
-		Status:  response.Status,
-		Created: response.Created,
-		Reason:  response.Reason,
+		Status:   response.Status,
+		Created:  response.Created,
+		ID:       response.ID,
+		Reason:   response.Reason,
+		Warnings: response.Warnings,
 	}
 
 	if err := json.NewEncoder(w).Encode(taskResponse); err != nil {
@@ -98,12 +172,161 @@ func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// queueable reports whether a task that failed with err is handed to the retry queue.
+// A busy executor or an open circuit is back-pressure, not a delivery failure, so the client
+// retries after Retry-After instead of the queue; a task that may already exist or that the
+// script rejected is never retried.
+func (h *Handlers) queueable(err error) bool {
+	return h.taskQueue != nil && services.Retryable(err) && !rejected(err)
+}
+
+// rejected reports whether the backend refused to run a script at all
+func rejected(err error) bool {
+	return errors.Is(err, services.ErrExecutorBusy) || errors.Is(err, services.ErrCircuitOpen)
+}
+
+// writeBackendError writes the response for a failed backend call: 503 with Retry-After when
+// the AppleScript executor pool had no free slot or the OmniFocus circuit is open, 504 when
+// the task may have been created regardless, a client error when the script rejected the
+// request itself, otherwise an AppleScript error
+func (h *Handlers) writeBackendError(w http.ResponseWriter, message string, err error) {
+	var circuitErr *services.CircuitOpenError
+	var scriptErr *services.ScriptError
+	if errors.As(err, &scriptErr) {
+		switch scriptErr.Code {
+		case services.ScriptErrorInvalidArgument:
+			writeValidationError(w, message)
+			return
+		case services.ScriptErrorNotFound:
+			writeNotFoundError(w, message)
+			return
+		case services.ScriptErrorProjectNotFound, services.ScriptErrorParentNotCreated:
+			writeUnprocessableError(w, message)
+			return
+		}
+	}
+
+	switch {
+	case errors.Is(err, services.ErrExecutorBusy):
+		writeUnavailableError(w, message, h.cfg.AppleScriptQueueTimeout, err)
+	case errors.As(err, &circuitErr):
+		writeUnavailableError(w, message, circuitErr.RetryAfter, err)
+	case errors.Is(err, services.ErrOutcomeUnknown):
+		writeOutcomeUnknownError(w, message+"; the task may have been created, check before retrying", err)
+	default:
+		writeAppleScriptError(w, message, err)
+	}
+}
+
+// queueTask hands a task that OmniFocus rejected to the retry queue and answers 202 Accepted
+func (h *Handlers) queueTask(w http.ResponseWriter, req services.TaskCreateRequest, reason string) {
+	// Persisting must not depend on the request context, which may already be near its deadline
+	taskID, err := h.taskQueue.Enqueue(context.Background(), req)
+	if err != nil {
+		writeAppleScriptError(w, reason, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(TaskResponse{
+		Status:  "queued",
+		Created: false,
+		TaskID:  taskID,
+		Reason:  reason,
+	}); err != nil {
+		slog.Error("Failed to encode task response", slog.String("error", err.Error()))
+	}
+}
+
+// buildTaskCreateRequest validates a task and its subtasks, resolving dates in the
+// configured timezone and enforcing the hierarchy depth and size limits. The task gets the
+// delivery ID it keeps if it is queued for retry.
+func (h *Handlers) buildTaskCreateRequest(taskReq TaskRequest) (services.TaskCreateRequest, error) {
+	now := time.Now().In(h.cfg.Location())
+	req, err := h.buildTaskNode(taskReq, now, 1)
+	if err != nil {
+		return req, err
+	}
+	req.DeliveryID = uuid.NewString()
+	if count := req.TaskCount(); count > MaxTasksPerRequest {
+		return req, fmt.Errorf("task hierarchy contains %d tasks; the limit is %d", count, MaxTasksPerRequest)
+	}
+	return req, nil
+}
+
+func (h *Handlers) buildTaskNode(taskReq TaskRequest, now time.Time, depth int) (services.TaskCreateRequest, error) {
+	req := services.TaskCreateRequest{
+		Title:            taskReq.Title,
+		Note:             taskReq.Note,
+		Project:          taskReq.Project,
+		Tags:             taskReq.Tags,
+		EstimatedMinutes: taskReq.EstimatedMinutes,
+		Flagged:          taskReq.Flagged,
+		Sequential:       taskReq.Sequential,
+	}
+
+	if taskReq.EstimatedMinutes < 0 {
+		return req, fmt.Errorf("estimated_minutes cannot be negative")
+	}
+
+	if taskReq.DueDate != "" {
+		due, err := dateparse.Parse(taskReq.DueDate, now, defaultDueHour)
+		if err != nil {
+			return req, fmt.Errorf("invalid due_date: %v", err)
+		}
+		req.DueDate = &due
+	}
+
+	if taskReq.DeferDate != "" {
+		deferDate, err := dateparse.Parse(taskReq.DeferDate, now, defaultDeferHour)
+		if err != nil {
+			return req, fmt.Errorf("invalid defer_date: %v", err)
+		}
+		req.DeferDate = &deferDate
+	}
+
+	if req.DueDate != nil && req.DeferDate != nil && req.DeferDate.After(*req.DueDate) {
+		return req, fmt.Errorf("defer_date cannot be later than due_date")
+	}
+
+	if len(taskReq.Children) == 0 {
+		return req, nil
+	}
+	if depth >= MaxTaskDepth {
+		return req, fmt.Errorf("task hierarchy cannot be deeper than %d levels", MaxTaskDepth)
+	}
+
+	req.Children = make([]services.TaskCreateRequest, len(taskReq.Children))
+	for i, childReq := range taskReq.Children {
+		if childReq.Title == "" {
+			return req, fmt.Errorf("children[%d]: title is required and cannot be empty", i)
+		}
+		if childReq.Project != "" {
+			return req, fmt.Errorf("children[%d]: project can only be set on top-level tasks", i)
+		}
+		child, err := h.buildTaskNode(childReq, now, depth+1)
+		if err != nil {
+			return req, fmt.Errorf("children[%d]: %w", i, err)
+		}
+		req.Children[i] = child
+	}
+
+	return req, nil
+}
+
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{
+	health := map[string]string{
 		"status":  "ok",
 		"version": h.version,
-	}); err != nil {
+	}
+	if backend, ok := h.taskBackends.Backend(services.BackendOmniFocus); ok {
+		if reporter, ok := backend.(services.CircuitReporter); ok && reporter.CircuitState() != "" {
+			health["omnifocus_circuit"] = string(reporter.CircuitState())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(health); err != nil {
 		// Headers already sent by Encode's first Write call; cannot change response status
 		slog.Error("Failed to encode health response", slog.String("error", err.Error()))
 	}
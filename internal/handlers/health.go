@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"omnidrop/internal/errors"
+	"omnidrop/internal/services"
+)
+
+// ReadinessResponse is returned by GET /health/ready
+type ReadinessResponse struct {
+	Status    string          `json:"status"` // "ready" or "degraded"
+	Version   string          `json:"version"`
+	CheckedAt time.Time       `json:"checked_at"`
+	Checks    ReadinessChecks `json:"checks"`
+	Errors    []string        `json:"errors,omitempty"`
+}
+
+// ReadinessChecks lists the outcome of each readiness check
+type ReadinessChecks struct {
+	TaskBackends          []string       `json:"task_backends"`
+	AppleScriptAccessible *bool          `json:"applescript_accessible,omitempty"` // only checked when omnifocus is in use
+	OmniFocusRunning      *bool          `json:"omnifocus_running,omitempty"`
+	FilesDirWritable      bool           `json:"files_dir_writable"`
+	OAuthClients          string         `json:"oauth_clients"` // "loaded", "disabled" or "failed"
+	Queue                 ReadinessQueue `json:"queue"`
+}
+
+// ReadinessQueue reports the task queue state
+type ReadinessQueue struct {
+	State            string  `json:"state"` // "enabled", "disabled" or "failed"
+	Depth            int     `json:"depth"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+}
+
+// Ready handles GET /health/ready, answering 503 when any dependency is degraded
+func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.readiness == nil {
+		writeErrorResponse(w, http.StatusServiceUnavailable, errors.ErrorCodeUnavailable, "Readiness checks are not configured", nil)
+		return
+	}
+
+	report := h.readiness.Check()
+	response := ReadinessResponse{
+		Status:    "ready",
+		Version:   h.version,
+		CheckedAt: report.CheckedAt,
+		Checks: ReadinessChecks{
+			TaskBackends:     report.TaskBackends,
+			FilesDirWritable: report.FilesDirWritable,
+			OAuthClients:     report.OAuthClients,
+			Queue: ReadinessQueue{
+				State:            report.Queue,
+				Depth:            report.QueueStats.Depth,
+				OldestAgeSeconds: report.QueueStats.OldestAge.Seconds(),
+			},
+		},
+		Errors: report.Errors,
+	}
+	if slices.Contains(report.TaskBackends, services.BackendOmniFocus) {
+		response.Checks.AppleScriptAccessible = &report.AppleScriptAccessible
+		response.Checks.OmniFocusRunning = &report.OmniFocusRunning
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		response.Status = "degraded"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// Headers already sent; cannot change response status
+		slog.Error("Failed to encode readiness response", slog.String("error", err.Error()))
+	}
+}
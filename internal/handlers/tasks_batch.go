@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"omnidrop/internal/services"
+)
+
+// MaxBatchSize is the maximum number of tasks accepted by a single batch request
+const MaxBatchSize = 100
+
+// TaskBatchResult reports the outcome of one task in a batch, by its position in the request
+type TaskBatchResult struct {
+	Index    int      `json:"index"`
+	Status   string   `json:"status"` // "ok", "queued" or "error"
+	Created  bool     `json:"created"`
+	ID       string   `json:"id,omitempty"` // backend's ID of the created task
+	TaskID   string   `json:"task_id,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// TaskBatchResponse is returned by POST /tasks/batch
+type TaskBatchResponse struct {
+	Status  string            `json:"status"` // "ok" (all created), "error" (none created or queued) or "partial"
+	Results []TaskBatchResult `json:"results"`
+}
+
+// CreateTaskBatch handles POST requests that create several tasks with one backend call
+// (a single AppleScript run for OmniFocus).
+// All tasks are validated before any is created. Responds 200 when every task was created
+// and 207 Multi-Status otherwise, with per-task results.
+func (h *Handlers) CreateTaskBatch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowedError(w, "Only POST method is allowed for batch task creation")
+		return
+	}
+
+	// Limit request body size to prevent memory exhaustion
+	r.Body = http.MaxBytesReader(w, r.Body, MaxTaskRequestSize)
+
+	var taskReqs []TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&taskReqs); err != nil {
+		writeValidationError(w, "Invalid JSON format in request body: expected an array of tasks")
+		return
+	}
+
+	if len(taskReqs) == 0 {
+		writeValidationError(w, "Batch must contain at least one task")
+		return
+	}
+	if len(taskReqs) > MaxBatchSize {
+		writeValidationError(w, fmt.Sprintf("Batch cannot contain more than %d tasks", MaxBatchSize))
+		return
+	}
+
+	backend, err := h.selectBackend(r)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return
+	}
+
+	// Validate every task up front so a bad item never leaves a half-created batch
+	createReqs := make([]services.TaskCreateRequest, len(taskReqs))
+	total := 0
+	for i, taskReq := range taskReqs {
+		if taskReq.Title == "" {
+			writeValidationError(w, fmt.Sprintf("tasks[%d]: Title field is required and cannot be empty", i))
+			return
+		}
+		createReq, err := h.buildTaskCreateRequest(taskReq)
+		if err != nil {
+			writeValidationError(w, fmt.Sprintf("tasks[%d]: %v", i, err))
+			return
+		}
+		createReq.Backend = backend
+		createReqs[i] = createReq
+		total += createReq.TaskCount()
+	}
+	if total > MaxTasksPerRequest {
+		writeValidationError(w, fmt.Sprintf("Batch contains %d tasks including subtasks; the limit is %d", total, MaxTasksPerRequest))
+		return
+	}
+
+	responses := h.taskBackends.CreateTasks(ctx, createReqs)
+
+	batchResponse := TaskBatchResponse{Results: make([]TaskBatchResult, len(createReqs))}
+	created, queued, refused := 0, 0, 0
+	for i, response := range responses {
+		if rejected(response.Err) {
+			refused++
+		}
+		result := TaskBatchResult{
+			Index:    i,
+			Status:   response.Status,
+			Created:  response.Created,
+			ID:       response.ID,
+			Reason:   response.Reason,
+			Warnings: response.Warnings,
+		}
+
+		if response.Status == "error" && h.queueable(response.Err) {
+			// Persisting must not depend on the request context, which may already be near its deadline
+			if taskID, err := h.taskQueue.Enqueue(context.Background(), createReqs[i]); err == nil {
+				result.Status = "queued"
+				result.TaskID = taskID
+			} else {
+				slog.Error("Failed to queue batch task",
+					slog.Int("index", i),
+					slog.String("error", err.Error()))
+			}
+		}
+
+		switch {
+		case result.Created:
+			created++
+		case result.Status == "queued":
+			queued++
+		}
+		batchResponse.Results[i] = result
+	}
+
+	// Nothing was attempted when the executor pool had no free slot or the circuit was open;
+	// ask the client to retry the whole batch
+	if refused == len(createReqs) {
+		h.writeBackendError(w, responses[0].Reason, responses[0].Err)
+		return
+	}
+
+	statusCode := http.StatusMultiStatus
+	switch created {
+	case len(createReqs):
+		batchResponse.Status = "ok"
+		statusCode = http.StatusOK
+	case 0:
+		if queued == 0 {
+			batchResponse.Status = "error"
+		} else {
+			batchResponse.Status = "partial"
+		}
+	default:
+		batchResponse.Status = "partial"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(batchResponse); err != nil {
+		// Headers already sent; cannot change response status
+		slog.Error("Failed to encode batch task response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"omnidrop/internal/dateparse"
+	"omnidrop/internal/services"
+)
+
+const (
+	// DefaultTaskListLimit is the number of tasks GET /tasks returns when no limit is given
+	DefaultTaskListLimit = 100
+	// MaxTaskListLimit is the largest limit GET /tasks accepts
+	MaxTaskListLimit = 1000
+
+	// queryDateHour is applied to date-only due_before and due_after filters (start of the day)
+	queryDateHour = 0
+)
+
+// taskIDPattern matches OmniFocus task IDs
+var taskIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// TaskListResponse is returned by GET /tasks
+type TaskListResponse struct {
+	Status string          `json:"status"`
+	Count  int             `json:"count"`
+	Tasks  []services.Task `json:"tasks"`
+}
+
+// TaskGetResponse is returned by GET /tasks/{id}
+type TaskGetResponse struct {
+	Status string        `json:"status"`
+	Task   services.Task `json:"task"`
+}
+
+// ListTasks handles GET /tasks. Filters are passed as query parameters:
+// project, tag, flagged, completed (true, false or any; default false), due_before,
+// due_after, q (title or note search), inbox and limit.
+func (h *Handlers) ListTasks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	reader, ok := h.selectTaskReader(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := h.parseTaskQuery(r)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return
+	}
+
+	response := reader.ListTasks(ctx, query)
+	if response.Status == "error" {
+		h.writeBackendError(w, response.Reason, response.Err)
+		return
+	}
+
+	tasks := response.Tasks
+	if tasks == nil {
+		tasks = []services.Task{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TaskListResponse{
+		Status: "ok",
+		Count:  len(tasks),
+		Tasks:  tasks,
+	}); err != nil {
+		// Headers already sent by Encode's first Write call; cannot change response status
+		slog.Error("Failed to encode task list response", slog.String("error", err.Error()))
+	}
+}
+
+// GetTask handles GET /tasks/{id}
+func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	id := chi.URLParam(r, "id")
+	if !taskIDPattern.MatchString(id) {
+		writeValidationError(w, "Invalid task ID")
+		return
+	}
+
+	reader, ok := h.selectTaskReader(w, r)
+	if !ok {
+		return
+	}
+
+	response := reader.GetTask(ctx, id)
+	if response.Status == "error" {
+		h.writeBackendError(w, response.Reason, response.Err)
+		return
+	}
+	if !response.Found {
+		writeNotFoundError(w, fmt.Sprintf("Task '%s' not found", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TaskGetResponse{
+		Status: "ok",
+		Task:   response.Task,
+	}); err != nil {
+		slog.Error("Failed to encode task response", slog.String("error", err.Error()))
+	}
+}
+
+// selectTaskReader resolves the request's backend and checks that it can read tasks back.
+// It writes the error response and returns false when it cannot.
+func (h *Handlers) selectTaskReader(w http.ResponseWriter, r *http.Request) (services.TaskReader, bool) {
+	name, err := h.selectBackend(r)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return nil, false
+	}
+
+	backend, _ := h.taskBackends.Backend(name)
+	reader, ok := backend.(services.TaskReader)
+	if !ok {
+		writeNotSupportedError(w, fmt.Sprintf("Task backend '%s' does not support reading tasks", name))
+		return nil, false
+	}
+	return reader, true
+}
+
+// parseTaskQuery builds a task query from the request's query parameters
+func (h *Handlers) parseTaskQuery(r *http.Request) (services.TaskQuery, error) {
+	params := r.URL.Query()
+	now := time.Now().In(h.cfg.Location())
+
+	completed := false
+	query := services.TaskQuery{
+		Project:   params.Get("project"),
+		Tag:       params.Get("tag"),
+		Search:    params.Get("q"),
+		Completed: &completed,
+		Limit:     DefaultTaskListLimit,
+	}
+
+	if value := params.Get("completed"); value != "" {
+		if value == "any" {
+			query.Completed = nil
+		} else {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return query, fmt.Errorf("invalid completed: must be true, false or any")
+			}
+			query.Completed = &parsed
+		}
+	}
+
+	if value := params.Get("flagged"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid flagged: must be true or false")
+		}
+		query.Flagged = &parsed
+	}
+
+	if value := params.Get("inbox"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid inbox: must be true or false")
+		}
+		query.Inbox = parsed
+	}
+
+	if value := params.Get("due_before"); value != "" {
+		dueBefore, err := dateparse.Parse(value, now, queryDateHour)
+		if err != nil {
+			return query, fmt.Errorf("invalid due_before: %v", err)
+		}
+		query.DueBefore = &dueBefore
+	}
+
+	if value := params.Get("due_after"); value != "" {
+		dueAfter, err := dateparse.Parse(value, now, queryDateHour)
+		if err != nil {
+			return query, fmt.Errorf("invalid due_after: %v", err)
+		}
+		query.DueAfter = &dueAfter
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxTaskListLimit {
+			return query, fmt.Errorf("invalid limit: must be between 1 and %d", MaxTaskListLimit)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
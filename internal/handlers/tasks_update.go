@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"omnidrop/internal/dateparse"
+	"omnidrop/internal/services"
+)
+
+// TaskPatchRequest is the body of PATCH /tasks/{id}. Omitted fields are left unchanged.
+type TaskPatchRequest struct {
+	Title     *string         `json:"title,omitempty"`
+	Note      *string         `json:"note,omitempty"`
+	Project   *string         `json:"project,omitempty"`    // moves the task to this project
+	Tags      *[]string       `json:"tags,omitempty"`       // replaces every tag; [] removes them all
+	DueDate   json.RawMessage `json:"due_date,omitempty"`   // same formats as POST /tasks; null removes the date
+	DeferDate json.RawMessage `json:"defer_date,omitempty"` // same formats as POST /tasks; null removes the date
+	Flagged   *bool           `json:"flagged,omitempty"`
+}
+
+// TaskActionResponse is returned by PATCH /tasks/{id}, POST /tasks/{id}/complete and DELETE /tasks/{id}
+type TaskActionResponse struct {
+	Status   string   `json:"status"`
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// UpdateTask handles PATCH /tasks/{id}
+func (h *Handlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	id := chi.URLParam(r, "id")
+	if !taskIDPattern.MatchString(id) {
+		writeValidationError(w, "Invalid task ID")
+		return
+	}
+
+	// Limit request body size to prevent memory exhaustion
+	r.Body = http.MaxBytesReader(w, r.Body, MaxTaskRequestSize)
+
+	var patchReq TaskPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchReq); err != nil {
+		writeValidationError(w, "Invalid JSON format in request body")
+		return
+	}
+
+	updateReq, err := h.buildTaskUpdateRequest(patchReq)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return
+	}
+
+	updater, ok := h.selectTaskUpdater(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeTaskAction(w, id, updater.UpdateTask(ctx, id, updateReq))
+}
+
+// CompleteTask handles POST /tasks/{id}/complete
+func (h *Handlers) CompleteTask(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	id := chi.URLParam(r, "id")
+	if !taskIDPattern.MatchString(id) {
+		writeValidationError(w, "Invalid task ID")
+		return
+	}
+
+	updater, ok := h.selectTaskUpdater(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeTaskAction(w, id, updater.CompleteTask(ctx, id))
+}
+
+// DeleteTask handles DELETE /tasks/{id}
+func (h *Handlers) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	id := chi.URLParam(r, "id")
+	if !taskIDPattern.MatchString(id) {
+		writeValidationError(w, "Invalid task ID")
+		return
+	}
+
+	updater, ok := h.selectTaskUpdater(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeTaskAction(w, id, updater.DeleteTask(ctx, id))
+}
+
+// selectTaskUpdater resolves the request's backend and checks that it can change existing tasks.
+// It writes the error response and returns false when it cannot.
+func (h *Handlers) selectTaskUpdater(w http.ResponseWriter, r *http.Request) (services.TaskUpdater, bool) {
+	name, err := h.selectBackend(r)
+	if err != nil {
+		writeValidationError(w, err.Error())
+		return nil, false
+	}
+
+	backend, _ := h.taskBackends.Backend(name)
+	updater, ok := backend.(services.TaskUpdater)
+	if !ok {
+		writeNotSupportedError(w, fmt.Sprintf("Task backend '%s' does not support changing tasks", name))
+		return nil, false
+	}
+	return updater, true
+}
+
+func (h *Handlers) writeTaskAction(w http.ResponseWriter, id string, response services.TaskActionResponse) {
+	if response.Status == "error" {
+		h.writeBackendError(w, response.Reason, response.Err)
+		return
+	}
+	if !response.Found {
+		writeNotFoundError(w, fmt.Sprintf("Task '%s' not found", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TaskActionResponse{Status: "ok", ID: id, Warnings: response.Warnings}); err != nil {
+		// Headers already sent by Encode's first Write call; cannot change response status
+		slog.Error("Failed to encode task response", slog.String("error", err.Error()))
+	}
+}
+
+// buildTaskUpdateRequest validates a patch, resolving dates in the configured timezone
+func (h *Handlers) buildTaskUpdateRequest(patchReq TaskPatchRequest) (services.TaskUpdateRequest, error) {
+	req := services.TaskUpdateRequest{
+		Title:   patchReq.Title,
+		Note:    patchReq.Note,
+		Project: patchReq.Project,
+		Tags:    patchReq.Tags,
+		Flagged: patchReq.Flagged,
+	}
+
+	if req.Title != nil && *req.Title == "" {
+		return req, fmt.Errorf("title cannot be empty")
+	}
+	if req.Project != nil && *req.Project == "" {
+		return req, fmt.Errorf("project cannot be empty; tasks cannot be moved back to the inbox")
+	}
+
+	now := time.Now().In(h.cfg.Location())
+	var err error
+	if req.DueDate, req.ClearDueDate, err = parsePatchDate(patchReq.DueDate, now, defaultDueHour); err != nil {
+		return req, fmt.Errorf("invalid due_date: %v", err)
+	}
+	if req.DeferDate, req.ClearDeferDate, err = parsePatchDate(patchReq.DeferDate, now, defaultDeferHour); err != nil {
+		return req, fmt.Errorf("invalid defer_date: %v", err)
+	}
+	if req.DueDate != nil && req.DeferDate != nil && req.DeferDate.After(*req.DueDate) {
+		return req, fmt.Errorf("defer_date cannot be later than due_date")
+	}
+
+	if req.Title == nil && req.Note == nil && req.Project == nil && req.Tags == nil &&
+		req.DueDate == nil && !req.ClearDueDate && req.DeferDate == nil && !req.ClearDeferDate && req.Flagged == nil {
+		return req, fmt.Errorf("request body must change at least one field")
+	}
+	return req, nil
+}
+
+// parsePatchDate interprets a patch date field: absent leaves the date unchanged,
+// null clears it and a string sets it
+func parsePatchDate(raw json.RawMessage, now time.Time, defaultHour int) (*time.Time, bool, error) {
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+	if string(raw) == "null" {
+		return nil, true, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false, fmt.Errorf("must be a string or null")
+	}
+	t, err := dateparse.Parse(value, now, defaultHour)
+	if err != nil {
+		return nil, false, err
+	}
+	return &t, false, nil
+}
@@ -0,0 +1,246 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"omnidrop/internal/auth"
+	"omnidrop/internal/errors"
+)
+
+const (
+	// HeaderKey is the request header carrying the client-chosen idempotency key
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed marks responses served from the store
+	HeaderReplayed = "Idempotent-Replayed"
+
+	// MaxKeyLength bounds the accepted Idempotency-Key header value
+	MaxKeyLength = 255
+
+	// legacyClientID scopes keys for requests authenticated without OAuth claims
+	legacyClientID = "legacy"
+)
+
+// Middleware replays stored responses for repeated Idempotency-Key requests
+type Middleware struct {
+	store  *Store
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+// NewMiddleware creates idempotency middleware backed by store
+func NewMiddleware(store *Store, logger *slog.Logger) *Middleware {
+	return &Middleware{
+		store:    store,
+		logger:   logger,
+		inFlight: make(map[string]struct{}),
+	}
+}
+
+// Handle wraps a handler with Idempotency-Key support. Requests without the header pass through.
+// Request bodies are buffered to be fingerprinted, so maxBodyBytes should be the route's own
+// body limit; larger bodies are rejected. Use HandleStream for uploads that may be larger.
+func (m *Middleware) Handle(next http.Handler, maxBodyBytes int64) http.Handler {
+	return m.handle(next, maxBodyBytes, false)
+}
+
+// HandleStream is Handle for streamed uploads, whose bodies are too large to buffer. They are
+// fingerprinted by the headers describing the body instead of the body itself, so clients
+// should send X-Content-SHA256 to have a reused key with different content rejected.
+func (m *Middleware) HandleStream(next http.Handler) http.Handler {
+	return m.handle(next, 0, true)
+}
+
+func (m *Middleware) handle(next http.Handler, maxBodyBytes int64, streamed bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > MaxKeyLength {
+			writeError(w, http.StatusBadRequest, errors.ErrorCodeValidation, "Idempotency-Key header is too long")
+			return
+		}
+
+		var requestHash string
+		if streamed {
+			requestHash = streamFingerprint(r)
+		} else {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				writeError(w, http.StatusRequestEntityTooLarge, errors.ErrorCodeValidation, "Request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			requestHash = fingerprint(r, body)
+		}
+
+		clientID := clientIDFromRequest(r)
+
+		if m.replayStored(w, r, clientID, key, requestHash) {
+			return
+		}
+
+		lockKey := clientID + "\x00" + key
+		if !m.acquire(lockKey) {
+			writeError(w, http.StatusConflict, errors.ErrorCodeIdempotencyInProgress,
+				"A request with this Idempotency-Key is still being processed")
+			return
+		}
+		defer m.release(lockKey)
+
+		// A request with the same key may have completed between the lookup and acquire
+		if m.replayStored(w, r, clientID, key, requestHash) {
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// Server errors are not stored so the client can retry them
+		if rec.statusCode >= http.StatusInternalServerError {
+			return
+		}
+
+		if err := m.store.Put(&Record{
+			ClientID:    clientID,
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  rec.statusCode,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+			CreatedAt:   m.store.now(),
+		}); err != nil {
+			m.logger.Error("Failed to store idempotent response",
+				slog.String("client_id", clientID),
+				slog.String("error", err.Error()))
+		}
+	})
+}
+
+// replayStored answers the request from the stored response for key, if there is one,
+// and reports whether it did
+func (m *Middleware) replayStored(w http.ResponseWriter, r *http.Request, clientID, key, requestHash string) bool {
+	record, ok := m.store.Get(clientID, key)
+	if !ok {
+		return false
+	}
+	if record.RequestHash != requestHash {
+		writeError(w, http.StatusUnprocessableEntity, errors.ErrorCodeIdempotencyMismatch,
+			"Idempotency-Key was already used with a different request")
+		return true
+	}
+	m.logger.Info("🔁 Replaying idempotent response",
+		slog.String("client_id", clientID),
+		slog.String("path", r.URL.Path))
+	replay(w, record)
+	return true
+}
+
+func (m *Middleware) acquire(lockKey string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, busy := m.inFlight[lockKey]; busy {
+		return false
+	}
+	m.inFlight[lockKey] = struct{}{}
+	return true
+}
+
+func (m *Middleware) release(lockKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inFlight, lockKey)
+}
+
+// clientIDFromRequest scopes keys per OAuth client; legacy token requests share one namespace
+func clientIDFromRequest(r *http.Request) string {
+	if claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims); ok && claims.ClientID != "" {
+		return claims.ClientID
+	}
+	return legacyClientID
+}
+
+// fingerprint identifies the request by method, path, query and body
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// streamHeaders describe a streamed upload's body: its target, write mode, precondition and
+// checksum. The multipart boundary is left out because clients choose a new one per attempt.
+var streamHeaders = []string{"X-Filename", "X-Directory", "X-File-Mode", "X-Content-SHA256", "If-Match"}
+
+// streamFingerprint identifies a streamed upload by method, path, query, media type,
+// length and the headers describing its body
+func streamFingerprint(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write([]byte(mediaType + "\n" + strconv.FormatInt(r.ContentLength, 10) + "\n"))
+	for _, name := range streamHeaders {
+		h.Write([]byte(name + ": " + r.Header.Get(name) + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, record *Record) {
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code errors.ErrorCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"status":  "error",
+		"message": message,
+		"code":    string(code),
+	}); err != nil {
+		slog.Error("Failed to encode idempotency error response", slog.String("error", err.Error()))
+	}
+}
+
+// recorder captures the status and body written by the wrapped handler
+type recorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.statusCode = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
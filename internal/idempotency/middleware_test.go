@@ -0,0 +1,273 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/auth"
+	"omnidrop/internal/observability"
+)
+
+// testBodyLimit is the buffered body limit of the routes under test
+const testBodyLimit = 1 << 20
+
+func newTestMiddleware(t *testing.T, ttl time.Duration) (*Middleware, *Store) {
+	t.Helper()
+	store, err := NewStore(t.TempDir(), ttl)
+	require.NoError(t, err)
+	return NewMiddleware(store, observability.SetupLogger()), store
+}
+
+// countingHandler creates a handler that returns a distinct body per invocation
+func countingHandler(calls *int32, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `}`))
+	})
+}
+
+func newRequest(clientID, key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	if clientID != "" {
+		ctx := context.WithValue(req.Context(), auth.ContextKeyClaims, &auth.Claims{ClientID: clientID})
+		req = req.WithContext(ctx)
+	}
+	return req
+}
+
+func TestMiddleware_ReplaysFirstResponse(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, newRequest("client-a", "key-1", `{"title":"A"}`))
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, newRequest("client-a", "key-1", `{"title":"A"}`))
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(HeaderReplayed))
+}
+
+func TestMiddleware_DifferentBodyReturns422(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{"title":"A"}`))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest("client-a", "key-1", `{"title":"B"}`))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "idempotency_key_reused")
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestMiddleware_KeysAreScopedPerClient(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "shared", `{"title":"A"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-b", "shared", `{"title":"B"}`))
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestMiddleware_WithoutHeaderPassesThrough(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "", `{"title":"A"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "", `{"title":"A"}`))
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestMiddleware_ServerErrorsAreNotStored(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusInternalServerError), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{"title":"A"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{"title":"A"}`))
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestMiddleware_ExpiredRecordIsNotReplayed(t *testing.T) {
+	m, store := newTestMiddleware(t, time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{"title":"A"}`))
+	now = now.Add(2 * time.Minute)
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{"title":"B"}`))
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestMiddleware_ConcurrentDuplicateReturns409(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}), testBodyLimit)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{}`))
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest("client-a", "key-1", `{}`))
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestMiddleware_ConcurrentDuplicatesRunHandlerOnce(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	// Requests that looked up the key before the first one stored its response must not run again
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			handler.ServeHTTP(httptest.NewRecorder(), newRequest("client-a", "key-1", `{}`))
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestMiddleware_RejectsBodyOverRouteLimit(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), 16)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest("client-a", "key-1", `{"title":"seventeen"}`))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, int32(0), calls)
+}
+
+func TestMiddleware_StreamedUploadIsNotBuffered(t *testing.T) {
+	store, err := NewStore(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	m := NewMiddleware(store, observability.SetupLogger())
+
+	var calls int32
+	var received int64
+	handler := m.HandleStream(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		n, err := io.Copy(io.Discard, r.Body)
+		require.NoError(t, err)
+		received = n
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	body := bytes.Repeat([]byte("x"), 11<<20)
+	upload := func(checksum string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("X-Filename", "large.bin")
+		req.Header.Set("X-Content-SHA256", checksum)
+		req.Header.Set(HeaderKey, "upload-1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := upload("aaaa")
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, int64(len(body)), received)
+
+	second := upload("aaaa")
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, "true", second.Header().Get(HeaderReplayed))
+
+	// A different checksum means different content under the same key
+	third := upload("bbbb")
+	assert.Equal(t, http.StatusUnprocessableEntity, third.Code)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestMiddleware_LegacyRequestsShareNamespace(t *testing.T) {
+	m, _ := newTestMiddleware(t, time.Hour)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls, http.StatusOK), testBodyLimit)
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("", "key-1", `{"title":"A"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest("", "key-1", `{"title":"A"}`))
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, "true", rr.Header().Get(HeaderReplayed))
+}
+
+func TestStore_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(&Record{
+		ClientID:    "client-a",
+		Key:         "key-1",
+		RequestHash: "abc",
+		StatusCode:  http.StatusAccepted,
+		Body:        []byte(`{"status":"queued"}`),
+		CreatedAt:   time.Now(),
+	}))
+
+	reopened, err := NewStore(dir, time.Hour)
+	require.NoError(t, err)
+
+	record, ok := reopened.Get("client-a", "key-1")
+	require.True(t, ok)
+	assert.Equal(t, http.StatusAccepted, record.StatusCode)
+	assert.Equal(t, `{"status":"queued"}`, string(record.Body))
+
+	_, ok = reopened.Get("client-b", "key-1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,128 @@
+// Package idempotency implements Idempotency-Key handling: the first response
+// for a key is persisted per OAuth client and replayed for repeated requests.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// purgeInterval limits how often expired records are swept from disk
+const purgeInterval = time.Hour
+
+// Record is a stored response for one client's Idempotency-Key
+type Record struct {
+	ClientID    string    `json:"client_id"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store persists records as one JSON file per (client, key) so they survive restarts
+type Store struct {
+	dir       string
+	ttl       time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewStore creates a store rooted at dir that keeps records for ttl
+func NewStore(dir string, ttl time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency directory: %w", err)
+	}
+	return &Store{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+// Get returns the unexpired record for the client's key, if any
+func (s *Store) Get(clientID, key string) (*Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(clientID, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil || s.expired(&record) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	return &record, true
+}
+
+// Put stores a record, replacing any previous one for the same client and key
+func (s *Store) Put(record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	path := s.path(record.ClientID, record.Key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write idempotency record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write idempotency record: %w", err)
+	}
+
+	if s.now().Sub(s.lastPurge) >= purgeInterval {
+		s.purgeLocked()
+	}
+	return nil
+}
+
+// purgeLocked removes expired records; callers must hold s.mu
+func (s *Store) purgeLocked() {
+	s.lastPurge = s.now()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		// Records are written once, so the file mtime is the creation time
+		if s.now().Sub(info.ModTime()) > s.ttl {
+			_ = os.Remove(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+}
+
+func (s *Store) expired(record *Record) bool {
+	return s.now().Sub(record.CreatedAt) > s.ttl
+}
+
+// path hashes the client and key so arbitrary header values never reach the filesystem
+func (s *Store) path(clientID, key string) string {
+	sum := sha256.Sum256([]byte(clientID + "\x00" + key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -51,6 +51,22 @@ var (
 		[]string{"status"}, // success, failure
 	)
 
+	TaskBackendRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "omnidrop_task_backend_requests_total",
+			Help: "Total number of task creation attempts per task backend",
+		},
+		[]string{"backend", "status"}, // status: success, failure
+	)
+
+	TaskModificationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "omnidrop_task_modifications_total",
+			Help: "Total number of task update, completion and deletion attempts",
+		},
+		[]string{"operation", "status"}, // operation: update, complete, delete; status: success, not_found, failure
+	)
+
 	TaskCreationDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "omnidrop_task_creation_duration_seconds",
@@ -98,6 +114,46 @@ var (
 		},
 	)
 
+	FileOperationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "omnidrop_file_operations_total",
+			Help: "Total number of file list, read and delete attempts",
+		},
+		[]string{"operation", "status"}, // operation: list, read, delete; status: success, not_found, failure
+	)
+
+	FileUsageBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_file_usage_bytes",
+			Help: "Total size of an OAuth client's files, updated on each write and delete",
+		},
+		[]string{"client_id"},
+	)
+
+	FileUsageFiles = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_file_usage_files",
+			Help: "Number of an OAuth client's files, updated on each write and delete",
+		},
+		[]string{"client_id"},
+	)
+
+	FileQuotaBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_file_quota_bytes",
+			Help: "Byte quota of an OAuth client's files; absent without a limit",
+		},
+		[]string{"client_id"},
+	)
+
+	FileQuotaFiles = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_file_quota_files",
+			Help: "File count quota of an OAuth client; absent without a limit",
+		},
+		[]string{"client_id"},
+	)
+
 	// AppleScript Metrics
 	AppleScriptExecutionsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -115,12 +171,102 @@ var (
 		},
 	)
 
+	AppleScriptQueueWaitDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "omnidrop_applescript_queue_wait_seconds",
+			Help:    "Time spent waiting for a free AppleScript execution slot in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	AppleScriptInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_applescript_in_flight",
+			Help: "Number of AppleScript executions currently running",
+		},
+	)
+
+	AppleScriptQueued = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_applescript_queued",
+			Help: "Number of AppleScript executions waiting for a free slot",
+		},
+	)
+
+	AppleScriptRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "omnidrop_applescript_rejections_total",
+			Help: "Total number of AppleScript executions rejected because the executor pool was busy",
+		},
+		[]string{"reason"}, // queue_full, timeout
+	)
+
 	AppleScriptErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "omnidrop_applescript_errors_total",
 			Help: "Total number of AppleScript errors by type",
 		},
-		[]string{"error_type"}, // compilation, runtime, unknown
+		[]string{"error_type"}, // compilation, runtime, timeout, busy, circuit_open, unknown, or an error code reported by the script
+	)
+
+	// OmniFocus Circuit Breaker Metrics
+	OmniFocusCircuitState = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_omnifocus_circuit_state",
+			Help: "State of the OmniFocus circuit breaker (0 = closed, 1 = half-open, 2 = open)",
+		},
+	)
+
+	OmniFocusCircuitRejectionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "omnidrop_omnifocus_circuit_rejections_total",
+			Help: "Total number of AppleScript executions rejected because the OmniFocus circuit breaker was open",
+		},
+	)
+
+	// Health Monitor Metrics
+	OmniFocusUp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_omnifocus_up",
+			Help: "Whether OmniFocus was running at the last health check (1 = running, 0 = not running)",
+		},
+	)
+
+	AppleScriptAccessible = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_applescript_accessible",
+			Help: "Whether AppleScript was accessible at the last health check (1 = accessible, 0 = not accessible)",
+		},
+	)
+
+	HealthLastCheckTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_health_last_check_timestamp_seconds",
+			Help: "Unix time of the last completed health check",
+		},
+	)
+
+	// Task Queue Metrics
+	TaskQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_task_queue_depth",
+			Help: "Number of tasks waiting in the retry queue",
+		},
+	)
+
+	TaskQueueOldestAgeSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "omnidrop_task_queue_oldest_age_seconds",
+			Help: "Age of the oldest task waiting in the retry queue in seconds",
+		},
+	)
+
+	TaskQueueDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "omnidrop_task_queue_deliveries_total",
+			Help: "Total number of queued task delivery attempts",
+		},
+		[]string{"status"}, // success, retry, dead_letter
 	)
 
 	// OAuth Metrics
@@ -0,0 +1,356 @@
+// Package outbox provides a durable on-disk queue for tasks that could not be
+// delivered to OmniFocus, together with a background worker that retries them
+// with exponential backoff.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"omnidrop/internal/observability"
+	"omnidrop/internal/services"
+)
+
+const (
+	// DefaultPollInterval is how often the worker scans the queue for due items
+	DefaultPollInterval = 5 * time.Second
+	// DefaultInitialBackoff is the delay before the first retry
+	DefaultInitialBackoff = 10 * time.Second
+	// DefaultMaxBackoff caps the exponential retry delay
+	DefaultMaxBackoff = 15 * time.Minute
+	// DefaultDeliveryTimeout bounds a single delivery attempt
+	DefaultDeliveryTimeout = 30 * time.Second
+
+	itemSuffix    = ".json"
+	deadLetterDir = "failed"
+)
+
+// Ensure Outbox implements services.TaskQueue
+var _ services.TaskQueue = (*Outbox)(nil)
+
+// Item is a queued task persisted as one JSON file
+type Item struct {
+	ID            string                     `json:"id"`
+	Request       services.TaskCreateRequest `json:"request"`
+	EnqueuedAt    time.Time                  `json:"enqueued_at"`
+	Attempts      int                        `json:"attempts"`
+	NextAttemptAt time.Time                  `json:"next_attempt_at"`
+	LastError     string                     `json:"last_error,omitempty"`
+}
+
+// Stats summarizes the current queue state
+type Stats struct {
+	Depth     int           `json:"depth"`
+	OldestAge time.Duration `json:"oldest_age"`
+}
+
+// Options tunes retry behavior; zero values select the defaults
+type Options struct {
+	PollInterval    time.Duration
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	DeliveryTimeout time.Duration
+	MaxAttempts     int // 0 retries forever; otherwise items move to the failed/ directory
+}
+
+// Outbox persists undelivered tasks under dir and retries them in the background
+type Outbox struct {
+	dir       string
+	deliverer services.TaskBackend
+	opts      Options
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu     sync.Mutex // serializes file access between Enqueue and the worker
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates an outbox rooted at dir, creating the directory if needed
+func New(dir string, deliverer services.TaskBackend, opts Options, logger *slog.Logger) (*Outbox, error) {
+	if err := os.MkdirAll(filepath.Join(dir, deadLetterDir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.DeliveryTimeout <= 0 {
+		opts.DeliveryTimeout = DefaultDeliveryTimeout
+	}
+
+	o := &Outbox{
+		dir:       dir,
+		deliverer: deliverer,
+		opts:      opts,
+		logger:    logger,
+		now:       time.Now,
+	}
+	o.updateMetrics()
+	return o, nil
+}
+
+// Enqueue persists a task for later delivery and returns its queue ID. A task without a
+// delivery ID gets the queue ID, so every retry presents the same one to the backend.
+func (o *Outbox) Enqueue(ctx context.Context, req services.TaskCreateRequest) (string, error) {
+	now := o.now()
+	id := uuid.NewString()
+	if req.DeliveryID == "" {
+		req.DeliveryID = id
+	}
+	item := &Item{
+		ID:            id,
+		Request:       req,
+		EnqueuedAt:    now,
+		NextAttemptAt: now.Add(o.opts.InitialBackoff),
+	}
+
+	o.mu.Lock()
+	err := o.writeItem(item)
+	o.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	o.logger.Info("📥 Task queued for retry",
+		slog.String("task_id", item.ID),
+		slog.String("task_title", req.Title))
+	o.updateMetrics()
+	return item.ID, nil
+}
+
+// Start launches the background delivery worker
+func (o *Outbox) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.done = make(chan struct{})
+
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(o.opts.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			o.ProcessDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	o.logger.Info("📤 Task outbox worker started", slog.String("dir", o.dir))
+}
+
+// Shutdown stops the worker, waiting for an in-flight delivery to finish.
+// Undelivered items stay on disk and are retried on the next start.
+func (o *Outbox) Shutdown(ctx context.Context) error {
+	if o.cancel == nil {
+		return nil
+	}
+	o.cancel()
+
+	select {
+	case <-o.done:
+		o.logger.Info("✅ Task outbox worker stopped", slog.Int("pending", o.Stats().Depth))
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("task outbox did not drain: %w", ctx.Err())
+	}
+}
+
+// ProcessDue attempts delivery of every item whose retry time has passed
+func (o *Outbox) ProcessDue(ctx context.Context) {
+	defer o.updateMetrics()
+
+	items, err := o.list()
+	if err != nil {
+		o.logger.Error("Failed to read task queue", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if item.NextAttemptAt.After(o.now()) {
+			continue
+		}
+		o.deliver(item)
+	}
+}
+
+// Stats returns the current queue depth and the age of the oldest item
+func (o *Outbox) Stats() Stats {
+	items, err := o.list()
+	if err != nil || len(items) == 0 {
+		return Stats{}
+	}
+	return Stats{
+		Depth:     len(items),
+		OldestAge: o.now().Sub(items[0].EnqueuedAt),
+	}
+}
+
+func (o *Outbox) deliver(item *Item) {
+	// Deliberately detached from the worker context: cancelling mid-attempt would kill
+	// an osascript run that may already have created the task.
+	deliverCtx, cancel := context.WithTimeout(context.Background(), o.opts.DeliveryTimeout)
+	defer cancel()
+
+	resp := o.deliverer.CreateTask(deliverCtx, item.Request)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if resp.Status != "error" {
+		if err := os.Remove(o.itemPath(item.ID)); err != nil && !os.IsNotExist(err) {
+			o.logger.Error("Failed to remove delivered task from queue",
+				slog.String("task_id", item.ID),
+				slog.String("error", err.Error()))
+		}
+		observability.TaskQueueDeliveriesTotal.WithLabelValues("success").Inc()
+		o.logger.Info("✅ Queued task delivered",
+			slog.String("task_id", item.ID),
+			slog.Int("attempts", item.Attempts+1))
+		return
+	}
+
+	item.Attempts++
+	item.LastError = resp.Reason
+
+	// A task that may already have been created, or that the script rejected, is not retried
+	if !services.Retryable(resp.Err) || (o.opts.MaxAttempts > 0 && item.Attempts >= o.opts.MaxAttempts) {
+		deadPath := filepath.Join(o.dir, deadLetterDir, item.ID+itemSuffix)
+		if err := o.writeItemTo(item, deadPath); err == nil {
+			_ = os.Remove(o.itemPath(item.ID))
+		}
+		observability.TaskQueueDeliveriesTotal.WithLabelValues("dead_letter").Inc()
+		o.logger.Error("❌ Queued task moved to dead letters",
+			slog.String("task_id", item.ID),
+			slog.Int("attempts", item.Attempts),
+			slog.String("error", item.LastError))
+		return
+	}
+
+	item.NextAttemptAt = o.now().Add(o.backoff(item.Attempts))
+	if err := o.writeItem(item); err != nil {
+		o.logger.Error("Failed to update queued task",
+			slog.String("task_id", item.ID),
+			slog.String("error", err.Error()))
+	}
+	observability.TaskQueueDeliveriesTotal.WithLabelValues("retry").Inc()
+	o.logger.Warn("⚠️ Queued task delivery failed; will retry",
+		slog.String("task_id", item.ID),
+		slog.Int("attempts", item.Attempts),
+		slog.Time("next_attempt_at", item.NextAttemptAt),
+		slog.String("error", item.LastError))
+}
+
+// backoff returns InitialBackoff * 2^attempts, capped at MaxBackoff
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.opts.InitialBackoff
+	for i := 0; i < attempts; i++ {
+		delay *= 2
+		if delay >= o.opts.MaxBackoff {
+			return o.opts.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// list returns queued items ordered from oldest to newest
+func (o *Outbox) list() ([]*Item, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), itemSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(o.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var item Item
+		if err := json.Unmarshal(data, &item); err != nil {
+			o.logger.Error("Skipping corrupt queue item",
+				slog.String("file", entry.Name()),
+				slog.String("error", err.Error()))
+			continue
+		}
+		items = append(items, &item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].EnqueuedAt.Before(items[j].EnqueuedAt)
+	})
+	return items, nil
+}
+
+func (o *Outbox) itemPath(id string) string {
+	return filepath.Join(o.dir, id+itemSuffix)
+}
+
+func (o *Outbox) writeItem(item *Item) error {
+	return o.writeItemTo(item, o.itemPath(item.ID))
+}
+
+// writeItemTo writes item via a temp file and rename so a crash never leaves a partial entry
+func (o *Outbox) writeItemTo(item *Item, path string) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue item: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create queue item: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write queue item: %w", err)
+	}
+	return nil
+}
+
+func (o *Outbox) updateMetrics() {
+	stats := o.Stats()
+	observability.TaskQueueDepth.Set(float64(stats.Depth))
+	observability.TaskQueueOldestAgeSeconds.Set(stats.OldestAge.Seconds())
+}
@@ -0,0 +1,224 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/observability"
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+func newTestOutbox(t *testing.T, deliverer services.OmniFocusServiceInterface, opts Options) (*Outbox, *time.Time) {
+	t.Helper()
+	o, err := New(t.TempDir(), deliverer, opts, observability.SetupLogger())
+	require.NoError(t, err)
+
+	now := time.Date(2025, 10, 15, 9, 0, 0, 0, time.UTC)
+	o.now = func() time.Time { return now }
+	return o, &now
+}
+
+func failingService(calls *int) *mocks.MockOmniFocusService {
+	return &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			*calls++
+			return services.TaskCreateResponse{Status: "error", Reason: "OmniFocus is not running"}
+		},
+	}
+}
+
+func TestOutbox_EnqueuePersistsItem(t *testing.T) {
+	o, _ := newTestOutbox(t, &mocks.MockOmniFocusService{}, Options{})
+
+	id, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Queued", Tags: []string{"a,b"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	// A fresh outbox over the same directory sees the item (survives restarts)
+	reopened, err := New(o.dir, &mocks.MockOmniFocusService{}, Options{}, observability.SetupLogger())
+	require.NoError(t, err)
+
+	items, err := reopened.list()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, id, items[0].ID)
+	assert.Equal(t, "Queued", items[0].Request.Title)
+	assert.Equal(t, []string{"a,b"}, items[0].Request.Tags)
+	assert.Equal(t, id, items[0].Request.DeliveryID, "a task without a delivery ID gets the queue ID")
+
+	// A task keeps the delivery ID of its first attempt
+	_, err = o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Retried", DeliveryID: "first-attempt"})
+	require.NoError(t, err)
+	items, err = o.list()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	deliveryIDs := []string{items[0].Request.DeliveryID, items[1].Request.DeliveryID}
+	assert.Contains(t, deliveryIDs, "first-attempt")
+}
+
+func TestOutbox_ProcessDue_DeliversAndRemoves(t *testing.T) {
+	var delivered []string
+	service := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			delivered = append(delivered, req.Title)
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	o, now := newTestOutbox(t, service, Options{InitialBackoff: time.Minute})
+
+	_, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "First"})
+	require.NoError(t, err)
+	*now = now.Add(time.Second)
+	_, err = o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Second"})
+	require.NoError(t, err)
+
+	// Not yet due
+	o.ProcessDue(context.Background())
+	assert.Empty(t, delivered)
+
+	*now = now.Add(2 * time.Minute)
+	o.ProcessDue(context.Background())
+
+	assert.Equal(t, []string{"First", "Second"}, delivered)
+	assert.Equal(t, 0, o.Stats().Depth)
+}
+
+func TestOutbox_ProcessDue_BacksOffExponentially(t *testing.T) {
+	calls := 0
+	o, now := newTestOutbox(t, failingService(&calls), Options{
+		InitialBackoff: time.Minute,
+		MaxBackoff:     5 * time.Minute,
+	})
+
+	_, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Retry me"})
+	require.NoError(t, err)
+
+	expectedDelays := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	*now = now.Add(time.Minute)
+	for i, delay := range expectedDelays {
+		o.ProcessDue(context.Background())
+		require.Equal(t, i+1, calls)
+
+		items, err := o.list()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, i+1, items[0].Attempts)
+		assert.Equal(t, "OmniFocus is not running", items[0].LastError)
+		assert.Equal(t, now.Add(delay), items[0].NextAttemptAt)
+
+		*now = items[0].NextAttemptAt
+	}
+}
+
+func TestOutbox_ProcessDue_MovesToFailedAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	o, now := newTestOutbox(t, failingService(&calls), Options{
+		InitialBackoff: time.Second,
+		MaxAttempts:    2,
+	})
+
+	id, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Doomed"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		*now = now.Add(time.Hour)
+		o.ProcessDue(context.Background())
+	}
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, o.Stats().Depth)
+	_, err = os.Stat(filepath.Join(o.dir, deadLetterDir, id+itemSuffix))
+	assert.NoError(t, err)
+}
+
+func TestOutbox_ProcessDue_DoesNotRetryUnretryableFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "unknown outcome", err: services.ErrOutcomeUnknown},
+		{name: "permanent script error", err: &services.ScriptError{Code: services.ScriptErrorProjectNotFound, Message: "Project not found: Nowhere"}},
+		{name: "permanent HTTP error", err: &services.HTTPStatusError{Server: "webhook", StatusCode: 422, Detail: "invalid task"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			service := &mocks.MockOmniFocusService{
+				CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+					calls++
+					return services.TaskCreateResponse{Status: "error", Reason: tt.err.Error(), Err: tt.err}
+				},
+			}
+			o, now := newTestOutbox(t, service, Options{InitialBackoff: time.Second})
+
+			id, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Not again"})
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				*now = now.Add(time.Hour)
+				o.ProcessDue(context.Background())
+			}
+
+			// Moved to the dead letters after the first attempt, even without a retry limit
+			assert.Equal(t, 1, calls)
+			assert.Equal(t, 0, o.Stats().Depth)
+			_, err = os.Stat(filepath.Join(o.dir, deadLetterDir, id+itemSuffix))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestOutbox_Stats(t *testing.T) {
+	o, now := newTestOutbox(t, &mocks.MockOmniFocusService{}, Options{})
+
+	assert.Equal(t, Stats{}, o.Stats())
+
+	_, err := o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Old"})
+	require.NoError(t, err)
+	*now = now.Add(30 * time.Second)
+	_, err = o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "New"})
+	require.NoError(t, err)
+
+	stats := o.Stats()
+	assert.Equal(t, 2, stats.Depth)
+	assert.Equal(t, 30*time.Second, stats.OldestAge)
+}
+
+func TestOutbox_StartAndShutdown(t *testing.T) {
+	delivered := make(chan string, 1)
+	service := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			delivered <- req.Title
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	o, err := New(t.TempDir(), service, Options{
+		PollInterval:   10 * time.Millisecond,
+		InitialBackoff: time.Millisecond,
+	}, observability.SetupLogger())
+	require.NoError(t, err)
+
+	_, err = o.Enqueue(context.Background(), services.TaskCreateRequest{Title: "Background"})
+	require.NoError(t, err)
+
+	o.Start()
+
+	select {
+	case title := <-delivered:
+		assert.Equal(t, "Background", title)
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker did not deliver queued task")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, o.Shutdown(ctx))
+}
@@ -0,0 +1,164 @@
+// Package scripts gives access to the OmniFocus scripts embedded in the server binary,
+// installs them and detects installed copies whose version or contents do not match.
+package scripts
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"omnidrop"
+)
+
+// versionMarker precedes the version in the first lines of every bundled script, inside a
+// comment: "-- omnidrop-script-version: 1" or "// omnidrop-script-version: 1"
+const versionMarker = "omnidrop-script-version:"
+
+// versionHeaderLines is how many lines from the top are searched for the version marker
+const versionHeaderLines = 5
+
+// Script is a script embedded in the server binary
+type Script struct {
+	Name     string
+	Content  []byte
+	Version  string // From the version header; "" when the script has none
+	Checksum string // Hex SHA-256 of Content
+}
+
+// Embedded returns every bundled script, sorted by name
+func Embedded() ([]Script, error) {
+	entries, err := fs.ReadDir(omnidrop.Scripts, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embedded scripts: %w", err)
+	}
+
+	scripts := make([]Script, 0, len(entries))
+	for _, entry := range entries {
+		script, err := Lookup(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// Lookup returns the bundled script with the given file name
+func Lookup(name string) (Script, error) {
+	content, err := omnidrop.Scripts.ReadFile(name)
+	if err != nil {
+		return Script{}, fmt.Errorf("no embedded script named %s", name)
+	}
+	return newScript(name, content), nil
+}
+
+func newScript(name string, content []byte) Script {
+	sum := sha256.Sum256(content)
+	return Script{
+		Name:     name,
+		Content:  content,
+		Version:  parseVersion(content),
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+}
+
+// parseVersion returns the version from a script's header, or "" when it has none
+func parseVersion(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for i := 0; i < versionHeaderLines && scanner.Scan(); i++ {
+		if _, version, ok := strings.Cut(scanner.Text(), versionMarker); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+	return ""
+}
+
+// Install writes every bundled script into dir, creating it if needed and replacing
+// existing copies. It returns the paths written.
+func Install(dir string) ([]string, error) {
+	scripts, err := Embedded()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create script directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		path := filepath.Join(dir, script.Name)
+		// Write to a temporary file first so a running server never executes a half-written script
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, script.Content, 0644); err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", script.Name, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return paths, fmt.Errorf("failed to install %s: %w", script.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Drift compares an installed script with the bundled one
+type Drift struct {
+	Name             string
+	Path             string
+	EmbeddedVersion  string
+	InstalledVersion string
+	Missing          bool // The installed script does not exist
+	VersionMismatch  bool // The version headers differ
+	ChecksumMismatch bool // The contents differ
+}
+
+// Matches reports whether the installed script is identical to the bundled one
+func (d Drift) Matches() bool {
+	return !d.Missing && !d.VersionMismatch && !d.ChecksumMismatch
+}
+
+// String describes the drift for log messages
+func (d Drift) String() string {
+	switch {
+	case d.Missing:
+		return fmt.Sprintf("%s is not installed at %s", d.Name, d.Path)
+	case d.VersionMismatch:
+		return fmt.Sprintf("%s at %s is version %q but the server bundles version %q",
+			d.Name, d.Path, d.InstalledVersion, d.EmbeddedVersion)
+	case d.ChecksumMismatch:
+		return fmt.Sprintf("%s at %s (version %q) differs from the bundled copy", d.Name, d.Path, d.InstalledVersion)
+	default:
+		return fmt.Sprintf("%s at %s matches the bundled version %q", d.Name, d.Path, d.EmbeddedVersion)
+	}
+}
+
+// Check compares the script installed at path with the bundled script of the given name
+func Check(name, path string) (Drift, error) {
+	embedded, err := Lookup(name)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	drift := Drift{Name: name, Path: path, EmbeddedVersion: embedded.Version}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		drift.Missing = true
+		return drift, nil
+	}
+	if err != nil {
+		return drift, fmt.Errorf("failed to read installed script: %w", err)
+	}
+
+	installed := newScript(name, content)
+	drift.InstalledVersion = installed.Version
+	drift.VersionMismatch = installed.Version != embedded.Version
+	drift.ChecksumMismatch = installed.Checksum != embedded.Checksum
+	return drift, nil
+}
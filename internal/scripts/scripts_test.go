@@ -0,0 +1,79 @@
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedded_HaveVersionHeaders(t *testing.T) {
+	embedded, err := Embedded()
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(embedded))
+	for _, script := range embedded {
+		names = append(names, script.Name)
+		assert.NotEmpty(t, script.Version, "%s has no version header", script.Name)
+		assert.Len(t, script.Checksum, 64)
+	}
+	assert.Equal(t, []string{"omnidrop-query.applescript", "omnidrop.applescript", "omnidrop.js"}, names)
+}
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, "3", parseVersion([]byte("-- omnidrop-script-version: 3\n-- rest")))
+	assert.Equal(t, "1.2", parseVersion([]byte("// header\n// omnidrop-script-version:  1.2 \n")))
+	assert.Equal(t, "", parseVersion([]byte("on run argv\nend run\n")))
+}
+
+func TestInstallAndCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "omnidrop")
+
+	paths, err := Install(dir)
+	require.NoError(t, err)
+	assert.Len(t, paths, 3)
+
+	path := filepath.Join(dir, "omnidrop.applescript")
+	drift, err := Check("omnidrop.applescript", path)
+	require.NoError(t, err)
+	assert.True(t, drift.Matches(), drift.String())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// Same version, edited contents
+	embedded, err := Lookup("omnidrop.applescript")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(embedded.Content, "\n-- local edit\n"...), 0644))
+	drift, err = Check("omnidrop.applescript", path)
+	require.NoError(t, err)
+	assert.False(t, drift.VersionMismatch)
+	assert.True(t, drift.ChecksumMismatch)
+	assert.Contains(t, drift.String(), "differs from the bundled copy")
+
+	// Older version
+	require.NoError(t, os.WriteFile(path, []byte("-- omnidrop-script-version: 0\non run argv\nend run\n"), 0644))
+	drift, err = Check("omnidrop.applescript", path)
+	require.NoError(t, err)
+	assert.True(t, drift.VersionMismatch)
+	assert.Equal(t, "0", drift.InstalledVersion)
+	assert.Contains(t, drift.String(), `version "0"`)
+
+	// Reinstalling restores the bundled copy
+	_, err = Install(dir)
+	require.NoError(t, err)
+	drift, err = Check("omnidrop.applescript", path)
+	require.NoError(t, err)
+	assert.True(t, drift.Matches())
+
+	drift, err = Check("omnidrop.applescript", filepath.Join(t.TempDir(), "missing.applescript"))
+	require.NoError(t, err)
+	assert.True(t, drift.Missing)
+	assert.False(t, drift.Matches())
+
+	_, err = Check("unknown.applescript", path)
+	assert.Error(t, err)
+}
@@ -14,6 +14,7 @@ import (
 	"omnidrop/internal/auth"
 	"omnidrop/internal/config"
 	"omnidrop/internal/handlers"
+	"omnidrop/internal/idempotency"
 	omnimiddleware "omnidrop/internal/middleware"
 )
 
@@ -24,19 +25,21 @@ type Server struct {
 	authMiddleware       *auth.Middleware
 	legacyAuthMiddleware *omnimiddleware.LegacyAuthMiddleware
 	tokenHandler         *auth.TokenHandler
+	idempotency          *idempotency.Middleware
 	logger               *slog.Logger
 	httpSrv              *http.Server
 	router               chi.Router
 }
 
 // NewServer creates a new server instance with the given configuration and handlers
-func NewServer(cfg *config.Config, handlers *handlers.Handlers, authMiddleware *auth.Middleware, legacyAuthMiddleware *omnimiddleware.LegacyAuthMiddleware, tokenHandler *auth.TokenHandler, logger *slog.Logger) (*Server, error) {
+func NewServer(cfg *config.Config, handlers *handlers.Handlers, authMiddleware *auth.Middleware, legacyAuthMiddleware *omnimiddleware.LegacyAuthMiddleware, tokenHandler *auth.TokenHandler, idempotencyMiddleware *idempotency.Middleware, logger *slog.Logger) (*Server, error) {
 	s := &Server{
 		config:               cfg,
 		handlers:             handlers,
 		authMiddleware:       authMiddleware,
 		legacyAuthMiddleware: legacyAuthMiddleware,
 		tokenHandler:         tokenHandler,
+		idempotency:          idempotencyMiddleware,
 		logger:               logger,
 	}
 	if err := s.setupRouter(); err != nil {
@@ -56,10 +59,12 @@ func (s *Server) setupRouter() error {
 	r.Use(middleware.RealIP)                      // Real IP detection
 	r.Use(omnimiddleware.HTTPLogging(s.logger))   // Structured logging
 	r.Use(omnimiddleware.Metrics)                 // Prometheus metrics collection
-	r.Use(middleware.Timeout(60 * time.Second))   // Request timeout
+	r.Use(requestTimeout(60 * time.Second))       // Request timeout
 
 	// Public routes (no authentication required)
 	r.Get("/health", s.handlers.Health)
+	r.Get("/health/live", s.handlers.Health)
+	r.Get("/health/ready", s.handlers.Ready)
 	r.Handle("/metrics", promhttp.Handler()) // Prometheus metrics endpoint
 
 	// OAuth token endpoint
@@ -75,18 +80,45 @@ func (s *Server) setupRouter() error {
 			r.Use(s.authMiddleware.Authenticate)
 
 			// Task creation requires tasks:write scope
-			r.With(auth.RequireScopes("tasks:write")).Post("/tasks", s.handlers.CreateTask)
+			r.With(auth.RequireScopes("tasks:write"), s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks", s.handlers.CreateTask)
+			r.With(auth.RequireScopes("tasks:write"), s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/batch", s.handlers.CreateTaskBatch)
+			// Templates additionally require their own scope, checked by the handler
+			r.With(auth.RequireScopes("tasks:write"), s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/from-template/{name}", s.handlers.CreateTaskFromTemplate)
+
+			// Reading tasks back requires tasks:read scope
+			r.With(auth.RequireScopes("tasks:read")).Get("/tasks", s.handlers.ListTasks)
+			r.With(auth.RequireScopes("tasks:read")).Get("/tasks/{id}", s.handlers.GetTask)
+
+			// Changing existing tasks requires tasks:update, deleting them tasks:delete
+			r.With(auth.RequireScopes("tasks:update"), s.idempotent(handlers.MaxTaskRequestSize)).Patch("/tasks/{id}", s.handlers.UpdateTask)
+			r.With(auth.RequireScopes("tasks:update"), s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/{id}/complete", s.handlers.CompleteTask)
+			r.With(auth.RequireScopes("tasks:delete"), s.idempotent(handlers.MaxTaskRequestSize)).Delete("/tasks/{id}", s.handlers.DeleteTask)
 
 			// File creation requires files:write scope
-			r.With(auth.RequireScopes("files:write")).Post("/files", s.handlers.CreateFile)
+			r.With(auth.RequireScopes("files:write"), s.idempotent(handlers.MaxFileRequestSize)).Post("/files", s.handlers.CreateFile)
+
+			// Reading files back requires files:read, deleting them files:delete
+			r.With(auth.RequireScopes("files:read")).Get("/files", s.handlers.ListFiles)
+			r.With(auth.RequireScopes("files:read")).Get("/files/*", s.handlers.GetFile)
+			r.With(auth.RequireScopes("files:delete"), s.idempotent(handlers.MaxTaskRequestSize)).Delete("/files/*", s.handlers.DeleteFile)
 		})
 	} else if s.legacyAuthMiddleware != nil {
 		// Legacy authentication mode (TOKEN-based)
 		s.logger.Warn("⚠️ Authentication: Legacy token-based (migration mode)")
 		r.Group(func(r chi.Router) {
 			r.Use(s.legacyAuthMiddleware.Authenticate)
-			r.Post("/tasks", s.handlers.CreateTask)
-			r.Post("/files", s.handlers.CreateFile)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks", s.handlers.CreateTask)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/batch", s.handlers.CreateTaskBatch)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/from-template/{name}", s.handlers.CreateTaskFromTemplate)
+			r.Get("/tasks", s.handlers.ListTasks)
+			r.Get("/tasks/{id}", s.handlers.GetTask)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Patch("/tasks/{id}", s.handlers.UpdateTask)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Post("/tasks/{id}/complete", s.handlers.CompleteTask)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Delete("/tasks/{id}", s.handlers.DeleteTask)
+			r.With(s.idempotent(handlers.MaxFileRequestSize)).Post("/files", s.handlers.CreateFile)
+			r.Get("/files", s.handlers.ListFiles)
+			r.Get("/files/*", s.handlers.GetFile)
+			r.With(s.idempotent(handlers.MaxTaskRequestSize)).Delete("/files/*", s.handlers.DeleteFile)
 		})
 	} else {
 		return fmt.Errorf("no authentication middleware configured - server cannot start safely")
@@ -96,6 +128,26 @@ func (s *Server) setupRouter() error {
 	return nil
 }
 
+// idempotent applies Idempotency-Key handling when it is configured, buffering at most the
+// route's own body limit and never buffering streamed file uploads. It must run after
+// authentication so keys are scoped per OAuth client.
+func (s *Server) idempotent(maxBodyBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if s.idempotency == nil {
+			return next
+		}
+		buffered := s.idempotency.Handle(next, maxBodyBytes)
+		streamed := s.idempotency.HandleStream(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if handlers.IsFileTransfer(r) {
+				streamed.ServeHTTP(w, r)
+				return
+			}
+			buffered.ServeHTTP(w, r)
+		})
+	}
+}
+
 // setupHTTPServer configures the HTTP server with appropriate timeouts
 func (s *Server) setupHTTPServer() {
 	s.httpSrv = &http.Server{
@@ -130,3 +182,18 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// requestTimeout applies middleware.Timeout to every request except streamed file uploads and
+// downloads, which extend their own deadlines to OMNIDROP_FILES_UPLOAD_TIMEOUT
+func requestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		limited := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if handlers.IsFileTransfer(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,27 +2,60 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
+	"omnidrop/internal/auth"
 	"omnidrop/internal/config"
 	"omnidrop/internal/handlers"
+	"omnidrop/internal/idempotency"
 	"omnidrop/internal/middleware"
 	"omnidrop/internal/observability"
+	"omnidrop/internal/services"
+	"omnidrop/internal/templates"
 	"omnidrop/test/mocks"
 )
 
+// newClientRepository writes an OAuth clients file holding clients and loads it
+func newClientRepository(t *testing.T, clients ...auth.OAuthClient) *auth.Repository {
+	t.Helper()
+	data, err := yaml.Marshal(auth.OAuthConfig{Clients: clients})
+	if err != nil {
+		t.Fatalf("Failed to marshal clients: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "oauth-clients.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write clients file: %v", err)
+	}
+	repo, err := auth.NewRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to load clients: %v", err)
+	}
+	return repo
+}
+
 // Helper to create test server with legacy auth middleware
 func createTestServer(t *testing.T, cfg *config.Config) *Server {
 	t.Helper()
 	mockOmniFocusService := &mocks.MockOmniFocusService{}
 	mockFilesService := &mocks.MockFilesService{}
-	h := handlers.New("test", mockOmniFocusService, mockFilesService)
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), mockFilesService, nil, nil, nil)
 	logger := observability.SetupLogger()
 	legacyAuth := middleware.NewLegacyAuthMiddleware(cfg.Token, logger)
-	srv, err := NewServer(cfg, h, nil, legacyAuth, nil, logger)
+	srv, err := NewServer(cfg, h, nil, legacyAuth, nil, nil, logger)
 	if err != nil {
 		t.Fatalf("Failed to create test server: %v", err)
 	}
@@ -77,12 +110,42 @@ func TestServer_RouteConfiguration(t *testing.T) {
 			path:           "/health",
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "Liveness endpoint GET",
+			method:         "GET",
+			path:           "/health/live",
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name:           "Tasks endpoint POST without auth",
 			method:         "POST",
 			path:           "/tasks",
 			expectedStatus: http.StatusUnauthorized, // Legacy auth middleware blocks unauthenticated requests
 		},
+		{
+			name:           "Tasks endpoint GET without auth",
+			method:         "GET",
+			path:           "/tasks",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Files list GET without auth",
+			method:         "GET",
+			path:           "/files",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "File download GET without auth",
+			method:         "GET",
+			path:           "/files/reports/report.txt",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "File DELETE without auth",
+			method:         "DELETE",
+			path:           "/files/reports/report.txt",
+			expectedStatus: http.StatusUnauthorized,
+		},
 		{
 			name:           "Non-existent endpoint",
 			method:         "GET",
@@ -91,7 +154,7 @@ func TestServer_RouteConfiguration(t *testing.T) {
 		},
 		{
 			name:           "Tasks endpoint with wrong method",
-			method:         "GET",
+			method:         "PUT",
 			path:           "/tasks",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
@@ -218,3 +281,1407 @@ func TestServer_Integration(t *testing.T) {
 		t.Errorf("Expected Content-Type application/json, got %s", contentType)
 	}
 }
+
+func TestServer_TaskBatch(t *testing.T) {
+	cfg := &config.Config{
+		Port:  "8788",
+		Token: "test-token",
+	}
+
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			if req.Title == "Two" {
+				return services.TaskCreateResponse{Status: "error", Reason: "boom"}
+			}
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "all created",
+			body:           `[{"title":"One"},{"title":"Three"}]`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ok"`,
+		},
+		{
+			name:           "partial failure",
+			body:           `[{"title":"One"},{"title":"Two"}]`,
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `"status":"partial"`,
+		},
+		{
+			name:           "invalid item rejects whole batch",
+			body:           `[{"title":"One"},{"title":""}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `tasks[1]`,
+		},
+		{
+			name:           "empty batch",
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `at least one task`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks/batch", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_ExecutorBusy(t *testing.T) {
+	cfg := &config.Config{
+		Port:                    "8788",
+		Token:                   "test-token",
+		AppleScriptQueueTimeout: 2500 * time.Millisecond,
+	}
+
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			return services.TaskCreateResponse{Status: "error", Reason: "busy", Err: services.ErrExecutorBusy}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "single task", path: "/tasks", body: `{"title":"One"}`},
+		{name: "batch", path: "/tasks/batch", body: `[{"title":"One"},{"title":"Two"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status %d, got %d (%s)", http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Retry-After"); got != "3" {
+				t.Errorf("Expected Retry-After 3, got %q", got)
+			}
+			if !strings.Contains(rr.Body.String(), `"code":"service_unavailable"`) {
+				t.Errorf("Expected service_unavailable code, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_CircuitOpen(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "omnidrop.applescript")
+	if err := os.WriteFile(scriptPath, []byte("return \"success\""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Port:       "8788",
+		Token:      "test-token",
+		ScriptPath: scriptPath,
+	}
+
+	executor := mocks.NewMockExecutor()
+	executor.Default = mocks.Failure(context.DeadlineExceeded)
+	breaker := services.NewCircuitBreakerExecutor(executor, services.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	})
+	omniFocus := services.NewOmniFocusServiceWithExecutor(cfg, breaker)
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(omniFocus), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"One"}`))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr := httptest.NewRecorder()
+		srv.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The first timeout opens the circuit; the killed run may have created the task
+	if rr := post(); rr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status %d, got %d (%s)", http.StatusGatewayTimeout, rr.Code, rr.Body.String())
+	}
+
+	rr := post()
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d (%s)", http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+	if executor.CallCount() != 1 {
+		t.Errorf("Expected the open circuit to skip the script, got %d calls", executor.CallCount())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rr = httptest.NewRecorder()
+	srv.router.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), `"omnifocus_circuit":"open"`) {
+		t.Errorf("Expected health to report the open circuit, got %s", rr.Body.String())
+	}
+}
+
+func TestServer_TaskQueueing(t *testing.T) {
+	cfg := &config.Config{
+		Port:  "8788",
+		Token: "test-token",
+	}
+
+	// Each task fails with the error named by its title
+	failures := map[string]error{
+		"unavailable": errors.New("exit status 1"),
+		"killed":      fmt.Errorf("%w: signal: killed", services.ErrOutcomeUnknown),
+		"invalid":     &services.ScriptError{Code: services.ScriptErrorInvalidArgument, Message: "Title is required"},
+		"no project":  &services.ScriptError{Code: services.ScriptErrorProjectNotFound, Message: "Project not found: Nowhere"},
+		"circuit":     &services.CircuitOpenError{RetryAfter: 30 * time.Second},
+	}
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			err, ok := failures[req.Title]
+			if !ok {
+				return services.TaskCreateResponse{Status: "ok", Created: true}
+			}
+			return services.TaskCreateResponse{Status: "error", Reason: err.Error(), Err: err}
+		},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedQueued []string
+	}{
+		{
+			name:           "transient failure is queued",
+			path:           "/tasks",
+			body:           `{"title":"unavailable"}`,
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `"status":"queued"`,
+			expectedQueued: []string{"unavailable"},
+		},
+		{
+			name:           "task that may exist is not queued",
+			path:           "/tasks",
+			body:           `{"title":"killed"}`,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   `may have been created`,
+		},
+		{
+			name:           "invalid argument is not queued",
+			path:           "/tasks",
+			body:           `{"title":"invalid"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `Title is required`,
+		},
+		{
+			name:           "unknown project is not queued",
+			path:           "/tasks",
+			body:           `{"title":"no project"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `Project not found`,
+		},
+		{
+			name:           "open circuit is not queued",
+			path:           "/tasks",
+			body:           `{"title":"circuit"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `service_unavailable`,
+		},
+		{
+			name:           "batch with open circuit is not queued",
+			path:           "/tasks/batch",
+			body:           `[{"title":"circuit"},{"title":"circuit"}]`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `service_unavailable`,
+		},
+		{
+			name:           "batch queues only transient failures",
+			path:           "/tasks/batch",
+			body:           `[{"title":"ok"},{"title":"unavailable"},{"title":"killed"},{"title":"no project"}]`,
+			expectedStatus: http.StatusMultiStatus,
+			expectedBody:   `{"index":3,"status":"error"`,
+			expectedQueued: []string{"unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &mocks.MockTaskQueue{}
+			h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, queue, nil, nil)
+			logger := observability.SetupLogger()
+			srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+			if err != nil {
+				t.Fatalf("Failed to create test server: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+			var queued []string
+			for _, req := range queue.Enqueued {
+				queued = append(queued, req.Title)
+				if req.DeliveryID == "" {
+					t.Errorf("Expected queued task %q to keep the delivery ID of its first attempt", req.Title)
+				}
+			}
+			if strings.Join(queued, ",") != strings.Join(tt.expectedQueued, ",") {
+				t.Errorf("Expected queued tasks %v, got %v", tt.expectedQueued, queued)
+			}
+		})
+	}
+}
+
+func TestServer_Readiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		health         services.HealthResult
+		defaultBackend string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "ready",
+			health:         services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ready"`,
+		},
+		{
+			name:           "OmniFocus not running",
+			health:         services.HealthResult{AppleScriptAccessible: true},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `"omnifocus_running":false`,
+		},
+		{
+			name:           "OmniFocus not in use",
+			health:         services.HealthResult{},
+			defaultBackend: services.BackendMarkdown,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"task_backends":["markdown"],"files_dir_writable":true`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Port:     "8788",
+				Token:    "test-token",
+				FilesDir: t.TempDir(),
+			}
+			backends := mocks.NewTaskBackends(&mocks.MockOmniFocusService{})
+			if tt.defaultBackend != "" {
+				backends.Register(tt.defaultBackend, &mocks.MockOmniFocusService{})
+				backends.SetDefault(tt.defaultBackend)
+			}
+			readiness := services.NewReadinessService(cfg, &mocks.MockHealthService{Result: tt.health}, services.ReadinessOptions{
+				OAuthClients: services.ComponentDisabled,
+				Queue:        services.ComponentDisabled,
+				TaskBackends: backends,
+			})
+			h := handlers.New(cfg, "test", backends, &mocks.MockFilesService{}, nil, nil, readiness)
+			logger := observability.SetupLogger()
+			srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+			if err != nil {
+				t.Fatalf("Failed to create test server: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			rr := httptest.NewRecorder()
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_TaskHierarchyValidation(t *testing.T) {
+	cfg := &config.Config{
+		Port:  "8788",
+		Token: "test-token",
+	}
+
+	var received services.TaskCreateRequest
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			received = req
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	nested := `{"title":"L5"}`
+	for _, title := range []string{"L4", "L3", "L2", "L1"} {
+		nested = `{"title":"` + title + `","children":[` + nested + `]}`
+	}
+	tooDeep := `{"title":"L0","children":[` + nested + `]}`
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "nested children",
+			body:           `{"title":"Trip","project":"Travel","sequential":true,"children":[{"title":"Book","children":[{"title":"Compare"}]},{"title":"Pack"}]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ok"`,
+		},
+		{
+			name:           "maximum depth",
+			body:           nested,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ok"`,
+		},
+		{
+			name:           "too deep",
+			body:           tooDeep,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `deeper than 5 levels`,
+		},
+		{
+			name:           "child without title",
+			body:           `{"title":"Trip","children":[{"title":"Book"},{"note":"x"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `children[1]: title is required`,
+		},
+		{
+			name:           "child with project",
+			body:           `{"title":"Trip","children":[{"title":"Book","project":"Other"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `project can only be set on top-level tasks`,
+		},
+		{
+			name:           "nested child error path",
+			body:           `{"title":"Trip","children":[{"title":"Book","children":[{"title":"Compare","estimated_minutes":-1}]}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `children[0]: children[0]: estimated_minutes cannot be negative`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	if len(received.Children) != 1 || received.Children[0].Title != "L2" {
+		t.Errorf("Expected the last accepted hierarchy to reach the service, got %+v", received)
+	}
+}
+
+func TestServer_TaskFromTemplate(t *testing.T) {
+	cfg := &config.Config{
+		Port: "8788",
+	}
+
+	templatesFile := filepath.Join(t.TempDir(), "task-templates.yaml")
+	err := os.WriteFile(templatesFile, []byte(`templates:
+  - name: release-checklist
+    title: "Release {{version}}"
+    children:
+      - title: "Tag v{{version}}"
+`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write templates: %v", err)
+	}
+	templateRepo, err := templates.NewRepository(templatesFile)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	var received services.TaskCreateRequest
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			received = req
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, templateRepo, nil)
+	logger := observability.SetupLogger()
+	jwtManager := auth.NewJWTManager("test-secret-key-with-at-least-32-characters")
+	srv, err := NewServer(cfg, h, auth.NewMiddleware(jwtManager, nil, logger, false, ""), nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tokenFor := func(scopes ...string) string {
+		token, err := jwtManager.GenerateToken(&auth.OAuthClient{ClientID: "client", Scopes: scopes}, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "template scope",
+			path:           "/tasks/from-template/release-checklist",
+			token:          tokenFor("tasks:write", "templates:release-checklist"),
+			body:           `{"variables":{"version":"1.2.0"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ok"`,
+		},
+		{
+			name:           "wildcard template scope",
+			path:           "/tasks/from-template/release-checklist",
+			token:          tokenFor("tasks:write", "templates:*"),
+			body:           `{"variables":{"version":"1.2.0"}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"ok"`,
+		},
+		{
+			name:           "missing template scope",
+			path:           "/tasks/from-template/release-checklist",
+			token:          tokenFor("tasks:write"),
+			body:           `{"variables":{"version":"1.2.0"}}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"message":"Template 'release-checklist' not found"`,
+		},
+		{
+			name:           "missing variable",
+			path:           "/tasks/from-template/release-checklist",
+			token:          tokenFor("tasks:write", "templates:*"),
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `missing template variables: version`,
+		},
+		{
+			name:           "unknown template",
+			path:           "/tasks/from-template/nope",
+			token:          tokenFor("tasks:write", "templates:*"),
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `not_found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	if received.Title != "Release 1.2.0" || len(received.Children) != 1 || received.Children[0].Title != "Tag v1.2.0" {
+		t.Errorf("Expected rendered template to reach the service, got %+v", received)
+	}
+}
+
+func TestServer_TaskBackendSelection(t *testing.T) {
+	cfg := &config.Config{
+		Port: "8788",
+	}
+
+	var routed []string
+	backendFor := func(name string) services.TaskBackend {
+		return &mocks.MockOmniFocusService{
+			CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+				routed = append(routed, name)
+				return services.TaskCreateResponse{Status: "ok", Created: true}
+			},
+		}
+	}
+	backends := services.NewTaskBackendRegistry(services.BackendOmniFocus)
+	backends.Register(services.BackendOmniFocus, backendFor(services.BackendOmniFocus))
+	backends.Register(services.BackendMarkdown, backendFor(services.BackendMarkdown))
+	backends.Register(services.BackendWebhook, backendFor(services.BackendWebhook))
+
+	h := handlers.New(cfg, "test", backends, &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	jwtManager := auth.NewJWTManager("test-secret-key-with-at-least-32-characters")
+	clients := newClientRepository(t,
+		auth.OAuthClient{ClientID: "client-", Scopes: []string{"tasks:write"}},
+		auth.OAuthClient{ClientID: "client-markdown", Scopes: []string{"tasks:write"}, Backend: services.BackendMarkdown},
+	)
+	srv, err := NewServer(cfg, h, auth.NewMiddleware(jwtManager, clients, logger, false, ""), nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	// The backend is looked up by client ID, not taken from the token
+	tokenFor := func(backend string) string {
+		token, err := jwtManager.GenerateToken(&auth.OAuthClient{ClientID: "client-" + backend, Scopes: []string{"tasks:write"}}, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+		expectedRoute  string
+	}{
+		{name: "server default", path: "/tasks", token: tokenFor(""), expectedStatus: http.StatusOK, expectedRoute: "omnifocus"},
+		{name: "client default", path: "/tasks", token: tokenFor("markdown"), expectedStatus: http.StatusOK, expectedRoute: "markdown"},
+		{name: "request overrides client", path: "/tasks?backend=webhook", token: tokenFor("markdown"), expectedStatus: http.StatusOK, expectedRoute: "webhook"},
+		{name: "batch", path: "/tasks/batch?backend=markdown", token: tokenFor(""), expectedStatus: http.StatusOK, expectedRoute: "markdown"},
+		{name: "unknown backend", path: "/tasks?backend=caldav", token: tokenFor(""), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routed = nil
+			body := `{"title":"Task"}`
+			if strings.HasPrefix(tt.path, "/tasks/batch") {
+				body = `[` + body + `]`
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedRoute == "" {
+				if len(routed) != 0 {
+					t.Errorf("Expected no backend call, got %v", routed)
+				}
+			} else if len(routed) != 1 || routed[0] != tt.expectedRoute {
+				t.Errorf("Expected task routed to %s, got %v", tt.expectedRoute, routed)
+			}
+		})
+	}
+}
+
+func TestServer_TaskQueries(t *testing.T) {
+	cfg := &config.Config{
+		Port: "8788",
+	}
+
+	var query services.TaskQuery
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		ListTasksFunc: func(ctx context.Context, q services.TaskQuery) services.TaskListResponse {
+			query = q
+			return services.TaskListResponse{Status: "ok", Tasks: []services.Task{{ID: "abc", Title: "Buy milk"}}}
+		},
+		GetTaskFunc: func(ctx context.Context, id string) services.TaskGetResponse {
+			if id != "abc" {
+				return services.TaskGetResponse{Status: "ok"}
+			}
+			return services.TaskGetResponse{Status: "ok", Found: true, Task: services.Task{ID: "abc", Title: "Buy milk"}}
+		},
+	}
+	backends := mocks.NewTaskBackends(mockOmniFocusService)
+	backends.Register(services.BackendWebhook, services.NewWebhookTaskBackend("http://127.0.0.1:0", "", nil))
+
+	h := handlers.New(cfg, "test", backends, &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	jwtManager := auth.NewJWTManager("test-secret-key-with-at-least-32-characters")
+	srv, err := NewServer(cfg, h, auth.NewMiddleware(jwtManager, nil, logger, false, ""), nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tokenFor := func(scopes ...string) string {
+		token, err := jwtManager.GenerateToken(&auth.OAuthClient{ClientID: "client", Scopes: scopes}, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "list", path: "/tasks?project=Errands&flagged=true&completed=any&q=milk&limit=5", token: tokenFor("tasks:read"), expectedStatus: http.StatusOK, expectedBody: `"count":1`},
+		{name: "get", path: "/tasks/abc", token: tokenFor("tasks:read"), expectedStatus: http.StatusOK, expectedBody: `"title":"Buy milk"`},
+		{name: "get unknown", path: "/tasks/nope", token: tokenFor("tasks:read"), expectedStatus: http.StatusNotFound, expectedBody: `not_found`},
+		{name: "write scope only", path: "/tasks", token: tokenFor("tasks:write"), expectedStatus: http.StatusForbidden},
+		{name: "invalid limit", path: "/tasks?limit=0", token: tokenFor("tasks:read"), expectedStatus: http.StatusBadRequest, expectedBody: `invalid limit`},
+		{name: "invalid due date", path: "/tasks?due_before=someday", token: tokenFor("tasks:read"), expectedStatus: http.StatusBadRequest, expectedBody: `invalid due_before`},
+		{name: "backend without reads", path: "/tasks?backend=webhook", token: tokenFor("tasks:read"), expectedStatus: http.StatusNotImplemented, expectedBody: `not_supported`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	if query.Project != "Errands" || query.Flagged == nil || !*query.Flagged || query.Completed != nil || query.Search != "milk" || query.Limit != 5 {
+		t.Errorf("Expected query parameters to reach the service, got %+v", query)
+	}
+}
+
+func TestServer_TaskModifications(t *testing.T) {
+	cfg := &config.Config{
+		Port: "8788",
+	}
+
+	var update services.TaskUpdateRequest
+	var actions []string
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		UpdateTaskFunc: func(ctx context.Context, id string, req services.TaskUpdateRequest) services.TaskActionResponse {
+			if req.Project != nil && *req.Project == "Nowhere" {
+				failure := &services.ScriptError{Code: services.ScriptErrorProjectNotFound, Message: "Project not found: Nowhere"}
+				return services.TaskActionResponse{Status: "error", Reason: failure.Message, Err: failure}
+			}
+			if id != "abc" {
+				return services.TaskActionResponse{Status: "ok"}
+			}
+			update = req
+			return services.TaskActionResponse{Status: "ok", Found: true}
+		},
+		ActionFunc: func(ctx context.Context, action, id string) services.TaskActionResponse {
+			if id == "bad-id" {
+				failure := &services.ScriptError{Code: services.ScriptErrorInvalidArgument, Message: "Invalid task ID"}
+				return services.TaskActionResponse{Status: "error", Reason: failure.Message, Err: failure}
+			}
+			actions = append(actions, action+" "+id)
+			return services.TaskActionResponse{Status: "ok", Found: id == "abc"}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	jwtManager := auth.NewJWTManager("test-secret-key-with-at-least-32-characters")
+	srv, err := NewServer(cfg, h, auth.NewMiddleware(jwtManager, nil, logger, false, ""), nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tokenFor := func(scopes ...string) string {
+		token, err := jwtManager.GenerateToken(&auth.OAuthClient{ClientID: "client", Scopes: scopes}, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "update", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:update"), body: `{"title":"Renamed","tags":[],"due_date":null,"flagged":true}`, expectedStatus: http.StatusOK, expectedBody: `"id":"abc"`},
+		{name: "update unknown task", method: http.MethodPatch, path: "/tasks/nope", token: tokenFor("tasks:update"), body: `{"flagged":true}`, expectedStatus: http.StatusNotFound, expectedBody: `not_found`},
+		{name: "update without fields", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:update"), body: `{}`, expectedStatus: http.StatusBadRequest, expectedBody: `at least one field`},
+		{name: "update empty title", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:update"), body: `{"title":""}`, expectedStatus: http.StatusBadRequest, expectedBody: `title cannot be empty`},
+		{name: "update invalid date", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:update"), body: `{"due_date":42}`, expectedStatus: http.StatusBadRequest, expectedBody: `invalid due_date`},
+		{name: "update unknown project", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:update"), body: `{"project":"Nowhere"}`, expectedStatus: http.StatusUnprocessableEntity, expectedBody: `Project not found`},
+		{name: "complete rejected by script", method: http.MethodPost, path: "/tasks/bad-id/complete", token: tokenFor("tasks:update"), expectedStatus: http.StatusBadRequest, expectedBody: `Invalid task ID`},
+		{name: "update needs scope", method: http.MethodPatch, path: "/tasks/abc", token: tokenFor("tasks:write"), body: `{"flagged":true}`, expectedStatus: http.StatusForbidden},
+		{name: "complete", method: http.MethodPost, path: "/tasks/abc/complete", token: tokenFor("tasks:update"), expectedStatus: http.StatusOK, expectedBody: `"status":"ok"`},
+		{name: "delete", method: http.MethodDelete, path: "/tasks/abc", token: tokenFor("tasks:delete"), expectedStatus: http.StatusOK, expectedBody: `"status":"ok"`},
+		{name: "delete needs scope", method: http.MethodDelete, path: "/tasks/abc", token: tokenFor("tasks:update"), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	if update.Title == nil || *update.Title != "Renamed" {
+		t.Errorf("Expected title update, got %+v", update)
+	}
+	if update.Tags == nil || len(*update.Tags) != 0 || !update.ClearDueDate || update.DueDate != nil {
+		t.Errorf("Expected tags and due date to be cleared, got %+v", update)
+	}
+	if strings.Join(actions, ",") != "complete abc,delete abc" {
+		t.Errorf("Expected complete and delete calls, got %v", actions)
+	}
+}
+
+func TestServer_TaskWarnings(t *testing.T) {
+	cfg := &config.Config{
+		Port:  "8788",
+		Token: "test-token",
+	}
+
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			return services.TaskCreateResponse{
+				Status:   "ok",
+				Created:  true,
+				ID:       "kXu2sF3vQ1a",
+				Warnings: []string{"Tag 'urgent' could not be assigned"},
+			}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Buy milk","tags":["urgent"]}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	expected := `"id":"kXu2sF3vQ1a","warnings":["Tag 'urgent' could not be assigned"]`
+	if !strings.Contains(rr.Body.String(), expected) {
+		t.Errorf("Expected body to contain %s, got %s", expected, rr.Body.String())
+	}
+}
+
+func TestServer_FileUploads(t *testing.T) {
+	cfg := &config.Config{
+		Port:               "8788",
+		Token:              "test-token",
+		FilesDir:           t.TempDir(),
+		FilesMaxUploadSize: 1024,
+		FilesUploadTimeout: time.Minute,
+	}
+
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(&mocks.MockOmniFocusService{}), services.NewFilesService(cfg), nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	multipartBody := func(filename, content string) (string, string) {
+		var buf strings.Builder
+		writer := multipart.NewWriter(&buf)
+		if err := writer.WriteField("directory", "uploads"); err != nil {
+			t.Fatalf("Failed to write field: %v", err)
+		}
+		part, err := writer.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatalf("Failed to create file part: %v", err)
+		}
+		_, _ = part.Write([]byte(content))
+		_ = writer.Close()
+		return buf.String(), writer.FormDataContentType()
+	}
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	formBody, formType := multipartBody("form.txt", "from a form")
+	largeBody, largeType := multipartBody("large.txt", strings.Repeat("x", 2048))
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		headers     map[string]string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantPath    string
+		wantContent string
+	}{
+		{
+			name:        "octet-stream with header filename",
+			target:      "/files",
+			contentType: "application/octet-stream",
+			headers:     map[string]string{"X-Filename": "raw.bin", "X-Directory": "uploads"},
+			body:        "raw bytes",
+			wantStatus:  http.StatusOK,
+			wantPath:    "uploads/raw.bin",
+			wantContent: "raw bytes",
+		},
+		{
+			name:        "octet-stream with query filename",
+			target:      "/files?filename=query.bin",
+			contentType: "application/octet-stream",
+			body:        "query bytes",
+			wantStatus:  http.StatusOK,
+			wantPath:    "query.bin",
+			wantContent: "query bytes",
+		},
+		{
+			name:        "multipart",
+			target:      "/files",
+			contentType: formType,
+			body:        formBody,
+			wantStatus:  http.StatusOK,
+			wantPath:    "uploads/form.txt",
+			wantContent: "from a form",
+		},
+		{
+			name:        "octet-stream without filename",
+			target:      "/files",
+			contentType: "application/octet-stream",
+			body:        "raw bytes",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "octet-stream over the limit",
+			target:      "/files?filename=big.bin",
+			contentType: "application/octet-stream",
+			body:        strings.Repeat("x", 2048),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "multipart over the limit",
+			target:      "/files",
+			contentType: largeType,
+			body:        largeBody,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "json with base64 content",
+			target:      "/files",
+			contentType: "application/json",
+			body:        `{"filename":"encoded.bin","encoding":"base64","content":"ZW5jb2RlZA==","content_sha256":"` + checksum("encoded") + `"}`,
+			wantStatus:  http.StatusOK,
+			wantPath:    "encoded.bin",
+			wantContent: "encoded",
+		},
+		{
+			name:        "json checksum mismatch",
+			target:      "/files",
+			contentType: "application/json",
+			body:        `{"filename":"mismatch.txt","content":"sent","content_sha256":"` + checksum("expected") + `"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "checksum_mismatch",
+		},
+		{
+			name:        "octet-stream checksum mismatch",
+			target:      "/files?filename=mismatch.bin",
+			contentType: "application/octet-stream",
+			headers:     map[string]string{"X-Content-SHA256": checksum("expected")},
+			body:        "sent",
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "checksum_mismatch",
+		},
+		{
+			name:        "unsupported media type",
+			target:      "/files",
+			contentType: "text/plain",
+			body:        "hello",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("Content-Type", tt.contentType)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d (%s)", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != "" && !strings.Contains(rr.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp handlers.FileResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Path != tt.wantPath {
+				t.Errorf("Expected path %q, got %q", tt.wantPath, resp.Path)
+			}
+			if resp.Size != int64(len(tt.wantContent)) {
+				t.Errorf("Expected size %d, got %d", len(tt.wantContent), resp.Size)
+			}
+			if resp.SHA256 != checksum(tt.wantContent) {
+				t.Errorf("Expected sha256 %s, got %s", checksum(tt.wantContent), resp.SHA256)
+			}
+			content, err := os.ReadFile(filepath.Join(cfg.FilesDir, tt.wantPath))
+			if err != nil {
+				t.Fatalf("Failed to read uploaded file: %v", err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("Expected content %q, got %q", tt.wantContent, content)
+			}
+		})
+	}
+}
+
+func TestServer_IdempotentTaskBodyLimit(t *testing.T) {
+	cfg := &config.Config{
+		Port:  "8788",
+		Token: "test-token",
+	}
+
+	store, err := idempotency.NewStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create idempotency store: %v", err)
+	}
+	logger := observability.SetupLogger()
+	calls := 0
+	mockOmniFocusService := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			calls++
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(mockOmniFocusService), &mocks.MockFilesService{}, nil, nil, nil)
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, idempotency.NewMiddleware(store, logger), logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	// Within the file route's limit but not the task route's, which is all the middleware may buffer
+	body := `{"title":"Large","note":"` + strings.Repeat("x", handlers.MaxTaskRequestSize) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotency.HeaderKey, "task-1")
+	rr := httptest.NewRecorder()
+	srv.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if calls != 0 {
+		t.Errorf("Expected the task not to be created, got %d calls", calls)
+	}
+}
+
+func TestServer_IdempotentFileUpload(t *testing.T) {
+	cfg := &config.Config{
+		Port:               "8788",
+		Token:              "test-token",
+		FilesDir:           t.TempDir(),
+		FilesMaxUploadSize: 32 << 20,
+		FilesUploadTimeout: time.Minute,
+	}
+
+	store, err := idempotency.NewStore(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create idempotency store: %v", err)
+	}
+	logger := observability.SetupLogger()
+	idempotencyMiddleware := idempotency.NewMiddleware(store, logger)
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(&mocks.MockOmniFocusService{}), services.NewFilesService(cfg), nil, nil, nil)
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, idempotencyMiddleware, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	// Larger than the buffered body limit of the idempotency middleware
+	content := strings.Repeat("x", handlers.MaxFileRequestSize+1)
+	sum := sha256.Sum256([]byte(content))
+	upload := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(content))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set(handlers.HeaderFilename, "large.bin")
+		req.Header.Set(handlers.HeaderContentSHA256, hex.EncodeToString(sum[:]))
+		req.Header.Set(idempotency.HeaderKey, "upload-1")
+		rr := httptest.NewRecorder()
+		srv.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := upload()
+	if first.Code != http.StatusOK && first.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload to succeed, got %d (%s)", first.Code, first.Body.String())
+	}
+	info, err := os.Stat(filepath.Join(cfg.FilesDir, "large.bin"))
+	if err != nil || info.Size() != int64(len(content)) {
+		t.Fatalf("Expected the whole file to be written, got %v, %v", info, err)
+	}
+
+	second := upload()
+	if second.Code != first.Code || second.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Errorf("Expected the first response to be replayed, got %d (%s)", second.Code, second.Body.String())
+	}
+}
+
+func TestServer_FileWriteModes(t *testing.T) {
+	cfg := &config.Config{
+		Port:               "8788",
+		Token:              "test-token",
+		FilesDir:           t.TempDir(),
+		FilesMaxUploadSize: 1024,
+		FilesUploadTimeout: time.Minute,
+	}
+
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(&mocks.MockOmniFocusService{}), services.NewFilesService(cfg), nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	post := func(body, ifMatch string) (*httptest.ResponseRecorder, handlers.FileResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		srv.router.ServeHTTP(rr, req)
+
+		var resp handlers.FileResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return rr, resp
+	}
+
+	rr, first := post(`{"filename":"journal.md","content":"day 1\n"}`, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for create, got %d (%s)", rr.Code, rr.Body.String())
+	}
+
+	rr, appended := post(`{"filename":"journal.md","content":"day 2\n","mode":"append"}`, `"`+first.SHA256+`"`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for append, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if appended.Created {
+		t.Error("Expected created=false when appending to an existing file")
+	}
+
+	// A client still holding the first checksum must not overwrite the appended file
+	rr, stale := post(`{"filename":"journal.md","content":"rewritten\n","mode":"overwrite"}`, `"`+first.SHA256+`"`)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412 for a stale If-Match, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if stale.Code != "precondition_failed" {
+		t.Errorf("Expected code precondition_failed, got %q", stale.Code)
+	}
+
+	content, err := os.ReadFile(filepath.Join(cfg.FilesDir, "journal.md"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "day 1\nday 2\n" {
+		t.Errorf("Expected appended content, got %q", content)
+	}
+}
+
+func TestServer_FileClientScopes(t *testing.T) {
+	cfg := &config.Config{
+		Port:               "8788",
+		FilesDir:           t.TempDir(),
+		FilesMaxUploadSize: 1024,
+		FilesUploadTimeout: time.Minute,
+	}
+
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(&mocks.MockOmniFocusService{}), services.NewFilesService(cfg), nil, nil, nil)
+	logger := observability.SetupLogger()
+	jwtManager := auth.NewJWTManager("test-secret-key-with-at-least-32-characters")
+	scopes := []string{"files:write", "files:read"}
+	clients := newClientRepository(t,
+		auth.OAuthClient{ClientID: "scoped", Scopes: scopes, Files: auth.FilesPolicy{Namespace: true, MaxBytes: 10, MaxFiles: 2}},
+		auth.OAuthClient{ClientID: "shared", Scopes: scopes},
+	)
+	srv, err := NewServer(cfg, h, auth.NewMiddleware(jwtManager, clients, logger, false, ""), nil, nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	tokenFor := func(clientID string) string {
+		token, err := jwtManager.GenerateToken(&auth.OAuthClient{ClientID: clientID, Scopes: scopes}, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+	scoped := tokenFor("scoped")
+	shared := tokenFor("shared")
+
+	post := func(token, body string) (*httptest.ResponseRecorder, handlers.FileResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/files", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		srv.router.ServeHTTP(rr, req)
+
+		var resp handlers.FileResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return rr, resp
+	}
+
+	rr, created := post(scoped, `{"filename":"a.txt","content":"012345"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if created.Path != "a.txt" {
+		t.Errorf("Expected a path relative to the namespace, got %q", created.Path)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.FilesDir, "clients", "scoped", "a.txt")); err != nil {
+		t.Errorf("Expected the file in the client's namespace: %v", err)
+	}
+
+	rr, overQuota := post(scoped, `{"filename":"b.txt","content":"01234"}`)
+	if rr.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected status 507 over the byte quota, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if overQuota.Code != "quota_exceeded" || !strings.Contains(overQuota.Reason, "quota exceeded") {
+		t.Errorf("Expected a quota_exceeded reason, got %+v", overQuota)
+	}
+
+	rr, tooLarge := post(scoped, `{"filename":"c.txt","content":"0123456789a"}`)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for a file larger than the quota, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if tooLarge.Code != "too_large" {
+		t.Errorf("Expected code too_large, got %q", tooLarge.Code)
+	}
+
+	// A client without a policy shares the base directory but cannot read the namespaces
+	if rr, _ := post(shared, `{"filename":"a.txt","content":"shared"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/files/clients/scoped/a.txt", nil)
+	req.Header.Set("Authorization", "Bearer "+shared)
+	rr = httptest.NewRecorder()
+	srv.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for another client's file, got %d (%s)", rr.Code, rr.Body.String())
+	}
+
+	// The scoped client reads its own a.txt, not the shared one
+	req = httptest.NewRequest(http.MethodGet, "/files/a.txt", nil)
+	req.Header.Set("Authorization", "Bearer "+scoped)
+	rr = httptest.NewRecorder()
+	srv.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "012345" {
+		t.Errorf("Expected the client's own file, got %d (%s)", rr.Code, rr.Body.String())
+	}
+}
+
+func TestServer_FileManagement(t *testing.T) {
+	cfg := &config.Config{
+		Port:               "8788",
+		Token:              "test-token",
+		FilesDir:           t.TempDir(),
+		FilesUploadTimeout: time.Minute,
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.FilesDir, "reports"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.FilesDir, "reports", "report.txt"), []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	h := handlers.New(cfg, "test", mocks.NewTaskBackends(&mocks.MockOmniFocusService{}), services.NewFilesService(cfg), nil, nil, nil)
+	logger := observability.SetupLogger()
+	srv, err := NewServer(cfg, h, nil, middleware.NewLegacyAuthMiddleware(cfg.Token, logger), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test server: %v", err)
+	}
+
+	do := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		srv.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	sum := sha256.Sum256([]byte("0123456789"))
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("list", func(t *testing.T) {
+		rr := do(http.MethodGet, "/files", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		var root handlers.FileListResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &root); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(root.Entries) != 1 || root.Entries[0].Type != "directory" || root.Entries[0].Path != "reports" {
+			t.Errorf("Expected the reports directory, got %+v", root.Entries)
+		}
+
+		rr = do(http.MethodGet, "/files?dir=reports", nil)
+		var reports handlers.FileListResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &reports); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(reports.Entries) != 1 {
+			t.Fatalf("Expected one entry, got %+v", reports.Entries)
+		}
+		entry := reports.Entries[0]
+		if entry.Path != "reports/report.txt" || entry.Size != 10 || entry.SHA256 != checksum || entry.Modified.IsZero() {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+	})
+
+	t.Run("download range", func(t *testing.T) {
+		rr := do(http.MethodGet, "/files/reports/report.txt", map[string]string{"Range": "bytes=2-5"})
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if rr.Body.String() != "2345" {
+			t.Errorf("Expected bytes 2-5, got %q", rr.Body.String())
+		}
+		if got := rr.Header().Get("ETag"); got != `"`+checksum+`"` {
+			t.Errorf("Expected the checksum as ETag, got %q", got)
+		}
+	})
+
+	t.Run("download not modified", func(t *testing.T) {
+		rr := do(http.MethodGet, "/files/reports/report.txt", map[string]string{"If-None-Match": `"` + checksum + `"`})
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("Expected status 304, got %d", rr.Code)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			method string
+			target string
+			want   int
+		}{
+			{http.MethodGet, "/files?dir=../etc", http.StatusBadRequest},
+			{http.MethodGet, "/files?dir=missing", http.StatusNotFound},
+			{http.MethodGet, "/files/missing.txt", http.StatusNotFound},
+			{http.MethodGet, "/files/reports", http.StatusBadRequest},
+			{http.MethodDelete, "/files/reports", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			if rr := do(tt.method, tt.target, nil); rr.Code != tt.want {
+				t.Errorf("%s %s: expected status %d, got %d (%s)", tt.method, tt.target, tt.want, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rr := do(http.MethodDelete, "/files/reports/report.txt", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, err := os.Stat(filepath.Join(cfg.FilesDir, "reports", "report.txt")); !os.IsNotExist(err) {
+			t.Error("Expected the file to be deleted")
+		}
+		if rr := do(http.MethodDelete, "/files/reports/report.txt", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for a second delete, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"omnidrop/internal/observability"
+)
+
+// Built-in task backend names
+const (
+	BackendOmniFocus = "omnifocus"
+	BackendMarkdown  = "markdown"
+	BackendTodoTxt   = "todotxt"
+	BackendCalDAV    = "caldav"
+	BackendWebhook   = "webhook"
+)
+
+// ErrOutcomeUnknown is matched by the error of a task creation that failed after the backend
+// may already have created the task, e.g. an osascript run killed at its deadline.
+// Such a task must not be retried automatically, or it can be created twice.
+var ErrOutcomeUnknown = errors.New("task may have been created")
+
+// Retryable reports whether a failed task creation can be tried again: it must not risk a
+// duplicate, and the failure must not be caused by the request itself, such as a missing project
+func Retryable(err error) bool {
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) && scriptErr.Code.Permanent() {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.Permanent() {
+		return false
+	}
+	return !errors.Is(err, ErrOutcomeUnknown)
+}
+
+// HTTPStatusError is returned by the HTTP-based backends when the server answers with a
+// status other than 2xx
+type HTTPStatusError struct {
+	Server     string // e.g. "webhook" or "CalDAV server"
+	StatusCode int
+	Detail     string // start of the response body
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Server, e.StatusCode, e.Detail)
+}
+
+// Permanent reports whether the server rejected the request itself. Client errors other than
+// 408 Request Timeout and 429 Too Many Requests do not go away when the request is repeated.
+func (e *HTTPStatusError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+// requestError wraps the error of an HTTP request that got no response. Only a failure to
+// connect proves that the request was never sent; after that the server may have acted on it
+// before the timeout or broken connection, so the outcome is unknown.
+func requestError(server string, err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("%s request failed: %w", server, err)
+	}
+	return fmt.Errorf("%w: %s request failed: %w", ErrOutcomeUnknown, server, err)
+}
+
+// statusError reads the start of a non-2xx response into an HTTPStatusError
+func statusError(server string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &HTTPStatusError{Server: server, StatusCode: resp.StatusCode, Detail: strings.TrimSpace(string(detail))}
+}
+
+// TaskBackendRegistry routes each task to the backend named by its Backend field.
+// It implements TaskBackend itself, so it can stand in for a single backend.
+type TaskBackendRegistry struct {
+	backends    map[string]TaskBackend
+	defaultName string
+}
+
+// Ensure TaskBackendRegistry implements TaskBackend
+var _ TaskBackend = (*TaskBackendRegistry)(nil)
+
+// NewTaskBackendRegistry creates an empty registry that routes unnamed tasks to defaultName
+func NewTaskBackendRegistry(defaultName string) *TaskBackendRegistry {
+	return &TaskBackendRegistry{
+		backends:    make(map[string]TaskBackend),
+		defaultName: defaultName,
+	}
+}
+
+// Register adds or replaces a backend. Registration is not safe for concurrent use
+// and must be completed before the registry serves requests.
+func (r *TaskBackendRegistry) Register(name string, backend TaskBackend) {
+	r.backends[name] = backend
+}
+
+// SetDefault changes the backend used for tasks without an explicit backend
+func (r *TaskBackendRegistry) SetDefault(name string) {
+	r.defaultName = name
+}
+
+// Has reports whether a backend with the given name is registered
+func (r *TaskBackendRegistry) Has(name string) bool {
+	_, ok := r.backends[name]
+	return ok
+}
+
+// Backend returns the backend registered under name
+func (r *TaskBackendRegistry) Backend(name string) (TaskBackend, bool) {
+	backend, ok := r.backends[name]
+	return backend, ok
+}
+
+// Default returns the name of the backend used for tasks without an explicit backend
+func (r *TaskBackendRegistry) Default() string {
+	return r.defaultName
+}
+
+// Names returns the registered backend names in sorted order
+func (r *TaskBackendRegistry) Names() []string {
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateTask creates the task in its backend
+func (r *TaskBackendRegistry) CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse {
+	name, backend, err := r.resolve(req.Backend)
+	if err != nil {
+		return TaskCreateResponse{Status: "error", Reason: err.Error()}
+	}
+
+	resp := backend.CreateTask(ctx, req)
+	recordBackendResult(name, resp)
+	return resp
+}
+
+// CreateTasks groups the tasks by backend and creates each group with one backend call.
+// Responses are returned in request order.
+func (r *TaskBackendRegistry) CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse {
+	responses := make([]TaskCreateResponse, len(reqs))
+
+	// Group request indexes by backend, keeping first-seen order
+	groups := make(map[string][]int)
+	var order []string
+	for i, req := range reqs {
+		name := req.Backend
+		if name == "" {
+			name = r.defaultName
+		}
+		if _, seen := groups[name]; !seen {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], i)
+	}
+
+	for _, name := range order {
+		indexes := groups[name]
+		_, backend, err := r.resolve(name)
+		if err != nil {
+			for _, i := range indexes {
+				responses[i] = TaskCreateResponse{Status: "error", Reason: err.Error()}
+			}
+			continue
+		}
+
+		groupReqs := make([]TaskCreateRequest, len(indexes))
+		for j, i := range indexes {
+			groupReqs[j] = reqs[i]
+		}
+		for j, resp := range backend.CreateTasks(ctx, groupReqs) {
+			responses[indexes[j]] = resp
+			recordBackendResult(name, resp)
+		}
+	}
+
+	return responses
+}
+
+func (r *TaskBackendRegistry) resolve(name string) (string, TaskBackend, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	backend, ok := r.backends[name]
+	if !ok {
+		return name, nil, fmt.Errorf("unknown task backend '%s'", name)
+	}
+	return name, backend, nil
+}
+
+func recordBackendResult(name string, resp TaskCreateResponse) {
+	label := "success"
+	if resp.Status == "error" {
+		label = "failure"
+	}
+	observability.TaskBackendRequestsTotal.WithLabelValues(name, label).Inc()
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBackendHTTPTimeout bounds a single request made by the HTTP-based task backends
+const DefaultBackendHTTPTimeout = 10 * time.Second
+
+// icalTimeFormat is the iCalendar UTC date-time format (RFC 5545 section 3.3.5)
+const icalTimeFormat = "20060102T150405Z"
+
+// CalDAVTaskBackend stores tasks as VTODO resources in a CalDAV calendar collection
+type CalDAVTaskBackend struct {
+	collectionURL string
+	username      string
+	password      string
+	client        *http.Client
+	now           func() time.Time
+}
+
+// Ensure CalDAVTaskBackend implements TaskBackend
+var _ TaskBackend = (*CalDAVTaskBackend)(nil)
+
+// NewCalDAVTaskBackend creates a backend for the calendar collection at collectionURL.
+// Basic authentication is used when username is set; a nil client selects a default one.
+func NewCalDAVTaskBackend(collectionURL, username, password string, client *http.Client) *CalDAVTaskBackend {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultBackendHTTPTimeout}
+	}
+	return &CalDAVTaskBackend{
+		collectionURL: strings.TrimSuffix(collectionURL, "/") + "/",
+		username:      username,
+		password:      password,
+		client:        client,
+		now:           time.Now,
+	}
+}
+
+// CreateTask uploads the task and its subtasks, linking each subtask to its parent with RELATED-TO.
+// If the top-level task is stored but some subtasks fail, the task is reported as created
+// and the failed subtasks are listed in Reason.
+// The UIDs are derived from the delivery ID, so a retry finds the resources of an earlier
+// attempt instead of creating them again.
+func (b *CalDAVTaskBackend) CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse {
+	uid := req.DeliveryID
+	if uid == "" {
+		uid = uuid.NewString()
+	}
+	if err := b.put(ctx, req, uid, ""); err != nil {
+		return TaskCreateResponse{Status: "error", Reason: err.Error(), Err: err}
+	}
+
+	var failures []string
+	b.putChildren(ctx, req, uid, &failures)
+
+	resp := TaskCreateResponse{Status: "ok", Created: true}
+	if len(failures) > 0 {
+		resp.Reason = fmt.Sprintf("%d subtask(s) could not be created: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return resp
+}
+
+// CreateTasks creates each task independently
+func (b *CalDAVTaskBackend) CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse {
+	responses := make([]TaskCreateResponse, len(reqs))
+	for i, req := range reqs {
+		responses[i] = b.CreateTask(ctx, req)
+	}
+	return responses
+}
+
+func (b *CalDAVTaskBackend) putChildren(ctx context.Context, parent TaskCreateRequest, parentUID string, failures *[]string) {
+	for i, child := range parent.Children {
+		uid := fmt.Sprintf("%s-%d", parentUID, i+1)
+		if err := b.put(ctx, child, uid, parentUID); err != nil {
+			// Descendants of a failed subtask are skipped along with it
+			*failures = append(*failures, fmt.Sprintf("subtask '%s': %v", child.Title, err))
+			continue
+		}
+		b.putChildren(ctx, child, uid, failures)
+	}
+}
+
+// put uploads one VTODO with the given UID. A resource that already exists was stored by an
+// earlier attempt of the same delivery and counts as uploaded.
+func (b *CalDAVTaskBackend) put(ctx context.Context, req TaskCreateRequest, uid, parentUID string) error {
+	body := b.buildVTODO(req, uid, parentUID)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, b.collectionURL+url.PathEscape(uid)+".ics", strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build CalDAV request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	// Never overwrite an existing resource
+	httpReq.Header.Set("If-None-Match", "*")
+	if b.username != "" {
+		httpReq.SetBasicAuth(b.username, b.password)
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return requestError("CalDAV", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		// If-None-Match failed: the UID is taken by this task's earlier attempt
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError("CalDAV server", resp)
+	}
+	return nil
+}
+
+// buildVTODO renders the task as an iCalendar object (RFC 5545)
+func (b *CalDAVTaskBackend) buildVTODO(req TaskCreateRequest, uid, parentUID string) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//omnidrop//omnidrop//EN",
+		"BEGIN:VTODO",
+		"UID:" + uid,
+		"DTSTAMP:" + b.now().UTC().Format(icalTimeFormat),
+		"SUMMARY:" + escapeICalText(req.Title),
+		"STATUS:NEEDS-ACTION",
+	}
+	if req.Note != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICalText(req.Note))
+	}
+	if req.DeferDate != nil {
+		lines = append(lines, "DTSTART:"+req.DeferDate.UTC().Format(icalTimeFormat))
+	}
+	if req.DueDate != nil {
+		lines = append(lines, "DUE:"+req.DueDate.UTC().Format(icalTimeFormat))
+	}
+	if len(req.Tags) > 0 {
+		escaped := make([]string, len(req.Tags))
+		for i, tag := range req.Tags {
+			escaped[i] = escapeICalText(tag)
+		}
+		lines = append(lines, "CATEGORIES:"+strings.Join(escaped, ","))
+	}
+	if req.Flagged {
+		lines = append(lines, "PRIORITY:1")
+	}
+	if req.Project != "" {
+		lines = append(lines, "X-OMNIDROP-PROJECT:"+escapeICalText(req.Project))
+	}
+	if req.EstimatedMinutes > 0 {
+		lines = append(lines, fmt.Sprintf("X-OMNIDROP-ESTIMATED-MINUTES:%d", req.EstimatedMinutes))
+	}
+	if parentUID != "" {
+		lines = append(lines, "RELATED-TO;RELTYPE=PARENT:"+parentUID)
+	}
+	lines = append(lines, "END:VTODO", "END:VCALENDAR")
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(foldICalLine(line))
+		sb.WriteString("\r\n")
+	}
+	return sb.String()
+}
+
+// escapeICalText escapes a TEXT property value (RFC 5545 section 3.3.11)
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets without breaking UTF-8 sequences (RFC 5545 section 3.1)
+func foldICalLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var sb strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			sb.WriteString("\r\n ")
+			width = 1
+		}
+		sb.WriteRune(r)
+		width += size
+	}
+	return sb.String()
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileTaskBackend appends tasks to a Markdown checklist or a todo.txt file under FilesDir
+type FileTaskBackend struct {
+	format string        // BackendMarkdown or BackendTodoTxt
+	files  *FilesService // its file system is rooted at FilesDir, so the path cannot be redirected outside it
+	name   string        // path of the task file relative to FilesDir
+	loc    *time.Location
+	now    func() time.Time
+}
+
+// Ensure FileTaskBackend implements TaskBackend
+var _ TaskBackend = (*FileTaskBackend)(nil)
+
+// NewFileTaskBackend creates a file backend writing format (BackendMarkdown or BackendTodoTxt)
+// to filename, which is resolved inside the files directory with the same rules as POST /files.
+// Appends take the path lock of files, so they never interleave with a write through the API.
+func NewFileTaskBackend(files *FilesService, format, filename string) (*FileTaskBackend, error) {
+	if format != BackendMarkdown && format != BackendTodoTxt {
+		return nil, fmt.Errorf("unsupported task file format '%s'", format)
+	}
+
+	directory := filepath.Dir(filename)
+	if directory == "." {
+		directory = ""
+	}
+	_, name, err := files.validateAndBuildPath(filepath.Base(filename), directory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s task file: %w", format, err)
+	}
+
+	return &FileTaskBackend{
+		format: format,
+		files:  files,
+		name:   name,
+		loc:    files.cfg.Location(),
+		now:    time.Now,
+	}, nil
+}
+
+// CreateTask appends the task, and any subtasks, to the file
+func (b *FileTaskBackend) CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse {
+	return b.CreateTasks(ctx, []TaskCreateRequest{req})[0]
+}
+
+// CreateTasks appends all tasks with a single write, so either every task is written or none is
+func (b *FileTaskBackend) CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse {
+	var sb strings.Builder
+	for _, req := range reqs {
+		if b.format == BackendMarkdown {
+			b.writeMarkdown(&sb, req, 0)
+		} else {
+			b.writeTodoTxt(&sb, req, req.Project)
+		}
+	}
+
+	resp := TaskCreateResponse{Status: "ok", Created: true}
+	if err := b.appendToFile(sb.String()); err != nil {
+		resp = TaskCreateResponse{Status: "error", Reason: err.Error()}
+	}
+
+	responses := make([]TaskCreateResponse, len(reqs))
+	for i := range responses {
+		responses[i] = resp
+	}
+	return responses
+}
+
+// appendToFile appends content under the file's commit lock, so a POST /files overwrite or
+// append that renames a new copy into place cannot drop it, and flushes it to disk before the
+// tasks are reported as created
+func (b *FileTaskBackend) appendToFile(content string) error {
+	defer b.files.lockCommit(b.name)()
+
+	if err := b.files.fs.MkdirAll(filepath.Dir(b.name), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	f, err := b.files.fs.OpenAppend(b.name, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open task file: %v", err)
+	}
+	_, err = io.WriteString(f, content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write task file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write task file: %v", err)
+	}
+	return nil
+}
+
+// writeMarkdown renders a checklist item with Dataview-style inline fields;
+// subtasks are nested list items and notes are indented under their task
+func (b *FileTaskBackend) writeMarkdown(sb *strings.Builder, req TaskCreateRequest, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	sb.WriteString(indent + "- [ ] " + singleLine(req.Title))
+	if req.Project != "" {
+		fmt.Fprintf(sb, " [project:: %s]", singleLine(req.Project))
+	}
+	if req.DeferDate != nil {
+		fmt.Fprintf(sb, " [start:: %s]", req.DeferDate.In(b.loc).Format("2006-01-02 15:04"))
+	}
+	if req.DueDate != nil {
+		fmt.Fprintf(sb, " [due:: %s]", req.DueDate.In(b.loc).Format("2006-01-02 15:04"))
+	}
+	if req.EstimatedMinutes > 0 {
+		fmt.Fprintf(sb, " [estimate:: %dm]", req.EstimatedMinutes)
+	}
+	if req.Flagged {
+		sb.WriteString(" [flagged:: true]")
+	}
+	if req.Sequential {
+		sb.WriteString(" [sequential:: true]")
+	}
+	for _, tag := range req.Tags {
+		sb.WriteString(" #" + tagToken(tag))
+	}
+	sb.WriteString("\n")
+
+	if req.Note != "" {
+		for _, line := range strings.Split(strings.TrimRight(req.Note, "\n"), "\n") {
+			sb.WriteString(indent + "  " + line + "\n")
+		}
+	}
+
+	for _, child := range req.Children {
+		b.writeMarkdown(sb, child, depth+1)
+	}
+}
+
+// writeTodoTxt renders one todo.txt line per task. The format has no hierarchy or notes:
+// subtasks become separate lines in their top-level task's project and notes are dropped.
+func (b *FileTaskBackend) writeTodoTxt(sb *strings.Builder, req TaskCreateRequest, project string) {
+	if req.Flagged {
+		sb.WriteString("(A) ")
+	}
+	sb.WriteString(b.now().In(b.loc).Format("2006-01-02") + " " + singleLine(req.Title))
+	if project != "" {
+		sb.WriteString(" +" + tagToken(project))
+	}
+	for _, tag := range req.Tags {
+		sb.WriteString(" @" + tagToken(tag))
+	}
+	if req.DueDate != nil {
+		sb.WriteString(" due:" + req.DueDate.In(b.loc).Format("2006-01-02"))
+	}
+	if req.DeferDate != nil {
+		sb.WriteString(" t:" + req.DeferDate.In(b.loc).Format("2006-01-02"))
+	}
+	if req.EstimatedMinutes > 0 {
+		fmt.Fprintf(sb, " estimate:%dm", req.EstimatedMinutes)
+	}
+	sb.WriteString("\n")
+
+	for _, child := range req.Children {
+		b.writeTodoTxt(sb, child, project)
+	}
+}
+
+// singleLine collapses line breaks so a value cannot start a new entry
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tagToken turns a tag or project path into a single whitespace-free token ("Work : Q4" -> "Work:Q4")
+func tagToken(s string) string {
+	parts := strings.Split(s, ":")
+	for i, part := range parts {
+		parts[i] = strings.Join(strings.Fields(part), "_")
+	}
+	return strings.Join(parts, ":")
+}
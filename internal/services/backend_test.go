@@ -0,0 +1,431 @@
+package services_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/config"
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+func TestTaskBackendRegistry_RoutesByBackend(t *testing.T) {
+	var omnifocusTitles, webhookTitles []string
+	omnifocus := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			omnifocusTitles = append(omnifocusTitles, req.Title)
+			return services.TaskCreateResponse{Status: "ok", Created: true}
+		},
+	}
+	webhook := &mocks.MockOmniFocusService{
+		CreateTaskFunc: func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
+			webhookTitles = append(webhookTitles, req.Title)
+			return services.TaskCreateResponse{Status: "error", Reason: "down"}
+		},
+	}
+
+	registry := services.NewTaskBackendRegistry(services.BackendOmniFocus)
+	registry.Register(services.BackendOmniFocus, omnifocus)
+	registry.Register(services.BackendWebhook, webhook)
+
+	responses := registry.CreateTasks(context.Background(), []services.TaskCreateRequest{
+		{Title: "A"},
+		{Title: "B", Backend: services.BackendWebhook},
+		{Title: "C", Backend: services.BackendOmniFocus},
+		{Title: "D", Backend: "nope"},
+	})
+
+	require.Len(t, responses, 4)
+	assert.True(t, responses[0].Created)
+	assert.Equal(t, "down", responses[1].Reason)
+	assert.True(t, responses[2].Created)
+	assert.Contains(t, responses[3].Reason, "unknown task backend 'nope'")
+	assert.Equal(t, []string{"A", "C"}, omnifocusTitles)
+	assert.Equal(t, []string{"B"}, webhookTitles)
+
+	assert.Equal(t, []string{"omnifocus", "webhook"}, registry.Names())
+	assert.True(t, registry.Has(services.BackendWebhook))
+	assert.False(t, registry.Has(services.BackendCalDAV))
+}
+
+func newFileBackendConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		FilesDir: t.TempDir(),
+		Timezone: time.UTC,
+	}
+}
+
+func TestFileTaskBackend_Markdown(t *testing.T) {
+	cfg := newFileBackendConfig(t)
+	backend, err := services.NewFileTaskBackend(services.NewFilesService(cfg), services.BackendMarkdown, "inbox/tasks.md")
+	require.NoError(t, err)
+
+	due := time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:      "Release 1.2",
+		Note:       "Checklist\nfor the release",
+		Project:    "Work : Releases",
+		Tags:       []string{"release", "high priority"},
+		DueDate:    &due,
+		Flagged:    true,
+		Sequential: true,
+		Children: []services.TaskCreateRequest{
+			{Title: "Tag\nv1.2", EstimatedMinutes: 5},
+		},
+	})
+	require.True(t, resp.Created, resp.Reason)
+
+	content, err := os.ReadFile(filepath.Join(cfg.FilesDir, "inbox", "tasks.md"))
+	require.NoError(t, err)
+	assert.Equal(t,
+		"- [ ] Release 1.2 [project:: Work : Releases] [due:: 2025-10-20 18:00] [flagged:: true] [sequential:: true] #release #high_priority\n"+
+			"  Checklist\n"+
+			"  for the release\n"+
+			"  - [ ] Tag v1.2 [estimate:: 5m]\n",
+		string(content))
+}
+
+func TestFileTaskBackend_TodoTxt(t *testing.T) {
+	cfg := newFileBackendConfig(t)
+	backend, err := services.NewFileTaskBackend(services.NewFilesService(cfg), services.BackendTodoTxt, "todo.txt")
+	require.NoError(t, err)
+
+	due := time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)
+	responses := backend.CreateTasks(context.Background(), []services.TaskCreateRequest{
+		{Title: "Pay rent", Project: "Home : Bills", Tags: []string{"errands"}, DueDate: &due, Flagged: true,
+			Children: []services.TaskCreateRequest{{Title: "Check balance"}}},
+		{Title: "Call mom"},
+	})
+	for _, resp := range responses {
+		require.True(t, resp.Created, resp.Reason)
+	}
+
+	content, err := os.ReadFile(filepath.Join(cfg.FilesDir, "todo.txt"))
+	require.NoError(t, err)
+	today := time.Now().UTC().Format("2006-01-02")
+	assert.Equal(t,
+		"(A) "+today+" Pay rent +Home:Bills @errands due:2025-10-20\n"+
+			today+" Check balance +Home:Bills\n"+
+			today+" Call mom\n",
+		string(content))
+}
+
+func TestFileTaskBackend_ConcurrentFileWrites(t *testing.T) {
+	cfg := newFileBackendConfig(t)
+	files := services.NewFilesService(cfg)
+	backend, err := services.NewFileTaskBackend(files, services.BackendMarkdown, "tasks.md")
+	require.NoError(t, err)
+
+	// Appends through POST /files rename a new copy of the file into place; the backend's
+	// lines written in between must not be lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: fmt.Sprintf("backend %d", i)})
+			assert.Equal(t, "ok", resp.Status, resp.Reason)
+		}()
+		go func() {
+			defer wg.Done()
+			resp := files.WriteFile(context.Background(), services.FileWriteRequest{
+				Filename: "tasks.md",
+				Content:  fmt.Sprintf("- [ ] api %d\n", i),
+				Mode:     services.FileModeAppend,
+			})
+			assert.Equal(t, "ok", resp.Status, resp.Reason)
+		}()
+	}
+	wg.Wait()
+
+	content, err := os.ReadFile(filepath.Join(cfg.FilesDir, "tasks.md"))
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		assert.Contains(t, string(content), fmt.Sprintf("- [ ] backend %d", i))
+		assert.Contains(t, string(content), fmt.Sprintf("- [ ] api %d\n", i))
+	}
+}
+
+func TestFileTaskBackend_RejectsPathOutsideFilesDir(t *testing.T) {
+	cfg := newFileBackendConfig(t)
+
+	_, err := services.NewFileTaskBackend(services.NewFilesService(cfg), services.BackendMarkdown, "../tasks.md")
+	assert.Error(t, err)
+
+	_, err = services.NewFileTaskBackend(services.NewFilesService(cfg), "org", "tasks.org")
+	assert.Error(t, err)
+}
+
+func TestFileTaskBackend_RefusesSymlinkCreatedAfterValidation(t *testing.T) {
+	cfg := newFileBackendConfig(t)
+	backend, err := services.NewFileTaskBackend(services.NewFilesService(cfg), services.BackendMarkdown, "inbox/tasks.md")
+	require.NoError(t, err)
+
+	// The directory is replaced by a link leading outside FilesDir after the path was checked
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(cfg.FilesDir, "inbox")))
+
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Escape"})
+
+	assert.Equal(t, "error", resp.Status)
+	_, err = os.Stat(filepath.Join(outside, "tasks.md"))
+	assert.True(t, os.IsNotExist(err), "task file must not be written outside FilesDir")
+}
+
+func TestCalDAVTaskBackend_CreateTaskWithSubtasks(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "*", r.Header.Get("If-None-Match"))
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "SUMMARY:Broken") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		uploads[r.URL.Path] = string(body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	backend := services.NewCalDAVTaskBackend(server.URL+"/calendars/alice/tasks", "alice", "secret", nil)
+	due := time.Date(2025, 10, 20, 9, 0, 0, 0, time.UTC)
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:    "Plan; trip, soon",
+		Note:     "line1\nline2",
+		Tags:     []string{"travel"},
+		DueDate:  &due,
+		Flagged:  true,
+		Children: []services.TaskCreateRequest{{Title: "Book"}, {Title: "Broken"}},
+	})
+
+	assert.Equal(t, "ok", resp.Status)
+	assert.True(t, resp.Created)
+	assert.Contains(t, resp.Reason, "subtask 'Broken'")
+	require.Len(t, uploads, 2)
+
+	var parent, child, parentUID string
+	for path, body := range uploads {
+		assert.True(t, strings.HasPrefix(path, "/calendars/alice/tasks/"))
+		assert.True(t, strings.HasSuffix(path, ".ics"))
+		if strings.Contains(body, "SUMMARY:Book") {
+			child = body
+		} else {
+			parent = body
+			parentUID = strings.TrimSuffix(filepath.Base(path), ".ics")
+		}
+	}
+	assert.Contains(t, parent, "SUMMARY:Plan\\; trip\\, soon\r\n")
+	assert.Contains(t, parent, "DESCRIPTION:line1\\nline2\r\n")
+	assert.Contains(t, parent, "DUE:20251020T090000Z\r\n")
+	assert.Contains(t, parent, "CATEGORIES:travel\r\n")
+	assert.Contains(t, parent, "PRIORITY:1\r\n")
+	assert.Contains(t, child, "RELATED-TO;RELTYPE=PARENT:"+parentUID+"\r\n")
+}
+
+func TestCalDAVTaskBackend_FailedParent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	backend := services.NewCalDAVTaskBackend(server.URL, "", "", nil)
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "401")
+}
+
+func TestCalDAVTaskBackend_PermanentFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	backend := services.NewCalDAVTaskBackend(server.URL, "", "", nil)
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	var statusErr *services.HTTPStatusError
+	require.ErrorAs(t, resp.Err, &statusErr)
+	assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+	assert.False(t, services.Retryable(resp.Err))
+}
+
+func TestCalDAVTaskBackend_RetryKeepsUIDs(t *testing.T) {
+	var mu sync.Mutex
+	stored := map[string]bool{}
+	var puts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		puts = append(puts, r.URL.Path)
+		if stored[r.URL.Path] {
+			// If-None-Match: * fails for an existing resource
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		stored[r.URL.Path] = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	backend := services.NewCalDAVTaskBackend(server.URL, "", "", nil)
+	req := services.TaskCreateRequest{
+		Title:      "Plan trip",
+		DeliveryID: "delivery-1",
+		Children:   []services.TaskCreateRequest{{Title: "Book"}, {Title: "Pack"}},
+	}
+
+	first := backend.CreateTask(context.Background(), req)
+	retry := backend.CreateTask(context.Background(), req)
+
+	assert.Equal(t, "ok", first.Status)
+	assert.Equal(t, "ok", retry.Status, "resources stored by an earlier attempt count as created")
+	assert.Empty(t, retry.Reason)
+	assert.Len(t, stored, 3, "a retry does not create the tasks again")
+	assert.Equal(t, []string{"/delivery-1.ics", "/delivery-1-1.ics", "/delivery-1-2.ics"}, puts[:3])
+	assert.Equal(t, puts[:3], puts[3:])
+}
+
+func TestWebhookTaskBackend_SignsPayload(t *testing.T) {
+	var received services.TaskCreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get(services.WebhookSignatureHeader))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	backend := services.NewWebhookTaskBackend(server.URL, "s3cret", nil)
+	resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:    "Review PR",
+		Tags:     []string{"code"},
+		Children: []services.TaskCreateRequest{{Title: "Run tests"}},
+	})
+
+	assert.True(t, resp.Created)
+	assert.Equal(t, "Review PR", received.Title)
+	require.Len(t, received.Children, 1)
+	assert.Equal(t, "Run tests", received.Children[0].Title)
+}
+
+func TestWebhookTaskBackend_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusServiceUnavailable, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusRequestTimeout, true},
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusUnprocessableEntity, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "maintenance", tt.status)
+			}))
+			defer server.Close()
+
+			backend := services.NewWebhookTaskBackend(server.URL, "", nil)
+			resp := backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+			assert.Equal(t, "error", resp.Status)
+			assert.Contains(t, resp.Reason, fmt.Sprintf("webhook returned %d: maintenance", tt.status))
+			var statusErr *services.HTTPStatusError
+			require.ErrorAs(t, resp.Err, &statusErr)
+			assert.Equal(t, tt.status, statusErr.StatusCode)
+			assert.Equal(t, tt.retryable, services.Retryable(resp.Err))
+		})
+	}
+}
+
+func TestWebhookTaskBackend_DeliveryID(t *testing.T) {
+	var headers []string
+	var bodies []services.TaskCreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received services.TaskCreateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		headers = append(headers, r.Header.Get(services.WebhookDeliveryHeader))
+		bodies = append(bodies, received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	backend := services.NewWebhookTaskBackend(server.URL, "", nil)
+	req := services.TaskCreateRequest{Title: "Task", DeliveryID: "delivery-1"}
+	backend.CreateTask(context.Background(), req)
+	backend.CreateTask(context.Background(), req)
+	backend.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Without ID"})
+
+	require.Len(t, headers, 3)
+	assert.Equal(t, []string{"delivery-1", "delivery-1"}, headers[:2], "a retry is sent with the same key")
+	assert.Equal(t, "delivery-1", bodies[0].DeliveryID)
+	assert.NotEmpty(t, headers[2])
+	assert.Equal(t, headers[2], bodies[2].DeliveryID)
+}
+
+func TestHTTPTaskBackends_RequestFailures(t *testing.T) {
+	// The server receives the request but answers only after the client gave up
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer slow.Close()
+	// Nothing listens at the address of a closed server
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	backends := map[string]func(url string) services.TaskBackend{
+		"webhook": func(url string) services.TaskBackend { return services.NewWebhookTaskBackend(url, "", client) },
+		"caldav":  func(url string) services.TaskBackend { return services.NewCalDAVTaskBackend(url, "", "", client) },
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name+" timeout after sending", func(t *testing.T) {
+			resp := newBackend(slow.URL).CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+			assert.Equal(t, "error", resp.Status)
+			assert.ErrorIs(t, resp.Err, services.ErrOutcomeUnknown)
+			assert.False(t, services.Retryable(resp.Err))
+		})
+		t.Run(name+" connection refused", func(t *testing.T) {
+			resp := newBackend(closed.URL).CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+			assert.Equal(t, "error", resp.Status)
+			require.Error(t, resp.Err)
+			assert.NotErrorIs(t, resp.Err, services.ErrOutcomeUnknown)
+			assert.True(t, services.Retryable(resp.Err))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// WebhookSignatureHeader carries the HMAC-SHA256 of the request body when a webhook secret is set
+	WebhookSignatureHeader = "X-Omnidrop-Signature"
+	// WebhookDeliveryHeader carries the task's delivery ID, the same on every retry of the task
+	WebhookDeliveryHeader = "Idempotency-Key"
+)
+
+// WebhookTaskBackend posts each task as JSON to a URL
+type WebhookTaskBackend struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// Ensure WebhookTaskBackend implements TaskBackend
+var _ TaskBackend = (*WebhookTaskBackend)(nil)
+
+// NewWebhookTaskBackend creates a backend posting to url. When secret is set every request is
+// signed with an "X-Omnidrop-Signature: sha256=<hex>" header; a nil client selects a default one.
+func NewWebhookTaskBackend(url, secret string, client *http.Client) *WebhookTaskBackend {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultBackendHTTPTimeout}
+	}
+	return &WebhookTaskBackend{
+		url:    url,
+		secret: secret,
+		client: client,
+	}
+}
+
+// CreateTask posts the task, including its subtasks, in the outbox JSON format.
+// Any 2xx response counts as created. The delivery ID is sent in the body and the
+// Idempotency-Key header so the receiver can drop a retry of a task it already has.
+func (b *WebhookTaskBackend) CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse {
+	if req.DeliveryID == "" {
+		req.DeliveryID = uuid.NewString()
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return TaskCreateResponse{Status: "error", Reason: fmt.Sprintf("failed to encode task: %v", err)}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return TaskCreateResponse{Status: "error", Reason: fmt.Sprintf("failed to build webhook request: %v", err)}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(WebhookDeliveryHeader, req.DeliveryID)
+	if b.secret != "" {
+		mac := hmac.New(sha256.New, []byte(b.secret))
+		mac.Write(body)
+		httpReq.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		err = requestError("webhook", err)
+		return TaskCreateResponse{Status: "error", Reason: err.Error(), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := statusError("webhook", resp)
+		return TaskCreateResponse{Status: "error", Reason: err.Error(), Err: err}
+	}
+	return TaskCreateResponse{Status: "ok", Created: true}
+}
+
+// CreateTasks posts each task independently
+func (b *WebhookTaskBackend) CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse {
+	responses := make([]TaskCreateResponse, len(reqs))
+	for i, req := range reqs {
+		responses[i] = b.CreateTask(ctx, req)
+	}
+	return responses
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"omnidrop/internal/observability"
+)
+
+// ErrCircuitOpen is matched by the error CircuitBreakerExecutor returns while it refuses to run scripts
+var ErrCircuitOpen = errors.New("OmniFocus circuit breaker is open")
+
+// CircuitOpenError is returned instead of running a script while the circuit is open
+type CircuitOpenError struct {
+	RetryAfter time.Duration // Time until the breaker probes OmniFocus again
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v; retry in %s", ErrCircuitOpen, e.RetryAfter.Round(time.Second))
+}
+
+// Is lets errors.Is(err, ErrCircuitOpen) match
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a CircuitBreakerExecutor
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Scripts run normally
+	CircuitOpen     CircuitState = "open"      // Scripts are rejected until the cooldown has passed
+	CircuitHalfOpen CircuitState = "half_open" // One trial script runs; its outcome closes or reopens the circuit
+)
+
+// circuitStateValues are the values of the OmniFocusCircuitState gauge
+var circuitStateValues = map[CircuitState]float64{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// CircuitBreakerOptions configures a CircuitBreakerExecutor
+type CircuitBreakerOptions struct {
+	FailureThreshold int           // Consecutive failures, timeouts or unreachable-OmniFocus results that open the circuit
+	Cooldown         time.Duration // Time the circuit stays open before OmniFocus is probed
+	Probe            func() bool   // Reports whether OmniFocus is running, e.g. HealthService.CheckOmniFocusStatus
+}
+
+// CircuitBreakerExecutor stops running scripts after repeated osascript failures, so requests fail
+// fast while OmniFocus is hung instead of each waiting for its full timeout. Once the cooldown has
+// passed, the next caller runs the probe; if OmniFocus is running, that caller's script is the trial.
+type CircuitBreakerExecutor struct {
+	next AppleScriptExecutor
+	opts CircuitBreakerOptions
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool // A caller is running the probe or the trial script
+}
+
+// NewCircuitBreakerExecutor wraps next in a circuit breaker
+func NewCircuitBreakerExecutor(next AppleScriptExecutor, opts CircuitBreakerOptions) *CircuitBreakerExecutor {
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+	if opts.Probe == nil {
+		opts.Probe = func() bool { return true }
+	}
+	observability.OmniFocusCircuitState.Set(circuitStateValues[CircuitClosed])
+	return &CircuitBreakerExecutor{
+		next:  next,
+		opts:  opts,
+		state: CircuitClosed,
+	}
+}
+
+// Execute runs a script file unless the circuit is open
+func (b *CircuitBreakerExecutor) Execute(ctx context.Context, script string, args ...string) ([]byte, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	output, err := b.next.Execute(ctx, script, args...)
+	b.record(outcome(output, err))
+	return output, err
+}
+
+// ExecuteSimple runs an inline script unless the circuit is open
+func (b *CircuitBreakerExecutor) ExecuteSimple(ctx context.Context, script string) ([]byte, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	output, err := b.next.ExecuteSimple(ctx, script)
+	b.record(outcome(output, err))
+	return output, err
+}
+
+// outcome is the error a script run counts as: its execution error, or the failure it reported
+// when osascript exited normally but could not reach OmniFocus, i.e. an AppleEvent timeout
+// (-1712) or OmniFocus not running (-600, -609)
+func outcome(output []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		result, ok := parseScriptResult(line)
+		if !ok {
+			continue
+		}
+		if failure := result.failure(); failure != nil &&
+			(failure.Code == ScriptErrorTimeout || failure.Code == ScriptErrorOmniFocusUnavailable) {
+			return failure
+		}
+	}
+	return nil
+}
+
+// State returns the current circuit state
+func (b *CircuitBreakerExecutor) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow decides whether a script may run, probing OmniFocus when the cooldown has passed
+func (b *CircuitBreakerExecutor) allow() error {
+	b.mu.Lock()
+	if b.state == CircuitClosed {
+		b.mu.Unlock()
+		return nil
+	}
+
+	remaining := b.opts.Cooldown - time.Since(b.openedAt)
+	if b.probing || remaining > 0 {
+		b.mu.Unlock()
+		return b.reject(remaining)
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	// The probe spawns osascript, so it runs without holding the lock
+	running := b.opts.Probe()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !running {
+		b.probing = false
+		b.openedAt = time.Now()
+		slog.Warn("⚠️ OmniFocus probe failed; circuit stays open", slog.Duration("cooldown", b.opts.Cooldown))
+		return b.reject(b.opts.Cooldown)
+	}
+	b.setState(CircuitHalfOpen)
+	return nil
+}
+
+// record updates the breaker with the outcome of a script that was allowed to run
+func (b *CircuitBreakerExecutor) record(err error) {
+	// A busy pool or a caller that went away says nothing about OmniFocus
+	if errors.Is(err, ErrExecutorBusy) || errors.Is(err, errNotStarted) || errors.Is(err, context.Canceled) {
+		b.mu.Lock()
+		if b.state == CircuitHalfOpen {
+			b.probing = false
+			b.openedAt = time.Time{} // Probe again on the next call
+			b.setState(CircuitOpen)
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.probing = false
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.opts.FailureThreshold {
+		b.probing = false
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// setState changes the state, logging and exporting transitions; the caller holds mu
+func (b *CircuitBreakerExecutor) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("🔌 OmniFocus circuit breaker state changed",
+		slog.String("from", string(b.state)),
+		slog.String("to", string(state)),
+		slog.Int("consecutive_failures", b.failures))
+	b.state = state
+	observability.OmniFocusCircuitState.Set(circuitStateValues[state])
+}
+
+func (b *CircuitBreakerExecutor) reject(retryAfter time.Duration) error {
+	observability.OmniFocusCircuitRejectionsTotal.Inc()
+	if retryAfter <= 0 {
+		// Another caller is probing; it finishes within the probe timeout
+		retryAfter = time.Second
+	}
+	return &CircuitOpenError{RetryAfter: retryAfter}
+}
@@ -0,0 +1,208 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+var errOsascript = errors.New("exit status 1")
+
+// openCircuit fails threshold scripts so the breaker opens
+func openCircuit(t *testing.T, breaker *services.CircuitBreakerExecutor, threshold int) {
+	t.Helper()
+	for i := 0; i < threshold; i++ {
+		_, err := breaker.Execute(context.Background(), "omnidrop.applescript")
+		require.ErrorIs(t, err, errOsascript)
+	}
+	require.Equal(t, services.CircuitOpen, breaker.State())
+}
+
+func TestCircuitBreakerExecutor_OpensAfterConsecutiveFailures(t *testing.T) {
+	executor := mocks.NewMockExecutor(
+		mocks.Failure(errOsascript),
+		mocks.Success("ok"), // resets the count
+		mocks.Failure(errOsascript),
+		mocks.Failure(errOsascript),
+	)
+	breaker := services.NewCircuitBreakerExecutor(executor, services.CircuitBreakerOptions{
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		breaker.Execute(context.Background(), "omnidrop.applescript")
+		assert.Equal(t, services.CircuitClosed, breaker.State(), "call %d", i)
+	}
+	_, err := breaker.Execute(context.Background(), "omnidrop.applescript")
+	require.ErrorIs(t, err, errOsascript)
+	assert.Equal(t, services.CircuitOpen, breaker.State())
+
+	// Open: rejected without running the script
+	_, err = breaker.ExecuteSimple(context.Background(), "return 1")
+	assert.ErrorIs(t, err, services.ErrCircuitOpen)
+	var circuitErr *services.CircuitOpenError
+	require.ErrorAs(t, err, &circuitErr)
+	assert.Greater(t, circuitErr.RetryAfter, 50*time.Second)
+	assert.Equal(t, 4, executor.CallCount())
+}
+
+func TestCircuitBreakerExecutor_TimeoutsCountAsFailures(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	breaker := services.NewCircuitBreakerExecutor(executor, services.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := breaker.Execute(ctx, "omnidrop.applescript")
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, services.CircuitOpen, breaker.State())
+}
+
+func TestCircuitBreakerExecutor_UnreachableResultsCountAsFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected services.CircuitState
+	}{
+		{
+			name:     "AppleEvent timed out",
+			output:   `{"version":1,"status":"error","error_code":"timeout","message":"AppleEvent timed out. (-1712)"}`,
+			expected: services.CircuitOpen,
+		},
+		{
+			name: "OmniFocus not running in batch",
+			output: `{"version":1,"index":1,"status":"ok","task_id":"abc"}` + "\n" +
+				`{"version":1,"index":2,"status":"error","error_code":"omnifocus_unavailable","message":"Application isn't running. (-600)"}`,
+			expected: services.CircuitOpen,
+		},
+		{
+			name:     "request rejected",
+			output:   `{"version":1,"status":"error","error_code":"invalid_argument","message":"Title is required"}`,
+			expected: services.CircuitClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := services.NewCircuitBreakerExecutor(mocks.NewMockExecutor(mocks.Success(tt.output)), services.CircuitBreakerOptions{
+				FailureThreshold: 1,
+				Cooldown:         time.Minute,
+			})
+
+			output, err := breaker.Execute(context.Background(), "omnidrop.applescript")
+
+			// The caller still gets the script's output to report per-task results
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, string(output))
+			assert.Equal(t, tt.expected, breaker.State())
+		})
+	}
+}
+
+func TestCircuitBreakerExecutor_IgnoresBusyAndCanceledCalls(t *testing.T) {
+	executor := mocks.NewMockExecutor(
+		mocks.Failure(services.ErrExecutorBusy),
+		mocks.Failure(context.Canceled),
+	)
+	breaker := services.NewCircuitBreakerExecutor(executor, services.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	})
+
+	breaker.Execute(context.Background(), "omnidrop.applescript")
+	breaker.Execute(context.Background(), "omnidrop.applescript")
+
+	assert.Equal(t, services.CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakerExecutor_HalfOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		probe       bool
+		trial       mocks.ExecutorResponse
+		wantErr     error
+		wantState   services.CircuitState
+		wantScripts int // scripts run after the circuit opened
+	}{
+		{
+			name:        "probe fails and circuit stays open",
+			probe:       false,
+			wantErr:     services.ErrCircuitOpen,
+			wantState:   services.CircuitOpen,
+			wantScripts: 0,
+		},
+		{
+			name:        "successful trial closes circuit",
+			probe:       true,
+			trial:       mocks.Success("ok"),
+			wantState:   services.CircuitClosed,
+			wantScripts: 1,
+		},
+		{
+			name:        "failed trial reopens circuit",
+			probe:       true,
+			trial:       mocks.Failure(errOsascript),
+			wantErr:     errOsascript,
+			wantState:   services.CircuitOpen,
+			wantScripts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := mocks.NewMockExecutor(mocks.Failure(errOsascript), tt.trial)
+			probes := 0
+			breaker := services.NewCircuitBreakerExecutor(executor, services.CircuitBreakerOptions{
+				FailureThreshold: 1,
+				Cooldown:         10 * time.Millisecond,
+				Probe: func() bool {
+					probes++
+					return tt.probe
+				},
+			})
+			openCircuit(t, breaker, 1)
+			time.Sleep(20 * time.Millisecond)
+
+			_, err := breaker.Execute(context.Background(), "omnidrop.applescript")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 1, probes)
+			assert.Equal(t, tt.wantState, breaker.State())
+			assert.Equal(t, 1+tt.wantScripts, executor.CallCount())
+		})
+	}
+}
+
+func TestOmniFocusService_CircuitState(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	assert.Equal(t, services.CircuitState(""), services.NewOmniFocusServiceWithExecutor(cfg, mocks.NewMockExecutor()).CircuitState())
+
+	breaker := services.NewCircuitBreakerExecutor(mocks.NewMockExecutor(mocks.Failure(errOsascript)), services.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	})
+	service := services.NewOmniFocusServiceWithExecutor(cfg, breaker)
+
+	first := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+	assert.Equal(t, "error", first.Status)
+	assert.Equal(t, services.CircuitOpen, service.CircuitState())
+
+	second := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+	assert.Equal(t, "error", second.Status)
+	assert.ErrorIs(t, second.Err, services.ErrCircuitOpen)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Ensure DefaultAppleScriptExecutor implements AppleScriptExecutor
+var _ AppleScriptExecutor = (*DefaultAppleScriptExecutor)(nil)
+
+// DefaultAppleScriptExecutor provides the default implementation for AppleScript execution
+type DefaultAppleScriptExecutor struct{}
+
+// Execute runs a file-based script with the given arguments via osascript.
+// JavaScript for Automation scripts (.js) are run with "-l JavaScript".
+func (e *DefaultAppleScriptExecutor) Execute(ctx context.Context, script string, args ...string) ([]byte, error) {
+	cmdArgs := append(osascriptLanguageArgs(script), script)
+	cmd := exec.CommandContext(ctx, "osascript", append(cmdArgs, args...)...)
+	return cmd.CombinedOutput()
+}
+
+// ExecuteSimple executes a simple AppleScript command
+func (e *DefaultAppleScriptExecutor) ExecuteSimple(ctx context.Context, script string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "osascript", "-e", script)
+	return cmd.CombinedOutput()
+}
+
+// osascriptLanguageArgs selects the osascript language for a script file by its extension
+func osascriptLanguageArgs(script string) []string {
+	if strings.EqualFold(filepath.Ext(script), ".js") {
+		return []string{"-l", "JavaScript"}
+	}
+	return nil
+}
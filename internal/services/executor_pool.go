@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"omnidrop/internal/observability"
+)
+
+// ErrExecutorBusy is returned by PooledAppleScriptExecutor when no execution slot became free,
+// either because the wait queue was full or because the queue timeout elapsed
+var ErrExecutorBusy = errors.New("AppleScript executor is busy")
+
+// errNotStarted wraps the context error of a caller that gave up waiting for a slot, telling it
+// apart from a script that was killed while it ran
+var errNotStarted = errors.New("script was not started")
+
+// ExecutorPoolOptions configures a PooledAppleScriptExecutor
+type ExecutorPoolOptions struct {
+	MaxConcurrent int           // Scripts allowed to run at the same time
+	MaxQueued     int           // Callers allowed to wait for a free slot; further callers are rejected immediately
+	QueueTimeout  time.Duration // Longest a caller waits for a free slot
+}
+
+// PooledAppleScriptExecutor limits how many scripts the wrapped executor runs at once.
+// OmniFocus handles Apple Events one at a time, so unbounded parallel osascript processes
+// only pile up behind each other until they hit their timeouts.
+type PooledAppleScriptExecutor struct {
+	next   AppleScriptExecutor
+	slots  chan struct{}
+	queued atomic.Int64
+	opts   ExecutorPoolOptions
+}
+
+// NewPooledAppleScriptExecutor wraps next in a bounded pool
+func NewPooledAppleScriptExecutor(next AppleScriptExecutor, opts ExecutorPoolOptions) *PooledAppleScriptExecutor {
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 1
+	}
+	if opts.MaxQueued < 0 {
+		opts.MaxQueued = 0
+	}
+	return &PooledAppleScriptExecutor{
+		next:  next,
+		slots: make(chan struct{}, opts.MaxConcurrent),
+		opts:  opts,
+	}
+}
+
+// Execute runs a script file once a slot is free
+func (p *PooledAppleScriptExecutor) Execute(ctx context.Context, script string, args ...string) ([]byte, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.next.Execute(ctx, script, args...)
+}
+
+// ExecuteSimple runs an inline script once a slot is free
+func (p *PooledAppleScriptExecutor) ExecuteSimple(ctx context.Context, script string) ([]byte, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.next.ExecuteSimple(ctx, script)
+}
+
+// acquire takes an execution slot, waiting in the queue when all slots are in use
+func (p *PooledAppleScriptExecutor) acquire(ctx context.Context) (func(), error) {
+	// Fast path: a slot is free
+	select {
+	case p.slots <- struct{}{}:
+		observability.AppleScriptQueueWaitDuration.Observe(0)
+		return p.release(), nil
+	default:
+	}
+
+	if p.queued.Add(1) > int64(p.opts.MaxQueued) {
+		p.queued.Add(-1)
+		observability.AppleScriptRejectionsTotal.WithLabelValues("queue_full").Inc()
+		return nil, ErrExecutorBusy
+	}
+	observability.AppleScriptQueued.Inc()
+	defer func() {
+		p.queued.Add(-1)
+		observability.AppleScriptQueued.Dec()
+	}()
+
+	var timeout <-chan time.Time
+	if p.opts.QueueTimeout > 0 {
+		timer := time.NewTimer(p.opts.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+		observability.AppleScriptQueueWaitDuration.Observe(time.Since(start).Seconds())
+		return p.release(), nil
+	case <-timeout:
+		observability.AppleScriptRejectionsTotal.WithLabelValues("timeout").Inc()
+		return nil, ErrExecutorBusy
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", errNotStarted, ctx.Err())
+	}
+}
+
+func (p *PooledAppleScriptExecutor) release() func() {
+	observability.AppleScriptInFlight.Inc()
+	return func() {
+		observability.AppleScriptInFlight.Dec()
+		<-p.slots
+	}
+}
@@ -0,0 +1,121 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+// occupySlot starts a script that holds one pool slot until the returned function is called
+func occupySlot(t *testing.T, pool *services.PooledAppleScriptExecutor, executor *mocks.MockAppleScriptExecutor) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Execute(ctx, "busy.applescript")
+	}()
+	require.Eventually(t, func() bool { return executor.CallCount() == 1 }, time.Second, time.Millisecond)
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestPooledAppleScriptExecutor_RejectsWhenQueueFull(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	pool := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{
+		MaxConcurrent: 1,
+		MaxQueued:     0,
+		QueueTimeout:  time.Second,
+	})
+	release := occupySlot(t, pool, executor)
+	defer release()
+
+	start := time.Now()
+	_, err := pool.Execute(context.Background(), "second.applescript")
+
+	assert.ErrorIs(t, err, services.ErrExecutorBusy)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "a full queue should reject without waiting")
+	assert.Equal(t, 1, executor.CallCount())
+}
+
+func TestPooledAppleScriptExecutor_RejectsAfterQueueTimeout(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	pool := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+		QueueTimeout:  20 * time.Millisecond,
+	})
+	release := occupySlot(t, pool, executor)
+	defer release()
+
+	start := time.Now()
+	_, err := pool.ExecuteSimple(context.Background(), "return 1")
+
+	assert.ErrorIs(t, err, services.ErrExecutorBusy)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, 1, executor.CallCount())
+}
+
+func TestPooledAppleScriptExecutor_RunsQueuedCallWhenSlotFrees(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	pool := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+		QueueTimeout:  time.Second,
+	})
+	release := occupySlot(t, pool, executor)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := pool.Execute(context.Background(), "second.applescript")
+		result <- err
+	}()
+
+	// The second call must wait while the first holds the only slot
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, executor.CallCount())
+
+	release()
+	require.NoError(t, <-result)
+	assert.Equal(t, 2, executor.CallCount())
+	assert.Equal(t, "second.applescript", executor.LastCall().Script)
+}
+
+func TestPooledAppleScriptExecutor_WaitEndsWithContext(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	pool := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+		QueueTimeout:  time.Second,
+	})
+	release := occupySlot(t, pool, executor)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.Execute(ctx, "second.applescript")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, services.ErrExecutorBusy)
+}
+
+func TestOmniFocusService_CreateTask_ExecutorBusy(t *testing.T) {
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	pool := services.NewPooledAppleScriptExecutor(executor, services.ExecutorPoolOptions{MaxConcurrent: 1})
+	release := occupySlot(t, pool, executor)
+	defer release()
+
+	service := services.NewOmniFocusServiceWithExecutor(newTestOmniFocusConfig(t), pool)
+	response := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+
+	assert.Equal(t, "error", response.Status)
+	assert.ErrorIs(t, response.Err, services.ErrExecutorBusy)
+}
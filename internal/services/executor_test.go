@@ -0,0 +1,24 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOsascriptLanguageArgs(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"/usr/local/share/omnidrop/omnidrop.applescript", nil},
+		{"omnidrop-query.applescript", nil},
+		{"/usr/local/share/omnidrop/omnidrop.js", []string{"-l", "JavaScript"}},
+		{"OMNIDROP.JS", []string{"-l", "JavaScript"}},
+	}
+
+	for _, tt := range tests {
+		if got := osascriptLanguageArgs(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("osascriptLanguageArgs(%q) = %v, want %v", tt.script, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -14,7 +20,17 @@ import (
 
 // FilesService handles file operations with security validation
 type FilesService struct {
-	cfg *config.Config
+	cfg   *config.Config
+	fs    fileSystem
+	dir   string    // directory fs is rooted at: cfg.FilesDir, or a client's namespace below it
+	scope FileScope // scope of a client's view; see scoped
+
+	// locks serializes moving staged files into place per path, so an If-Match or quota check
+	// and the write it guards cannot interleave with another request's commit. Client views
+	// share it; see lockCommit.
+	locks *pathLocks
+
+	checksums *checksumCache // shared by client views like locks
 }
 
 // Ensure FilesService implements FilesServiceInterface
@@ -23,12 +39,26 @@ var _ FilesServiceInterface = (*FilesService)(nil)
 // NewFilesService creates a new FilesService instance
 func NewFilesService(cfg *config.Config) *FilesService {
 	return &FilesService{
-		cfg: cfg,
+		cfg:       cfg,
+		fs:        rootFileSystem{base: cfg.FilesDir},
+		dir:       cfg.FilesDir,
+		locks:     &pathLocks{locks: make(map[string]*pathLock)},
+		checksums: newChecksumCache(),
+	}
+}
+
+// WriteFile writes content to a file with security validation. The content is staged in a
+// temporary file next to the target and moved into place according to req.Mode, so readers
+// never see a partially written file.
+func (s *FilesService) WriteFile(ctx context.Context, req FileWriteRequest) FileWriteResponse {
+	files, err := s.scoped(ctx)
+	if err != nil {
+		return FileWriteResponse{Status: "error", Reason: err.Error(), ErrorKind: "internal"}
 	}
+	return files.writeFile(req)
 }
 
-// WriteFile writes content to a file with security validation
-func (s *FilesService) WriteFile(ctx context.Context, req FileWriteRequest) (resp FileWriteResponse) {
+func (s *FilesService) writeFile(req FileWriteRequest) (resp FileWriteResponse) {
 	start := time.Now()
 	defer func() {
 		label := "success"
@@ -48,52 +78,148 @@ func (s *FilesService) WriteFile(ctx context.Context, req FileWriteRequest) (res
 		}
 	}
 
-	// Build and validate file path
-	safePath, relativePath, err := s.validateAndBuildPath(req.Filename, req.Directory)
-	if err != nil {
+	mode := strings.ToLower(req.Mode)
+	if mode == "" {
+		mode = FileModeCreate
+	}
+	if mode != FileModeCreate && mode != FileModeOverwrite && mode != FileModeAppend && mode != FileModeVersion {
 		return FileWriteResponse{
 			Status:    "error",
-			Reason:    err.Error(),
+			Reason:    fmt.Sprintf("unsupported mode '%s'; use create, overwrite, append or version", req.Mode),
 			ErrorKind: "validation",
 		}
 	}
 
-	// Check if file already exists
-	if _, err := os.Stat(safePath); err == nil {
+	ifMatch := strings.ToLower(req.IfMatch)
+	if ifMatch != "" {
+		if mode != FileModeOverwrite && mode != FileModeAppend {
+			return FileWriteResponse{
+				Status:    "error",
+				Reason:    "If-Match only applies to the overwrite and append modes",
+				ErrorKind: "validation",
+			}
+		}
+		if ifMatch != "*" && !sha256Pattern.MatchString(ifMatch) {
+			return FileWriteResponse{
+				Status:    "error",
+				Reason:    "If-Match must be * or a hex SHA-256 of the current file",
+				ErrorKind: "validation",
+			}
+		}
+	}
+
+	expectedSHA256 := strings.ToLower(req.ContentSHA256)
+	if expectedSHA256 != "" && !sha256Pattern.MatchString(expectedSHA256) {
 		return FileWriteResponse{
 			Status:    "error",
-			Reason:    "file already exists",
-			ErrorKind: "conflict",
+			Reason:    "content_sha256 must be 64 hexadecimal characters",
+			ErrorKind: "validation",
 		}
 	}
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(safePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	body := req.Body
+	if body == nil {
+		decoded, err := decodeContent(req.Content, req.Encoding)
+		if err != nil {
+			return FileWriteResponse{
+				Status:    "error",
+				Reason:    err.Error(),
+				ErrorKind: "validation",
+			}
+		}
+		body = bytes.NewReader(decoded)
+	}
+	if s.scope.MaxBytes > 0 {
+		// No single file can be larger than the whole quota; stop reading once it is
+		body = &quotaReader{r: body, limit: s.scope.MaxBytes}
+	}
+
+	// Build and validate file path
+	_, relativePath, err := s.validateAndBuildPath(req.Filename, req.Directory)
+	if err == nil {
+		err = s.checkReserved(relativePath)
+	}
+	if err != nil {
 		return FileWriteResponse{
 			Status:    "error",
-			Reason:    fmt.Sprintf("failed to create directory: %v", err),
-			ErrorKind: "internal",
+			Reason:    err.Error(),
+			ErrorKind: "validation",
+		}
+	}
+
+	// Fail fast before reading the body; commitFile repeats the check atomically
+	if mode == FileModeCreate {
+		if _, err := s.fs.Stat(relativePath); err == nil {
+			return FileWriteResponse{
+				Status:    "error",
+				Reason:    "file already exists",
+				ErrorKind: "conflict",
+			}
 		}
 	}
 
-	// Write file with appropriate permissions
-	contentBytes := []byte(req.Content)
-	if err := os.WriteFile(safePath, contentBytes, 0644); err != nil {
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(relativePath)
+	if err := s.fs.MkdirAll(dir, 0755); err != nil {
 		return FileWriteResponse{
 			Status:    "error",
-			Reason:    fmt.Sprintf("failed to write file: %v", err),
+			Reason:    fmt.Sprintf("failed to create directory: %v", err),
 			ErrorKind: "internal",
 		}
 	}
 
-	// Record file size metric (success only)
-	observability.FilesSizeBytes.Observe(float64(len(contentBytes)))
+	staged, err := s.stageFile(dir, body)
+	if err != nil {
+		return stagingFailure(err)
+	}
+	defer s.fs.Remove(staged.path)
+
+	// Verify the checksum before anything is committed
+	if expectedSHA256 != "" && staged.checksum != expectedSHA256 {
+		return checksumMismatch(expectedSHA256, staged.checksum)
+	}
+
+	resp = s.commitFile(mode, relativePath, ifMatch, staged)
+	if resp.Status == "ok" {
+		// Record file size metric (success only)
+		observability.FilesSizeBytes.Observe(float64(staged.size))
+		s.reportUsage()
+	}
+	return resp
+}
+
+// sha256Pattern matches a lowercase hex SHA-256 digest
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
 
+// decodeContent converts JSON string content to the bytes it encodes
+func decodeContent(content, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", EncodingUTF8:
+		return []byte(content), nil
+	case EncodingBase64:
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("content is not valid base64: %v", err)
+		}
+		return decoded, nil
+	case EncodingHex:
+		decoded, err := hex.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("content is not valid hex: %v", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding '%s'; use utf8, base64 or hex", encoding)
+	}
+}
+
+// checksumMismatch reports content that does not match the SHA-256 the client sent,
+// usually because it was corrupted in transit; the client should resend it
+func checksumMismatch(expected, actual string) FileWriteResponse {
 	return FileWriteResponse{
-		Status:  "ok",
-		Created: true,
-		Path:    relativePath,
+		Status:    "error",
+		Reason:    fmt.Sprintf("content SHA-256 %s does not match content_sha256 %s", actual, expected),
+		ErrorKind: "checksum_mismatch",
 	}
 }
 
@@ -103,6 +229,9 @@ func (s *FilesService) validateAndBuildPath(filename, directory string) (string,
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") {
 		return "", "", fmt.Errorf("invalid path: filename cannot contain path separators or '..'")
 	}
+	if isStagingFile(filename) {
+		return "", "", fmt.Errorf("invalid path: filenames matching .omnidrop-*.tmp are reserved")
+	}
 
 	// Build the target path
 	var targetPath string
@@ -117,15 +246,15 @@ func (s *FilesService) validateAndBuildPath(filename, directory string) (string,
 			return "", "", fmt.Errorf("invalid path: directory contains invalid characters")
 		}
 
-		targetPath = filepath.Join(s.cfg.FilesDir, cleanDir, filename)
+		targetPath = filepath.Join(s.dir, cleanDir, filename)
 		relativePath = filepath.Join(cleanDir, filename)
 	} else {
-		targetPath = filepath.Join(s.cfg.FilesDir, filename)
+		targetPath = filepath.Join(s.dir, filename)
 		relativePath = filename
 	}
 
 	// Resolve to absolute path and check it's within base directory
-	absBasePath, err := filepath.Abs(s.cfg.FilesDir)
+	absBasePath, err := filepath.Abs(s.dir)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to resolve base directory: %v", err)
 	}
@@ -136,9 +265,69 @@ func (s *FilesService) validateAndBuildPath(filename, directory string) (string,
 	}
 
 	// Ensure the target path is within the base directory
-	if !strings.HasPrefix(absTargetPath, absBasePath+string(filepath.Separator)) && absTargetPath != absBasePath {
+	if !isWithin(absBasePath, absTargetPath) {
 		return "", "", fmt.Errorf("invalid path: outside base directory")
 	}
 
+	// The lexical checks above do not see symbolic links; reject those leading outside up front
+	// for a clear error. rootFileSystem enforces the same at the time of each operation.
+	if err := checkSymlinks(absBasePath, relativePath); err != nil {
+		return "", "", err
+	}
+
 	return absTargetPath, relativePath, nil
-}
\ No newline at end of file
+}
+
+// checkSymlinks resolves every existing component of relativePath below base and rejects
+// symbolic links that are dangling, resolve outside base or have an absolute target
+func checkSymlinks(base, relativePath string) error {
+	realBase, err := filepath.EvalSymlinks(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Nothing below a missing base directory can be a link
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve base directory: %v", err)
+	}
+
+	current := realBase
+	for _, component := range strings.Split(relativePath, string(filepath.Separator)) {
+		if component == "" || component == "." {
+			continue
+		}
+		next := filepath.Join(current, component)
+		info, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The rest of the path will be created
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve target path: %v", err)
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(next)
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("invalid path: symbolic link '%s' is dangling", component)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid path: symbolic link '%s' cannot be resolved", component)
+			}
+			if !isWithin(realBase, resolved) {
+				return fmt.Errorf("invalid path: symbolic link '%s' leads outside base directory", component)
+			}
+			// os.Root, which rootFileSystem is built on, treats every absolute link as an escape
+			if target, err := os.Readlink(next); err != nil || filepath.IsAbs(target) {
+				return fmt.Errorf("invalid path: symbolic link '%s' must be relative", component)
+			}
+			next = resolved
+		}
+		current = next
+	}
+	return nil
+}
+
+// isWithin reports whether the absolute path target is base or below it
+func isWithin(base, target string) bool {
+	return target == base || strings.HasPrefix(target, base+string(filepath.Separator))
+}
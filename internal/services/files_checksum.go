@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checksumCache remembers the SHA-256 of files by path, so listings, downloads and If-Match
+// checks do not hash a file again until its size or modification time changes. Files
+// committed by the service are cached as they are written. Client views share it.
+type checksumCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedChecksum
+}
+
+type cachedChecksum struct {
+	size     int64
+	modTime  time.Time
+	checksum string
+}
+
+func newChecksumCache() *checksumCache {
+	return &checksumCache{entries: make(map[string]cachedChecksum)}
+}
+
+// get returns the checksum cached for path if the file still matches info
+func (c *checksumCache) get(path string, info os.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[path]
+	if !ok || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	return entry.checksum, true
+}
+
+// put caches the checksum of the file at path as described by info
+func (c *checksumCache) put(path string, info os.FileInfo, checksum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = cachedChecksum{size: info.Size(), modTime: info.ModTime(), checksum: checksum}
+}
+
+// remove forgets the checksum of a deleted file
+func (c *checksumCache) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, path)
+}
+
+// cachedChecksum returns the cached checksum of the file at relativePath if info still matches it
+func (s *FilesService) cachedChecksum(relativePath string, info os.FileInfo) (string, bool) {
+	return s.checksums.get(filepath.Join(s.dir, relativePath), info)
+}
+
+// cacheChecksum records the checksum of the file at relativePath as described by info
+func (s *FilesService) cacheChecksum(relativePath string, info os.FileInfo, checksum string) {
+	s.checksums.put(filepath.Join(s.dir, relativePath), info, checksum)
+}
+
+// fileChecksum returns the hex SHA-256 of the file at path and whether it exists
+func (s *FilesService) fileChecksum(path string) (string, bool, error) {
+	file, err := s.fs.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", true, err
+	}
+	checksum, err := s.readChecksum(path, file, info)
+	return checksum, true, err
+}
+
+// readChecksum returns the checksum of an open file described by info, hashing and rewinding
+// it unless the checksum is cached
+func (s *FilesService) readChecksum(path string, file readableFile, info os.FileInfo) (string, error) {
+	if checksum, ok := s.cachedChecksum(path, info); ok {
+		return checksum, nil
+	}
+
+	checksum, err := hashReader(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	// A file changed while it was hashed is not cached; its next reader hashes it again
+	if after, err := file.Stat(); err == nil && after.Size() == info.Size() && after.ModTime().Equal(info.ModTime()) {
+		s.cacheChecksum(path, info, checksum)
+	}
+	return checksum, nil
+}
+
+// hashReader returns the hex SHA-256 of everything r yields
+func hashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
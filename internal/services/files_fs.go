@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fileSystem is the set of file operations FilesService writes through. Tests replace it to
+// simulate failures such as a full disk.
+type fileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	CreateTemp(dir, pattern string) (stagingFile, error)
+	Open(name string) (readableFile, error)
+	Link(oldname, newname string) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	// OpenAppend opens a file for appending, creating it with perm if it does not exist
+	OpenAppend(name string, perm os.FileMode) (appendFile, error)
+	// SyncDir flushes a directory so a link or rename in it survives a crash
+	SyncDir(dir string) error
+	// Sub returns the fileSystem rooted at dir below this one
+	Sub(dir string) fileSystem
+}
+
+// stagingFile is a temporary file content is written to before it is committed
+type stagingFile interface {
+	io.Writer
+	Name() string
+	Chmod(mode os.FileMode) error
+	Sync() error
+	Close() error
+}
+
+// appendFile is a file opened for appending
+type appendFile interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+// readableFile is a file opened for reading
+type readableFile interface {
+	io.ReadSeekCloser
+	Stat() (os.FileInfo, error)
+}
+
+// rootFileSystem is the fileSystem backed by os.Root. Names are relative to dir below base, and
+// every operation resolves them with openat-style traversal that refuses symbolic links and ".."
+// leading outside the root, even when the tree is changed between validating a path and using it.
+type rootFileSystem struct {
+	base string
+	dir  string // subdirectory of base set by Sub; "" for base itself
+}
+
+// open opens the root of the file system. A subdirectory is opened through base's root,
+// so a symbolic link in place of it cannot lead outside base.
+func (f rootFileSystem) open() (*os.Root, error) {
+	root, err := os.OpenRoot(f.base)
+	if err != nil || f.dir == "" {
+		return root, err
+	}
+	defer root.Close()
+	return root.OpenRoot(f.dir)
+}
+
+func (f rootFileSystem) Stat(name string) (os.FileInfo, error) {
+	root, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.Stat(name)
+}
+
+func (f rootFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	// The base directory and a subdirectory set by Sub are created on first use
+	if err := os.MkdirAll(f.base, perm); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(f.base)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.MkdirAll(filepath.Join(f.dir, path), perm)
+}
+
+func (f rootFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	root, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	dir, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	// Sorted like os.ReadDir
+	slices.SortFunc(entries, func(a, b os.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+func (f rootFileSystem) CreateTemp(dir, pattern string) (stagingFile, error) {
+	root, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	// os.Root has no CreateTemp; pick random names the way os.CreateTemp does
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		file, err := root.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return rootFile{File: file, name: name}, nil
+	}
+	return nil, &os.PathError{Op: "createtemp", Path: filepath.Join(dir, pattern), Err: os.ErrExist}
+}
+
+func (f rootFileSystem) Open(name string) (readableFile, error) {
+	root, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(name)
+	if err != nil {
+		// Avoid returning a non-nil interface holding a nil *os.File
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f rootFileSystem) Link(oldname, newname string) error {
+	root, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.Link(oldname, newname)
+}
+
+func (f rootFileSystem) Rename(oldpath, newpath string) error {
+	root, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.Rename(oldpath, newpath)
+}
+
+func (f rootFileSystem) Remove(name string) error {
+	root, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.Remove(name)
+}
+
+func (f rootFileSystem) OpenAppend(name string, perm os.FileMode) (appendFile, error) {
+	root, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	file, err := root.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		// Avoid returning a non-nil interface holding a nil *os.File
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f rootFileSystem) SyncDir(dir string) error {
+	root, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	d, err := root.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (f rootFileSystem) Sub(dir string) fileSystem {
+	return rootFileSystem{base: f.base, dir: filepath.Join(f.dir, dir)}
+}
+
+// rootFile is a file created through os.Root, named relative to the root like the other
+// rootFileSystem names rather than by its absolute path
+type rootFile struct {
+	*os.File
+	name string
+}
+
+func (f rootFile) Name() string {
+	return f.name
+}
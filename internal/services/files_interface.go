@@ -1,24 +1,107 @@
 package services
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // FilesServiceInterface defines the interface for file operations
 type FilesServiceInterface interface {
 	WriteFile(ctx context.Context, req FileWriteRequest) FileWriteResponse
+	ListFiles(ctx context.Context, directory string) FileListResponse
+	// OpenFile returns the file's checksum when it is known without reading the file, and
+	// otherwise only if hash is set
+	OpenFile(ctx context.Context, path string, hash bool) FileOpenResponse
+	DeleteFile(ctx context.Context, path string) FileDeleteResponse
+}
+
+// FileScope is the part of the base directory a request works in and the quota it is held to.
+// Requests carry it in their context (see WithFileScope); without one they use the whole base
+// directory without limits.
+type FileScope struct {
+	ClientID  string // OAuth client the request is made for; labels the usage metrics
+	Namespace bool   // confine the request to clients/<ClientID> below the base directory
+	MaxBytes  int64  // total size of the files in the scope; 0 for no limit
+	MaxFiles  int64  // number of files in the scope; 0 for no limit
 }
 
 // FileWriteRequest represents a request to write a file
 type FileWriteRequest struct {
-	Filename  string // Required: name of the file to create
-	Content   string // Required: content to write to the file
-	Directory string // Optional: subdirectory path within the base directory
+	Filename  string    // Required: name of the file to create
+	Content   string    // Required unless Body is set: content to write to the file
+	Directory string    // Optional: subdirectory path within the base directory
+	Body      io.Reader // Optional: streamed content, written instead of Content without buffering it
+
+	Encoding      string // Optional: encoding of Content: "utf8" (default), "base64" or "hex"
+	ContentSHA256 string // Optional: expected hex SHA-256 of the decoded content; the file is not kept on a mismatch
+
+	Mode    string // Optional: "create" (default), "overwrite", "append" or "version"
+	IfMatch string // Optional for overwrite and append: hex SHA-256 the current file must have, or "*" for any existing file
 }
 
+// File write modes accepted in FileWriteRequest.Mode
+const (
+	FileModeCreate    = "create"    // fail with a conflict when the file exists
+	FileModeOverwrite = "overwrite" // replace the file if it exists
+	FileModeAppend    = "append"    // add the content to the end of the file, creating it if needed
+	FileModeVersion   = "version"   // keep the existing file and write "name (2).ext", "name (3).ext", ...
+)
+
+// Content encodings accepted in FileWriteRequest.Encoding
+const (
+	EncodingUTF8   = "utf8"
+	EncodingBase64 = "base64"
+	EncodingHex    = "hex"
+)
+
 // FileWriteResponse represents the response from a file write operation
 type FileWriteResponse struct {
 	Status    string // "ok" or "error"
-	Created   bool   // true if file was successfully created
-	Path      string // relative path of the created file (on success)
+	Created   bool   // true if the write created a new file
+	Path      string // relative path of the written file (on success)
+	Size      int64  // size of the file after the write (on success)
+	SHA256    string // hex SHA-256 of the file after the write (on success)
 	Reason    string // error message (on failure)
-	ErrorKind string // "validation", "conflict", "internal", or "" for success
-}
\ No newline at end of file
+	ErrorKind string // "validation", "conflict", "precondition_failed", "too_large", "checksum_mismatch", "quota_exceeded", "internal", or "" for success
+}
+
+// FileEntry describes a file or subdirectory in a listing
+type FileEntry struct {
+	Name    string
+	Path    string // relative to the base directory
+	IsDir   bool
+	Size    int64 // files only
+	ModTime time.Time
+	SHA256  string // hex SHA-256; files only
+}
+
+// FileListResponse represents the response from listing a directory
+type FileListResponse struct {
+	Status    string      // "ok" or "error"
+	Directory string      // relative path of the listed directory; "" for the base directory
+	Entries   []FileEntry // sorted by name
+	Reason    string      // error message (on failure)
+	ErrorKind string      // "validation", "not_found", "internal", or "" for success
+}
+
+// FileOpenResponse represents the response from opening a file for reading.
+// On success the caller must close File.
+type FileOpenResponse struct {
+	Status    string // "ok" or "error"
+	Path      string // relative path of the file
+	File      io.ReadSeekCloser
+	Size      int64
+	ModTime   time.Time
+	SHA256    string // hex SHA-256 of the file; "" when not known and hashing was not requested
+	Reason    string // error message (on failure)
+	ErrorKind string // "validation", "not_found", "internal", or "" for success
+}
+
+// FileDeleteResponse represents the response from deleting a file
+type FileDeleteResponse struct {
+	Status    string // "ok" or "error"
+	Path      string // relative path of the deleted file
+	Reason    string // error message (on failure)
+	ErrorKind string // "validation", "not_found", "internal", or "" for success
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"omnidrop/internal/observability"
+)
+
+// ListFiles lists the files and subdirectories of a directory below the base directory,
+// with the size, modification time and checksum of each file
+func (s *FilesService) ListFiles(ctx context.Context, directory string) (resp FileListResponse) {
+	defer func() { recordFileOperation("list", resp.Status, resp.ErrorKind) }()
+
+	files, err := s.scoped(ctx)
+	if err != nil {
+		return FileListResponse{Status: "error", Reason: err.Error(), ErrorKind: "internal"}
+	}
+	return files.listFiles(directory)
+}
+
+func (s *FilesService) listFiles(directory string) FileListResponse {
+	_, relativeDir, err := s.validateAndBuildPath("", directory)
+	if err == nil {
+		err = s.checkReserved(relativeDir)
+	}
+	if err != nil {
+		return FileListResponse{Status: "error", Reason: err.Error(), ErrorKind: "validation"}
+	}
+	dir := relativeDir
+	if dir == "" {
+		dir = "."
+	}
+
+	info, err := s.fs.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileListResponse{Status: "error", Reason: "directory not found", ErrorKind: "not_found"}
+	}
+	if err != nil {
+		return FileListResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to read directory: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+	if !info.IsDir() {
+		return FileListResponse{Status: "error", Reason: "path is not a directory", ErrorKind: "validation"}
+	}
+
+	dirEntries, err := s.fs.ReadDir(dir)
+	if err != nil {
+		return FileListResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to read directory: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+
+	entries := make([]FileEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		entryPath := filepath.Join(relativeDir, dirEntry.Name())
+		if isStagingFile(dirEntry.Name()) || s.checkReserved(entryPath) != nil {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		// Only regular files and directories are listed; links and special files are not served
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			continue
+		}
+
+		entry := FileEntry{
+			Name:    dirEntry.Name(),
+			Path:    filepath.ToSlash(entryPath),
+			IsDir:   info.IsDir(),
+			ModTime: info.ModTime(),
+		}
+		if !entry.IsDir {
+			checksum, exists, err := s.fileChecksum(entryPath)
+			if err != nil || !exists {
+				continue
+			}
+			entry.Size = info.Size()
+			entry.SHA256 = checksum
+		}
+		entries = append(entries, entry)
+	}
+
+	return FileListResponse{Status: "ok", Directory: filepath.ToSlash(relativeDir), Entries: entries}
+}
+
+// OpenFile opens a file below the base directory for reading. Its checksum is returned when it
+// is cached, or computed by reading the whole file if hash is set.
+func (s *FilesService) OpenFile(ctx context.Context, filePath string, hash bool) (resp FileOpenResponse) {
+	defer func() { recordFileOperation("read", resp.Status, resp.ErrorKind) }()
+
+	files, err := s.scoped(ctx)
+	if err != nil {
+		return FileOpenResponse{Status: "error", Reason: err.Error(), ErrorKind: "internal"}
+	}
+	return files.openFile(filePath, hash)
+}
+
+func (s *FilesService) openFile(filePath string, hash bool) FileOpenResponse {
+	relativePath, err := s.resolveFilePath(filePath)
+	if err == nil {
+		err = s.checkReserved(relativePath)
+	}
+	if err != nil {
+		return FileOpenResponse{Status: "error", Reason: err.Error(), ErrorKind: "validation"}
+	}
+
+	file, err := s.fs.Open(relativePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileOpenResponse{Status: "error", Reason: "file not found", ErrorKind: "not_found"}
+	}
+	if err != nil {
+		return FileOpenResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to open file: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+
+	info, err := file.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		file.Close()
+		return FileOpenResponse{Status: "error", Reason: "path is not a file", ErrorKind: "validation"}
+	}
+	checksum := ""
+	if err == nil {
+		var cached bool
+		if checksum, cached = s.cachedChecksum(relativePath, info); !cached && hash {
+			checksum, err = s.readChecksum(relativePath, file, info)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return FileOpenResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to read file: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+
+	return FileOpenResponse{
+		Status:  "ok",
+		Path:    filepath.ToSlash(relativePath),
+		File:    file,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		SHA256:  checksum,
+	}
+}
+
+// DeleteFile removes a file below the base directory. Directories are not removed.
+func (s *FilesService) DeleteFile(ctx context.Context, filePath string) (resp FileDeleteResponse) {
+	defer func() { recordFileOperation("delete", resp.Status, resp.ErrorKind) }()
+
+	files, err := s.scoped(ctx)
+	if err != nil {
+		return FileDeleteResponse{Status: "error", Reason: err.Error(), ErrorKind: "internal"}
+	}
+	resp = files.deleteFile(filePath)
+	if resp.Status == "ok" {
+		files.reportUsage()
+	}
+	return resp
+}
+
+func (s *FilesService) deleteFile(filePath string) FileDeleteResponse {
+	relativePath, err := s.resolveFilePath(filePath)
+	if err == nil {
+		err = s.checkReserved(relativePath)
+	}
+	if err != nil {
+		return FileDeleteResponse{Status: "error", Reason: err.Error(), ErrorKind: "validation"}
+	}
+
+	// Deleting must not interleave with a write committing to the same path
+	defer s.lockCommit(relativePath)()
+
+	info, err := s.fs.Stat(relativePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileDeleteResponse{Status: "error", Reason: "file not found", ErrorKind: "not_found"}
+	}
+	if err == nil && !info.Mode().IsRegular() {
+		return FileDeleteResponse{Status: "error", Reason: "path is not a file", ErrorKind: "validation"}
+	}
+	if err == nil {
+		err = s.fs.Remove(relativePath)
+	}
+	if err != nil {
+		return FileDeleteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to delete file: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+
+	s.checksums.remove(filepath.Join(s.dir, relativePath))
+	s.syncDir(filepath.Dir(relativePath))
+	return FileDeleteResponse{Status: "ok", Path: filepath.ToSlash(relativePath)}
+}
+
+// resolveFilePath validates a slash-separated file path and returns it relative to the base directory
+func (s *FilesService) resolveFilePath(filePath string) (string, error) {
+	dir, name := path.Split(filePath)
+	if name == "" {
+		return "", fmt.Errorf("invalid path: must name a file")
+	}
+	_, relativePath, err := s.validateAndBuildPath(name, strings.TrimSuffix(dir, "/"))
+	return relativePath, err
+}
+
+// isStagingFile reports whether name is a temporary file of a write in progress
+func isStagingFile(name string) bool {
+	return strings.HasPrefix(name, ".omnidrop-") && strings.HasSuffix(name, ".tmp")
+}
+
+func recordFileOperation(operation, status, errorKind string) {
+	label := "success"
+	switch {
+	case errorKind == "not_found":
+		label = "not_found"
+	case status == "error":
+		label = "failure"
+	}
+	observability.FileOperationsTotal.WithLabelValues(operation, label).Inc()
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"omnidrop/internal/observability"
+)
+
+// clientNamespaceDir holds the namespaces of clients confined to their own directory
+const clientNamespaceDir = "clients"
+
+// namespacePattern matches client IDs usable as a directory name. Starting with a letter or
+// digit rules out "." and ".." as well as hidden directories.
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+type fileScopeKey struct{}
+
+// WithFileScope returns a context whose file operations are confined to scope
+func WithFileScope(ctx context.Context, scope FileScope) context.Context {
+	return context.WithValue(ctx, fileScopeKey{}, scope)
+}
+
+// scoped returns the view of the service for the FileScope in ctx: rooted at the client's
+// namespace if it has one, and held to its quota. Without a scope it is the service itself.
+func (s *FilesService) scoped(ctx context.Context) (*FilesService, error) {
+	scope, ok := ctx.Value(fileScopeKey{}).(FileScope)
+	if !ok || scope == (FileScope{}) {
+		return s, nil
+	}
+
+	view := *s
+	view.scope = scope
+	if scope.Namespace {
+		if !namespacePattern.MatchString(scope.ClientID) {
+			return nil, fmt.Errorf("client ID '%s' cannot be used as a file namespace", scope.ClientID)
+		}
+		namespace := filepath.Join(clientNamespaceDir, scope.ClientID)
+		view.fs = s.fs.Sub(namespace)
+		view.dir = filepath.Join(s.dir, namespace)
+	}
+	return &view, nil
+}
+
+// checkReserved refuses to let a client without a namespace of its own list, read or change
+// relativePath if it lies in the directory holding the namespaces of other clients. Only requests
+// without a client scope may access files there. Names are compared case-insensitively like on the default macOS
+// file system.
+func (s *FilesService) checkReserved(relativePath string) error {
+	if s.scope.ClientID == "" || s.scope.Namespace {
+		return nil
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(relativePath), "/")
+	if strings.EqualFold(first, clientNamespaceDir) {
+		return fmt.Errorf("'%s/' is reserved for client namespaces", clientNamespaceDir)
+	}
+	return nil
+}
+
+// fileUsage is the total size and number of the files in a scope
+type fileUsage struct {
+	bytes int64
+	files int64
+}
+
+// usage walks the scope's directory tree. Staging files of writes in progress are not counted.
+func (s *FilesService) usage(dir string) (fileUsage, error) {
+	entries, err := s.fs.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		// Nothing has been written to a new namespace yet
+		return fileUsage{}, nil
+	}
+	if err != nil {
+		return fileUsage{}, err
+	}
+
+	var total fileUsage
+	for _, entry := range entries {
+		if isStagingFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		switch {
+		case info.IsDir():
+			sub, err := s.usage(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return fileUsage{}, err
+			}
+			total.bytes += sub.bytes
+			total.files += sub.files
+		case info.Mode().IsRegular():
+			total.bytes += info.Size()
+			total.files++
+		}
+	}
+	return total, nil
+}
+
+// exceedsQuota reports whether committing size bytes to relativePath, replacing the file there
+// if replaces is set, would take the scope over its quota, and the response to send if so.
+// The caller must hold lockCommit so concurrent writes cannot both fit in the same space.
+func (s *FilesService) exceedsQuota(relativePath string, replaces bool, size int64) (FileWriteResponse, bool) {
+	if s.scope.MaxBytes == 0 && s.scope.MaxFiles == 0 {
+		return FileWriteResponse{}, false
+	}
+	if s.scope.MaxBytes > 0 && size > s.scope.MaxBytes {
+		return fileLargerThanQuota(s.scope.MaxBytes), true
+	}
+
+	usage, err := s.usage(".")
+	if err == nil && replaces {
+		var info os.FileInfo
+		if info, err = s.fs.Stat(relativePath); err == nil {
+			usage.bytes -= info.Size()
+			usage.files--
+		}
+	}
+	if err != nil {
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to compute quota usage: %v", err),
+			ErrorKind: "internal",
+		}, true
+	}
+
+	if s.scope.MaxFiles > 0 && usage.files+1 > s.scope.MaxFiles {
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("quota exceeded: %d of %d files in use", usage.files, s.scope.MaxFiles),
+			ErrorKind: "quota_exceeded",
+		}, true
+	}
+	if s.scope.MaxBytes > 0 && usage.bytes+size > s.scope.MaxBytes {
+		return FileWriteResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("quota exceeded: %d of %d bytes in use, %d more needed",
+				usage.bytes, s.scope.MaxBytes, usage.bytes+size-s.scope.MaxBytes),
+			ErrorKind: "quota_exceeded",
+		}, true
+	}
+	return FileWriteResponse{}, false
+}
+
+// fileLargerThanQuota reports a file that could not fit even in an empty scope
+func fileLargerThanQuota(limit int64) FileWriteResponse {
+	return FileWriteResponse{
+		Status:    "error",
+		Reason:    fmt.Sprintf("file exceeds the quota of %d bytes", limit),
+		ErrorKind: "too_large",
+	}
+}
+
+// reportUsage exports the usage and quota of a client's namespace after a write or delete.
+// A client sharing the base directory has no usage of its own.
+func (s *FilesService) reportUsage() {
+	if s.scope.ClientID == "" || !s.scope.Namespace {
+		return
+	}
+	usage, err := s.usage(".")
+	if err != nil {
+		slog.Warn("⚠️ Failed to compute file usage",
+			slog.String("client_id", s.scope.ClientID),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	observability.FileUsageBytes.WithLabelValues(s.scope.ClientID).Set(float64(usage.bytes))
+	observability.FileUsageFiles.WithLabelValues(s.scope.ClientID).Set(float64(usage.files))
+	if s.scope.MaxBytes > 0 {
+		observability.FileQuotaBytes.WithLabelValues(s.scope.ClientID).Set(float64(s.scope.MaxBytes))
+	}
+	if s.scope.MaxFiles > 0 {
+		observability.FileQuotaFiles.WithLabelValues(s.scope.ClientID).Set(float64(s.scope.MaxFiles))
+	}
+}
+
+// quotaReader fails once more than limit bytes are read, so an upload larger than the whole
+// quota is not staged in full before it is rejected
+type quotaReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	q.read += int64(n)
+	if q.read > q.limit {
+		return n, &quotaError{limit: q.limit}
+	}
+	return n, err
+}
+
+// quotaError is returned by quotaReader
+type quotaError struct {
+	limit int64
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("file exceeds the quota of %d bytes", e.limit)
+}
@@ -2,13 +2,26 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"omnidrop/internal/config"
+	"omnidrop/internal/observability"
 )
 
 func TestFilesService_WriteFile_Success(t *testing.T) {
@@ -37,6 +50,597 @@ func TestFilesService_WriteFile_Success(t *testing.T) {
 	assert.Equal(t, "Hello, World!", string(content))
 }
 
+func TestFilesService_WriteFile_Stream(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	content := strings.Repeat("streamed content\n", 1000)
+	response := service.WriteFile(context.Background(), FileWriteRequest{
+		Filename:  "upload.bin",
+		Directory: "uploads",
+		Body:      strings.NewReader(content),
+	})
+
+	sum := sha256.Sum256([]byte(content))
+	assert.Equal(t, "ok", response.Status)
+	assert.True(t, response.Created)
+	assert.Equal(t, "uploads/upload.bin", response.Path)
+	assert.Equal(t, int64(len(content)), response.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), response.SHA256)
+
+	written, err := os.ReadFile(filepath.Join(tempDir, "uploads", "upload.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(written))
+}
+
+func TestFilesService_WriteFile_StreamTooLarge(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(strings.Repeat("x", 100))), 10)
+	response := service.WriteFile(context.Background(), FileWriteRequest{
+		Filename: "big.bin",
+		Body:     body,
+	})
+
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, "too_large", response.ErrorKind)
+
+	// The partial file is removed
+	_, err := os.Stat(filepath.Join(tempDir, "big.bin"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFilesService_WriteFile_StreamConflict(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "existing.bin"), []byte("original"), 0644))
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	response := service.WriteFile(context.Background(), FileWriteRequest{
+		Filename: "existing.bin",
+		Body:     strings.NewReader("replacement"),
+	})
+
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, "conflict", response.ErrorKind)
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "existing.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(content))
+}
+
+func TestFilesService_WriteFile_Encodings(t *testing.T) {
+	binary := []byte{0x00, 0xff, 0x10, 'O', 'K'}
+	sum := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		req      FileWriteRequest
+		wantKind string
+	}{
+		{
+			name: "base64",
+			req:  FileWriteRequest{Content: base64.StdEncoding.EncodeToString(binary), Encoding: EncodingBase64},
+		},
+		{
+			name: "hex with checksum",
+			req:  FileWriteRequest{Content: hex.EncodeToString(binary), Encoding: EncodingHex, ContentSHA256: strings.ToUpper(checksum)},
+		},
+		{
+			name:     "invalid base64",
+			req:      FileWriteRequest{Content: "not base64!", Encoding: EncodingBase64},
+			wantKind: "validation",
+		},
+		{
+			name:     "unsupported encoding",
+			req:      FileWriteRequest{Content: "abc", Encoding: "rot13"},
+			wantKind: "validation",
+		},
+		{
+			name:     "malformed checksum",
+			req:      FileWriteRequest{Content: "abc", ContentSHA256: "abc"},
+			wantKind: "validation",
+		},
+		{
+			name:     "checksum mismatch",
+			req:      FileWriteRequest{Content: base64.StdEncoding.EncodeToString(binary[1:]), Encoding: EncodingBase64, ContentSHA256: checksum},
+			wantKind: "checksum_mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+			tt.req.Filename = "data.bin"
+			response := service.WriteFile(context.Background(), tt.req)
+
+			path := filepath.Join(tempDir, "data.bin")
+			if tt.wantKind != "" {
+				assert.Equal(t, "error", response.Status)
+				assert.Equal(t, tt.wantKind, response.ErrorKind)
+				_, err := os.Stat(path)
+				assert.True(t, os.IsNotExist(err), "file must not be written")
+				return
+			}
+
+			assert.Equal(t, "ok", response.Status)
+			assert.Equal(t, checksum, response.SHA256)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, binary, content)
+		})
+	}
+}
+
+func TestFilesService_WriteFile_StreamChecksumMismatch(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	response := service.WriteFile(context.Background(), FileWriteRequest{
+		Filename:      "upload.bin",
+		Body:          strings.NewReader("corrupted in transit"),
+		ContentSHA256: strings.Repeat("0", 64),
+	})
+
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, "checksum_mismatch", response.ErrorKind)
+	_, err := os.Stat(filepath.Join(tempDir, "upload.bin"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFilesService_WriteFile_Modes(t *testing.T) {
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name        string
+		existing    string // content of note.md before the write; "" for none
+		req         FileWriteRequest
+		wantKind    string
+		wantCreated bool
+		wantPath    string
+		wantContent string
+	}{
+		{
+			name:        "overwrite existing",
+			existing:    "old",
+			req:         FileWriteRequest{Content: "new", Mode: FileModeOverwrite},
+			wantPath:    "note.md",
+			wantContent: "new",
+		},
+		{
+			name:        "overwrite missing creates",
+			req:         FileWriteRequest{Content: "new", Mode: FileModeOverwrite},
+			wantCreated: true,
+			wantPath:    "note.md",
+			wantContent: "new",
+		},
+		{
+			name:        "overwrite with matching If-Match",
+			existing:    "old",
+			req:         FileWriteRequest{Content: "new", Mode: FileModeOverwrite, IfMatch: checksum("old")},
+			wantPath:    "note.md",
+			wantContent: "new",
+		},
+		{
+			name:     "overwrite with stale If-Match",
+			existing: "changed",
+			req:      FileWriteRequest{Content: "new", Mode: FileModeOverwrite, IfMatch: checksum("old")},
+			wantKind: "precondition_failed",
+		},
+		{
+			name:     "If-Match * requires an existing file",
+			req:      FileWriteRequest{Content: "new", Mode: FileModeOverwrite, IfMatch: "*"},
+			wantKind: "precondition_failed",
+		},
+		{
+			name:        "append to existing",
+			existing:    "line 1\n",
+			req:         FileWriteRequest{Content: "line 2\n", Mode: FileModeAppend},
+			wantPath:    "note.md",
+			wantContent: "line 1\nline 2\n",
+		},
+		{
+			name:        "append to missing creates",
+			req:         FileWriteRequest{Content: "line 1\n", Mode: FileModeAppend},
+			wantCreated: true,
+			wantPath:    "note.md",
+			wantContent: "line 1\n",
+		},
+		{
+			name:        "version keeps existing",
+			existing:    "first",
+			req:         FileWriteRequest{Content: "second", Mode: FileModeVersion},
+			wantCreated: true,
+			wantPath:    "note (2).md",
+			wantContent: "second",
+		},
+		{
+			name:     "If-Match with create",
+			req:      FileWriteRequest{Content: "new", IfMatch: "*"},
+			wantKind: "validation",
+		},
+		{
+			name:     "unknown mode",
+			req:      FileWriteRequest{Content: "new", Mode: "replace"},
+			wantKind: "validation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if tt.existing != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, "note.md"), []byte(tt.existing), 0644))
+			}
+			service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+			tt.req.Filename = "note.md"
+			response := service.WriteFile(context.Background(), tt.req)
+
+			if tt.wantKind != "" {
+				assert.Equal(t, "error", response.Status)
+				assert.Equal(t, tt.wantKind, response.ErrorKind)
+				if tt.existing != "" {
+					content, err := os.ReadFile(filepath.Join(tempDir, "note.md"))
+					require.NoError(t, err)
+					assert.Equal(t, tt.existing, string(content), "existing file must be unchanged")
+				}
+				return
+			}
+
+			require.Equal(t, "ok", response.Status, response.Reason)
+			assert.Equal(t, tt.wantCreated, response.Created)
+			assert.Equal(t, tt.wantPath, response.Path)
+			assert.Equal(t, int64(len(tt.wantContent)), response.Size)
+			assert.Equal(t, checksum(tt.wantContent), response.SHA256)
+
+			content, err := os.ReadFile(filepath.Join(tempDir, tt.wantPath))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantContent, string(content))
+
+			// Staged temporary files never outlive the request
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.False(t, strings.HasPrefix(entry.Name(), ".omnidrop-"), "leftover staging file %s", entry.Name())
+			}
+		})
+	}
+}
+
+// fullDiskFS fails staging file writes with ENOSPC once a file exceeds limit bytes
+type fullDiskFS struct {
+	fileSystem
+	limit int
+}
+
+func (f fullDiskFS) CreateTemp(dir, pattern string) (stagingFile, error) {
+	file, err := f.fileSystem.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &fullDiskFile{stagingFile: file, remaining: f.limit}, nil
+}
+
+type fullDiskFile struct {
+	stagingFile
+	remaining int
+}
+
+func (f *fullDiskFile) Write(p []byte) (int, error) {
+	if len(p) > f.remaining {
+		n, _ := f.stagingFile.Write(p[:f.remaining])
+		f.remaining = 0
+		return n, syscall.ENOSPC
+	}
+	f.remaining -= len(p)
+	return f.stagingFile.Write(p)
+}
+
+func TestFilesService_WriteFile_DiskFull(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // content of data.txt before the write; "" for none
+		req      FileWriteRequest
+	}{
+		{
+			name: "create",
+			req:  FileWriteRequest{Content: "more than ten bytes"},
+		},
+		{
+			name:     "overwrite",
+			existing: "original",
+			req:      FileWriteRequest{Content: "more than ten bytes", Mode: FileModeOverwrite},
+		},
+		{
+			name:     "append",
+			existing: "original content",
+			req:      FileWriteRequest{Content: "tail", Mode: FileModeAppend},
+		},
+		{
+			name: "stream",
+			req:  FileWriteRequest{Body: strings.NewReader("more than ten bytes")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			path := filepath.Join(tempDir, "data.txt")
+			if tt.existing != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.existing), 0644))
+			}
+			service := NewFilesService(&config.Config{FilesDir: tempDir})
+			diskFS := service.fs
+			service.fs = fullDiskFS{fileSystem: diskFS, limit: 10}
+
+			tt.req.Filename = "data.txt"
+			response := service.WriteFile(context.Background(), tt.req)
+
+			assert.Equal(t, "error", response.Status)
+			assert.Equal(t, "internal", response.ErrorKind)
+			assert.Contains(t, response.Reason, syscall.ENOSPC.Error())
+
+			// The target is untouched and no staging file is left behind
+			content, err := os.ReadFile(path)
+			if tt.existing == "" {
+				assert.True(t, os.IsNotExist(err), "truncated file must not be created")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.existing, string(content))
+			}
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.Equal(t, "data.txt", entry.Name(), "unexpected leftover file")
+			}
+
+			// A retry once space is available succeeds
+			service.fs = diskFS
+			if tt.req.Body != nil {
+				tt.req.Body = strings.NewReader("more than ten bytes")
+			}
+			assert.Equal(t, "ok", service.WriteFile(context.Background(), tt.req).Status)
+		})
+	}
+}
+
+// recordingFS records the order of the operations that make a write durable
+type recordingFS struct {
+	fileSystem
+	ops *[]string
+}
+
+func (f recordingFS) CreateTemp(dir, pattern string) (stagingFile, error) {
+	file, err := f.fileSystem.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return recordingFile{stagingFile: file, ops: f.ops}, nil
+}
+
+func (f recordingFS) Link(oldname, newname string) error {
+	*f.ops = append(*f.ops, "link")
+	return f.fileSystem.Link(oldname, newname)
+}
+
+func (f recordingFS) Rename(oldpath, newpath string) error {
+	*f.ops = append(*f.ops, "rename")
+	return f.fileSystem.Rename(oldpath, newpath)
+}
+
+func (f recordingFS) SyncDir(dir string) error {
+	*f.ops = append(*f.ops, "sync dir")
+	return f.fileSystem.SyncDir(dir)
+}
+
+type recordingFile struct {
+	stagingFile
+	ops *[]string
+}
+
+func (f recordingFile) Sync() error {
+	*f.ops = append(*f.ops, "sync")
+	return f.stagingFile.Sync()
+}
+
+func TestFilesService_WriteFile_SyncsBeforeCommit(t *testing.T) {
+	tempDir := t.TempDir()
+	var ops []string
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+	service.fs = recordingFS{fileSystem: service.fs, ops: &ops}
+
+	response := service.WriteFile(context.Background(), FileWriteRequest{Filename: "a.txt", Content: "a"})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, []string{"sync", "link", "sync dir"}, ops)
+
+	ops = nil
+	response = service.WriteFile(context.Background(), FileWriteRequest{Filename: "a.txt", Content: "b", Mode: FileModeOverwrite})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, []string{"sync", "rename", "sync dir"}, ops)
+}
+
+// openCountingFS counts the files opened for reading
+type openCountingFS struct {
+	fileSystem
+	opens *int
+}
+
+func (f openCountingFS) Open(name string) (readableFile, error) {
+	*f.opens++
+	return f.fileSystem.Open(name)
+}
+
+func TestFilesService_WriteFile_HashesOnlyForIfMatch(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("old"), 0644))
+	opens := 0
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+	service.fs = openCountingFS{fileSystem: service.fs, opens: &opens}
+
+	response := service.WriteFile(context.Background(), FileWriteRequest{Filename: "a.txt", Content: "new", Mode: FileModeOverwrite})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Zero(t, opens, "an overwrite without If-Match must not read the current file")
+
+	response = service.WriteFile(context.Background(), FileWriteRequest{
+		Filename: "a.txt",
+		Content:  "newer",
+		Mode:     FileModeOverwrite,
+		IfMatch:  response.SHA256,
+	})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, 1, opens)
+}
+
+func TestFilesService_CommitLocksPerPath(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+	unlock := service.lockCommit("a.txt")
+
+	// A write to another file does not wait for the held lock
+	response := service.WriteFile(context.Background(), FileWriteRequest{Filename: "b.txt", Content: "b"})
+	require.Equal(t, "ok", response.Status, response.Reason)
+
+	done := make(chan FileWriteResponse)
+	go func() {
+		done <- service.WriteFile(context.Background(), FileWriteRequest{Filename: "a.txt", Content: "a"})
+	}()
+	select {
+	case <-done:
+		t.Fatal("a write to a locked file must wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	assert.Equal(t, "ok", (<-done).Status)
+	assert.Empty(t, service.locks.locks, "released locks are removed")
+}
+
+func TestFilesService_ListFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "notes", "archive"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "a.md"), []byte("a"), 0644))
+	// A write in progress and a symlink are not listed
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", ".omnidrop-123.tmp"), []byte("partial"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(tempDir, "notes", "a.md"), filepath.Join(tempDir, "notes", "link.md")))
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	response := service.ListFiles(context.Background(), "notes")
+
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, "notes", response.Directory)
+	require.Len(t, response.Entries, 2)
+
+	sum := sha256.Sum256([]byte("a"))
+	assert.Equal(t, "a.md", response.Entries[0].Name)
+	assert.Equal(t, "notes/a.md", response.Entries[0].Path)
+	assert.False(t, response.Entries[0].IsDir)
+	assert.Equal(t, int64(1), response.Entries[0].Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), response.Entries[0].SHA256)
+	assert.Equal(t, "notes/archive", response.Entries[1].Path)
+	assert.True(t, response.Entries[1].IsDir)
+	assert.Empty(t, response.Entries[1].SHA256)
+
+	assert.Equal(t, "not_found", service.ListFiles(context.Background(), "missing").ErrorKind)
+	assert.Equal(t, "validation", service.ListFiles(context.Background(), "../outside").ErrorKind)
+	assert.Equal(t, "validation", service.ListFiles(context.Background(), "notes/a.md").ErrorKind)
+}
+
+func TestFilesService_OpenFile(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "a.md"), []byte("content"), 0644))
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	response := service.OpenFile(context.Background(), "notes/a.md", true)
+	require.Equal(t, "ok", response.Status, response.Reason)
+	defer response.File.Close()
+
+	sum := sha256.Sum256([]byte("content"))
+	assert.Equal(t, "notes/a.md", response.Path)
+	assert.Equal(t, int64(7), response.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), response.SHA256)
+	// The file is rewound after hashing
+	content, err := io.ReadAll(response.File)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	assert.Equal(t, "not_found", service.OpenFile(context.Background(), "notes/missing.md", true).ErrorKind)
+	assert.Equal(t, "validation", service.OpenFile(context.Background(), "notes", true).ErrorKind)
+	assert.Equal(t, "validation", service.OpenFile(context.Background(), "notes/", true).ErrorKind)
+	assert.Equal(t, "validation", service.OpenFile(context.Background(), "../etc/passwd", true).ErrorKind)
+	assert.Equal(t, "validation", service.OpenFile(context.Background(), "notes/.omnidrop-1.tmp", true).ErrorKind)
+}
+
+func TestFilesService_ChecksumCache(t *testing.T) {
+	tempDir := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	open := func(path string, hash bool) string {
+		response := service.OpenFile(context.Background(), path, hash)
+		require.Equal(t, "ok", response.Status, response.Reason)
+		response.File.Close()
+		return response.SHA256
+	}
+
+	// A committed file's checksum is known without reading it
+	written := service.WriteFile(context.Background(), FileWriteRequest{Filename: "written.txt", Content: "written"})
+	require.Equal(t, "ok", written.Status, written.Reason)
+	assert.Equal(t, checksum("written"), open("written.txt", false))
+
+	// A file placed outside the service is hashed only on request, then cached
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "placed.txt"), []byte("placed"), 0644))
+	assert.Empty(t, open("placed.txt", false))
+	assert.Equal(t, checksum("placed"), open("placed.txt", true))
+	assert.Equal(t, checksum("placed"), open("placed.txt", false))
+
+	// Changing the file outside the service invalidates the cached checksum
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "placed.txt"), []byte("replaced"), 0644))
+	assert.Empty(t, open("placed.txt", false))
+	list := service.ListFiles(context.Background(), "")
+	require.Equal(t, "ok", list.Status, list.Reason)
+	for _, entry := range list.Entries {
+		if entry.Name == "placed.txt" {
+			assert.Equal(t, checksum("replaced"), entry.SHA256)
+		}
+	}
+	assert.Equal(t, checksum("replaced"), open("placed.txt", false))
+}
+
+func TestFilesService_DeleteFile(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "notes"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes", "a.md"), []byte("a"), 0644))
+	service := NewFilesService(&config.Config{FilesDir: tempDir})
+
+	response := service.DeleteFile(context.Background(), "notes/a.md")
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, "notes/a.md", response.Path)
+	_, err := os.Stat(filepath.Join(tempDir, "notes", "a.md"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, "not_found", service.DeleteFile(context.Background(), "notes/a.md").ErrorKind)
+	assert.Equal(t, "validation", service.DeleteFile(context.Background(), "notes").ErrorKind)
+	assert.DirExists(t, filepath.Join(tempDir, "notes"))
+}
+
+func TestVersionedPath(t *testing.T) {
+	assert.Equal(t, "/files/note.md", versionedPath("/files/note.md", 1))
+	assert.Equal(t, "/files/note (2).md", versionedPath("/files/note.md", 2))
+	assert.Equal(t, "/files/archive.tar (3).gz", versionedPath("/files/archive.tar.gz", 3))
+	assert.Equal(t, "/files/README (2)", versionedPath("/files/README", 2))
+	assert.Equal(t, "/files/.env (2)", versionedPath("/files/.env", 2))
+}
+
 func TestFilesService_WriteFile_WithDirectory(t *testing.T) {
 	// Setup
 	tempDir := t.TempDir()
@@ -174,4 +778,445 @@ func TestFilesService_WriteFile_InvalidBaseDirectory(t *testing.T) {
 	assert.Equal(t, "error", response.Status)
 	assert.False(t, response.Created)
 	assert.Contains(t, response.Reason, "failed to create directory")
-}
\ No newline at end of file
+}
+// symlinkTree creates a base directory with adversarial symbolic links next to a directory
+// outside it holding secret.txt. Only "inside" is a link the service follows.
+func symlinkTree(t *testing.T) (base, outside string) {
+	t.Helper()
+	base = t.TempDir()
+	outside = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(base, "real"), 0755))
+
+	relativeOutside, err := filepath.Rel(base, outside)
+	require.NoError(t, err)
+	links := map[string]string{
+		"escape":      outside,
+		"relative":    relativeOutside,
+		"leak.txt":    filepath.Join(outside, "secret.txt"),
+		"dangling":    filepath.Join(outside, "missing.txt"),
+		"dangling-in": filepath.Join(base, "missing"),
+		"inside":      "real",
+		"absolute":    filepath.Join(base, "real"),
+		"real/up":     outside,
+	}
+	for name, target := range links {
+		require.NoError(t, os.Symlink(target, filepath.Join(base, name)))
+	}
+	return base, outside
+}
+
+func TestFilesService_WriteFile_Symlinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        FileWriteRequest
+		wantReason string // "" when the write succeeds
+	}{
+		{
+			name:       "directory link leading outside",
+			req:        FileWriteRequest{Directory: "escape", Filename: "new.txt", Content: "x"},
+			wantReason: "symbolic link 'escape' leads outside base directory",
+		},
+		{
+			name:       "relative directory link leading outside",
+			req:        FileWriteRequest{Directory: "relative", Filename: "new.txt", Content: "x"},
+			wantReason: "symbolic link 'relative' leads outside base directory",
+		},
+		{
+			name:       "link leading outside through a linked directory",
+			req:        FileWriteRequest{Directory: "inside/up", Filename: "new.txt", Content: "x"},
+			wantReason: "symbolic link 'up' leads outside base directory",
+		},
+		{
+			name:       "overwrite of a file link leading outside",
+			req:        FileWriteRequest{Filename: "leak.txt", Content: "x", Mode: FileModeOverwrite},
+			wantReason: "symbolic link 'leak.txt' leads outside base directory",
+		},
+		{
+			name:       "append to a file link leading outside",
+			req:        FileWriteRequest{Filename: "leak.txt", Content: "x", Mode: FileModeAppend},
+			wantReason: "symbolic link 'leak.txt' leads outside base directory",
+		},
+		{
+			name:       "dangling file link",
+			req:        FileWriteRequest{Filename: "dangling", Content: "x", Mode: FileModeOverwrite},
+			wantReason: "symbolic link 'dangling' is dangling",
+		},
+		{
+			name:       "dangling directory link",
+			req:        FileWriteRequest{Directory: "dangling-in", Filename: "new.txt", Content: "x"},
+			wantReason: "symbolic link 'dangling-in' is dangling",
+		},
+		{
+			name:       "absolute directory link inside the base directory",
+			req:        FileWriteRequest{Directory: "absolute", Filename: "new.txt", Content: "x"},
+			wantReason: "symbolic link 'absolute' must be relative",
+		},
+		{
+			name: "directory link inside the base directory",
+			req:  FileWriteRequest{Directory: "inside", Filename: "new.txt", Content: "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, outside := symlinkTree(t)
+			service := NewFilesService(&config.Config{FilesDir: base})
+
+			response := service.WriteFile(context.Background(), tt.req)
+
+			if tt.wantReason == "" {
+				require.Equal(t, "ok", response.Status, response.Reason)
+				content, err := os.ReadFile(filepath.Join(base, "real", tt.req.Filename))
+				require.NoError(t, err)
+				assert.Equal(t, tt.req.Content, string(content))
+				return
+			}
+			assert.Equal(t, "error", response.Status)
+			assert.Equal(t, "validation", response.ErrorKind)
+			assert.Contains(t, response.Reason, tt.wantReason)
+
+			// Nothing outside the base directory is created or changed
+			entries, err := os.ReadDir(outside)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			content, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "secret", string(content))
+		})
+	}
+}
+
+func TestFilesService_ReadAndDelete_Symlinks(t *testing.T) {
+	base, outside := symlinkTree(t)
+	service := NewFilesService(&config.Config{FilesDir: base})
+	ctx := context.Background()
+
+	for _, dir := range []string{"escape", "relative", "inside/up", "dangling-in"} {
+		response := service.ListFiles(ctx, dir)
+		assert.Equal(t, "validation", response.ErrorKind, "list %s", dir)
+	}
+	for _, path := range []string{"leak.txt", "escape/secret.txt", "relative/secret.txt", "inside/up/secret.txt", "dangling"} {
+		open := service.OpenFile(ctx, path, true)
+		assert.Equal(t, "validation", open.ErrorKind, "open %s", path)
+		deleted := service.DeleteFile(ctx, path)
+		assert.Equal(t, "validation", deleted.ErrorKind, "delete %s", path)
+	}
+	_, err := os.Stat(filepath.Join(outside, "secret.txt"))
+	assert.NoError(t, err)
+
+	// Links that stay inside the base directory are followed
+	require.Equal(t, "ok", service.WriteFile(ctx, FileWriteRequest{Directory: "real", Filename: "a.txt", Content: "a"}).Status)
+	list := service.ListFiles(ctx, "inside")
+	require.Equal(t, "ok", list.Status, list.Reason)
+	require.Len(t, list.Entries, 1)
+	assert.Equal(t, "inside/a.txt", list.Entries[0].Path)
+	open := service.OpenFile(ctx, "inside/a.txt", true)
+	require.Equal(t, "ok", open.Status, open.Reason)
+	open.File.Close()
+}
+
+// swapFS replaces the directory dir with a symbolic link to target before the first operation,
+// i.e. after the path was validated but before it is used
+type swapFS struct {
+	fileSystem
+	swap func()
+}
+
+func (f swapFS) Stat(name string) (os.FileInfo, error) {
+	f.swap()
+	return f.fileSystem.Stat(name)
+}
+
+func (f swapFS) MkdirAll(path string, perm os.FileMode) error {
+	f.swap()
+	return f.fileSystem.MkdirAll(path, perm)
+}
+
+func (f swapFS) Open(name string) (readableFile, error) {
+	f.swap()
+	return f.fileSystem.Open(name)
+}
+
+func TestFilesService_SymlinkSwappedAfterCheck(t *testing.T) {
+	operations := map[string]func(*FilesService) string{
+		"create": func(s *FilesService) string {
+			return s.WriteFile(context.Background(), FileWriteRequest{Directory: "dir", Filename: "new.txt", Content: "x"}).Status
+		},
+		"overwrite": func(s *FilesService) string {
+			return s.WriteFile(context.Background(), FileWriteRequest{Directory: "dir", Filename: "secret.txt", Content: "x", Mode: FileModeOverwrite}).Status
+		},
+		"open": func(s *FilesService) string {
+			response := s.OpenFile(context.Background(), "dir/secret.txt", true)
+			if response.File != nil {
+				response.File.Close()
+			}
+			return response.Status
+		},
+		"delete": func(s *FilesService) string {
+			return s.DeleteFile(context.Background(), "dir/secret.txt").Status
+		},
+	}
+
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			base := t.TempDir()
+			outside := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+			dir := filepath.Join(base, "dir")
+			require.NoError(t, os.Mkdir(dir, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("inside"), 0644))
+
+			service := NewFilesService(&config.Config{FilesDir: base})
+			var once sync.Once
+			service.fs = swapFS{fileSystem: service.fs, swap: func() {
+				once.Do(func() {
+					require.NoError(t, os.RemoveAll(dir))
+					require.NoError(t, os.Symlink(outside, dir))
+				})
+			}}
+
+			assert.Equal(t, "error", operation(service))
+
+			entries, err := os.ReadDir(outside)
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "nothing may be created outside the base directory")
+			content, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "secret", string(content))
+		})
+	}
+}
+
+func TestFilesService_SymlinkedBaseDirectory(t *testing.T) {
+	realBase := t.TempDir()
+	base := filepath.Join(t.TempDir(), "files")
+	require.NoError(t, os.Symlink(realBase, base))
+	service := NewFilesService(&config.Config{FilesDir: base})
+
+	response := service.WriteFile(context.Background(), FileWriteRequest{Directory: "notes", Filename: "a.txt", Content: "a"})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	_, err := os.Stat(filepath.Join(realBase, "notes", "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", service.ListFiles(context.Background(), "notes").Status)
+}
+
+func TestFilesService_ClientNamespaces(t *testing.T) {
+	base := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: base})
+	alpha := WithFileScope(context.Background(), FileScope{ClientID: "alpha", Namespace: true})
+	beta := WithFileScope(context.Background(), FileScope{ClientID: "beta", Namespace: true})
+
+	response := service.WriteFile(alpha, FileWriteRequest{Directory: "notes", Filename: "a.txt", Content: "alpha"})
+	require.Equal(t, "ok", response.Status, response.Reason)
+	assert.Equal(t, "notes/a.txt", response.Path, "paths are relative to the namespace")
+	content, err := os.ReadFile(filepath.Join(base, "clients", "alpha", "notes", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", string(content))
+
+	// Another client neither sees nor reaches the file
+	assert.Equal(t, "not_found", service.ListFiles(beta, "notes").ErrorKind)
+	assert.Equal(t, "not_found", service.OpenFile(beta, "notes/a.txt", true).ErrorKind)
+	assert.Equal(t, "not_found", service.DeleteFile(beta, "notes/a.txt").ErrorKind)
+	assert.Equal(t, "validation", service.OpenFile(beta, "../alpha/notes/a.txt", true).ErrorKind)
+	require.Equal(t, "ok", service.WriteFile(beta, FileWriteRequest{Directory: "notes", Filename: "a.txt", Content: "beta"}).Status)
+
+	list := service.ListFiles(alpha, "notes")
+	require.Equal(t, "ok", list.Status, list.Reason)
+	require.Len(t, list.Entries, 1)
+	assert.Equal(t, "notes/a.txt", list.Entries[0].Path)
+	open := service.OpenFile(alpha, "notes/a.txt", true)
+	require.Equal(t, "ok", open.Status, open.Reason)
+	data, err := io.ReadAll(open.File)
+	open.File.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", string(data))
+
+	// Requests without a namespace see every client's files
+	list = service.ListFiles(context.Background(), "clients")
+	require.Equal(t, "ok", list.Status, list.Reason)
+	assert.Len(t, list.Entries, 2)
+
+	// A client ID that is not a plain directory name is refused rather than used as a path
+	for _, clientID := range []string{"", ".", "..", "../beta", "a/b", ".hidden"} {
+		scope := WithFileScope(context.Background(), FileScope{ClientID: clientID, Namespace: true})
+		response := service.WriteFile(scope, FileWriteRequest{Filename: "x.txt", Content: "x"})
+		assert.Equal(t, "internal", response.ErrorKind, "client ID %q", clientID)
+	}
+}
+
+func TestFilesService_NamespacesReservedForUnscopedRequests(t *testing.T) {
+	base := t.TempDir()
+	service := NewFilesService(&config.Config{FilesDir: base})
+	alpha := WithFileScope(context.Background(), FileScope{ClientID: "alpha", Namespace: true})
+	shared := WithFileScope(context.Background(), FileScope{ClientID: "shared"})
+	require.Equal(t, "ok", service.WriteFile(alpha, FileWriteRequest{Filename: "a.txt", Content: "alpha"}).Status)
+
+	// A client sharing the base directory cannot change another client's namespace
+	for _, directory := range []string{"clients/alpha", "Clients/alpha", "clients"} {
+		response := service.WriteFile(shared, FileWriteRequest{Directory: directory, Filename: "a.txt", Content: "shared", Mode: FileModeOverwrite})
+		assert.Equal(t, "validation", response.ErrorKind, "directory %q", directory)
+	}
+	assert.Equal(t, "validation", service.DeleteFile(shared, "clients/alpha/a.txt").ErrorKind)
+	content, err := os.ReadFile(filepath.Join(base, "clients", "alpha", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha", string(content))
+
+	// Nor list or read it
+	for _, directory := range []string{"clients", "CLIENTS/alpha"} {
+		assert.Equal(t, "validation", service.ListFiles(shared, directory).ErrorKind, "directory %q", directory)
+	}
+	assert.Equal(t, "validation", service.OpenFile(shared, "clients/alpha/a.txt", false).ErrorKind)
+
+	// Elsewhere it works as before, without clients/ in its listings, and a request without a
+	// client scope may still manage namespaces
+	assert.Equal(t, "ok", service.WriteFile(shared, FileWriteRequest{Directory: "notes", Filename: "a.txt", Content: "shared"}).Status)
+	listing := service.ListFiles(shared, "")
+	require.Equal(t, "ok", listing.Status)
+	require.Len(t, listing.Entries, 1)
+	assert.Equal(t, "notes", listing.Entries[0].Path)
+	assert.Len(t, service.ListFiles(context.Background(), "").Entries, 2)
+	opened := service.OpenFile(context.Background(), "clients/alpha/a.txt", false)
+	require.Equal(t, "ok", opened.Status)
+	opened.File.Close()
+	assert.Equal(t, "ok", service.DeleteFile(context.Background(), "clients/alpha/a.txt").Status)
+}
+
+func TestFilesService_SymlinkedClientNamespace(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "clients"), 0755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(base, "clients", "mallory")))
+	service := NewFilesService(&config.Config{FilesDir: base})
+	scope := WithFileScope(context.Background(), FileScope{ClientID: "mallory", Namespace: true})
+
+	response := service.WriteFile(scope, FileWriteRequest{Filename: "a.txt", Content: "a"})
+
+	assert.Equal(t, "error", response.Status)
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the namespace must not lead outside the files directory")
+	assert.NotEqual(t, "ok", service.ListFiles(scope, "").Status)
+}
+
+func TestFilesService_ClientQuotas(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    FileScope
+		existing map[string]string // files in the namespace before the write
+		req      FileWriteRequest
+		wantKind string // "" when the write succeeds
+	}{
+		{
+			name:  "within byte quota",
+			scope: FileScope{MaxBytes: 10},
+			req:   FileWriteRequest{Filename: "a.txt", Content: "0123456789"},
+		},
+		{
+			name:     "larger than the byte quota",
+			scope:    FileScope{MaxBytes: 10},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "0123456789a"},
+			wantKind: "too_large",
+		},
+		{
+			name:     "streamed larger than the byte quota",
+			scope:    FileScope{MaxBytes: 10},
+			req:      FileWriteRequest{Filename: "a.txt", Body: strings.NewReader(strings.Repeat("x", 100))},
+			wantKind: "too_large",
+		},
+		{
+			name:     "over byte quota with existing files",
+			scope:    FileScope{MaxBytes: 10},
+			existing: map[string]string{"old.txt": "012345"},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "01234"},
+			wantKind: "quota_exceeded",
+		},
+		{
+			name:     "overwrite frees the replaced bytes",
+			scope:    FileScope{MaxBytes: 10},
+			existing: map[string]string{"a.txt": "012345"},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "0123456789", Mode: FileModeOverwrite},
+		},
+		{
+			name:     "append counts the combined file",
+			scope:    FileScope{MaxBytes: 10},
+			existing: map[string]string{"a.txt": "0123", "b.txt": "0123"},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "012", Mode: FileModeAppend},
+			wantKind: "quota_exceeded",
+		},
+		{
+			name:     "version adds a file",
+			scope:    FileScope{MaxFiles: 1},
+			existing: map[string]string{"a.txt": "a"},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "b", Mode: FileModeVersion},
+			wantKind: "quota_exceeded",
+		},
+		{
+			name:     "over file quota counting subdirectories",
+			scope:    FileScope{MaxFiles: 2},
+			existing: map[string]string{"a.txt": "a", "sub/b.txt": "b"},
+			req:      FileWriteRequest{Filename: "c.txt", Content: "c"},
+			wantKind: "quota_exceeded",
+		},
+		{
+			name:     "overwrite at the file quota",
+			scope:    FileScope{MaxFiles: 2},
+			existing: map[string]string{"a.txt": "a", "sub/b.txt": "b"},
+			req:      FileWriteRequest{Filename: "a.txt", Content: "c", Mode: FileModeOverwrite},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			namespace := filepath.Join(base, "clients", "client")
+			for name, content := range tt.existing {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(namespace, name)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(namespace, name), []byte(content), 0644))
+			}
+			service := NewFilesService(&config.Config{FilesDir: base})
+			tt.scope.ClientID = "client"
+			tt.scope.Namespace = true
+
+			response := service.WriteFile(WithFileScope(context.Background(), tt.scope), tt.req)
+
+			if tt.wantKind == "" {
+				assert.Equal(t, "ok", response.Status, response.Reason)
+			} else {
+				assert.Equal(t, "error", response.Status)
+				assert.Equal(t, tt.wantKind, response.ErrorKind, response.Reason)
+				assert.Contains(t, response.Reason, "quota")
+			}
+
+			// Nothing but the existing files and a successful write is left behind
+			var files []string
+			require.NoError(t, filepath.WalkDir(namespace, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					files = append(files, d.Name())
+				}
+				return err
+			}))
+			wantFiles := len(tt.existing)
+			if tt.wantKind == "" && tt.req.Mode != FileModeOverwrite {
+				wantFiles++
+			}
+			assert.Len(t, files, wantFiles, "files: %v", files)
+		})
+	}
+}
+
+func TestFilesService_ClientUsageMetrics(t *testing.T) {
+	service := NewFilesService(&config.Config{FilesDir: t.TempDir()})
+	ctx := WithFileScope(context.Background(), FileScope{ClientID: "metrics-client", Namespace: true, MaxBytes: 100, MaxFiles: 5})
+
+	require.Equal(t, "ok", service.WriteFile(ctx, FileWriteRequest{Filename: "a.txt", Content: "0123456789"}).Status)
+	require.Equal(t, "ok", service.WriteFile(ctx, FileWriteRequest{Directory: "sub", Filename: "b.txt", Content: "01234"}).Status)
+	assert.Equal(t, float64(15), testutil.ToFloat64(observability.FileUsageBytes.WithLabelValues("metrics-client")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(observability.FileUsageFiles.WithLabelValues("metrics-client")))
+	assert.Equal(t, float64(100), testutil.ToFloat64(observability.FileQuotaBytes.WithLabelValues("metrics-client")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(observability.FileQuotaFiles.WithLabelValues("metrics-client")))
+
+	require.Equal(t, "ok", service.DeleteFile(ctx, "a.txt").Status)
+	assert.Equal(t, float64(5), testutil.ToFloat64(observability.FileUsageBytes.WithLabelValues("metrics-client")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(observability.FileUsageFiles.WithLabelValues("metrics-client")))
+}
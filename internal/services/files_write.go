@@ -0,0 +1,301 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// stagingPattern names the temporary files content is staged in before it is moved into place
+	stagingPattern = ".omnidrop-*.tmp"
+
+	// maxFileVersions bounds the suffixes tried by the version mode
+	maxFileVersions = 1000
+)
+
+// stagedFile is content written to a temporary file, ready to be committed
+type stagedFile struct {
+	path     string
+	size     int64
+	checksum string      // hex SHA-256
+	info     os.FileInfo // kept by the committed file, which is linked or renamed from this one
+}
+
+// stageFile copies body into a new temporary file in dir, hashing it on the way, and flushes
+// it to disk. The temporary file is removed when any step fails, e.g. because the disk is full.
+func (s *FilesService) stageFile(dir string, body io.Reader) (stagedFile, error) {
+	file, err := s.fs.CreateTemp(dir, stagingPattern)
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), body)
+	if err == nil {
+		// CreateTemp uses 0600; committed files are readable like those os.WriteFile creates
+		err = file.Chmod(0644)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	var info os.FileInfo
+	if err == nil {
+		info, err = s.fs.Stat(file.Name())
+	}
+	if err != nil {
+		s.fs.Remove(file.Name())
+		return stagedFile{}, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return stagedFile{path: file.Name(), size: size, checksum: hex.EncodeToString(hash.Sum(nil)), info: info}, nil
+}
+
+// stagingFailure converts a stageFile error into a response
+func stagingFailure(err error) FileWriteResponse {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("file exceeds the upload limit of %d bytes", tooLarge.Limit),
+			ErrorKind: "too_large",
+		}
+	}
+	var overQuota *quotaError
+	if errors.As(err, &overQuota) {
+		return fileLargerThanQuota(overQuota.limit)
+	}
+	return FileWriteResponse{
+		Status:    "error",
+		Reason:    err.Error(),
+		ErrorKind: "internal",
+	}
+}
+
+// commitFile moves a staged file to relativePath according to mode. Create and version link the
+// staged file, which like O_EXCL fails when the target exists, so an existing file is never
+// replaced; overwrite and append rename over it. Either way the target is never seen half written.
+func (s *FilesService) commitFile(mode, relativePath, ifMatch string, staged stagedFile) FileWriteResponse {
+	defer s.lockCommit(relativePath)()
+
+	switch mode {
+	case FileModeCreate:
+		if failure, exceeded := s.exceedsQuota(relativePath, false, staged.size); exceeded {
+			return failure
+		}
+		if err := s.fs.Link(staged.path, relativePath); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return FileWriteResponse{
+					Status:    "error",
+					Reason:    "file already exists",
+					ErrorKind: "conflict",
+				}
+			}
+			return FileWriteResponse{
+				Status:    "error",
+				Reason:    fmt.Sprintf("failed to create file: %v", err),
+				ErrorKind: "internal",
+			}
+		}
+		s.syncDir(filepath.Dir(relativePath))
+		return s.committed(relativePath, true, staged)
+
+	case FileModeVersion:
+		if failure, exceeded := s.exceedsQuota(relativePath, false, staged.size); exceeded {
+			return failure
+		}
+		for n := 1; n <= maxFileVersions; n++ {
+			candidate := versionedPath(relativePath, n)
+			err := s.fs.Link(staged.path, candidate)
+			if err == nil {
+				s.syncDir(filepath.Dir(candidate))
+				return s.committed(candidate, true, staged)
+			}
+			if !errors.Is(err, os.ErrExist) {
+				return FileWriteResponse{
+					Status:    "error",
+					Reason:    fmt.Sprintf("failed to create file: %v", err),
+					ErrorKind: "internal",
+				}
+			}
+		}
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("file already has %d versions", maxFileVersions),
+			ErrorKind: "conflict",
+		}
+	}
+
+	// Overwrite and append replace the current file, optionally only if it is the one the client saw.
+	// The file is only hashed when its checksum is compared.
+	existed, err := s.fileExists(relativePath)
+	current := ""
+	if err == nil && existed && ifMatch != "" && ifMatch != "*" {
+		current, _, err = s.fileChecksum(relativePath)
+	}
+	if err != nil {
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to read current file: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+	if ifMatch != "" && (!existed || (ifMatch != "*" && ifMatch != current)) {
+		reason := "file does not exist"
+		if existed {
+			reason = fmt.Sprintf("file has changed; its SHA-256 is %s", current)
+		}
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    reason,
+			ErrorKind: "precondition_failed",
+		}
+	}
+
+	if mode == FileModeAppend && existed {
+		combined, err := s.appendStaged(relativePath, staged)
+		if err != nil {
+			return stagingFailure(err)
+		}
+		defer s.fs.Remove(combined.path)
+		staged = combined
+	}
+	if failure, exceeded := s.exceedsQuota(relativePath, existed, staged.size); exceeded {
+		return failure
+	}
+
+	if err := s.fs.Rename(staged.path, relativePath); err != nil {
+		return FileWriteResponse{
+			Status:    "error",
+			Reason:    fmt.Sprintf("failed to replace file: %v", err),
+			ErrorKind: "internal",
+		}
+	}
+	s.syncDir(filepath.Dir(relativePath))
+	return s.committed(relativePath, !existed, staged)
+}
+
+// syncDir flushes the directory entry of a committed file; its content was synced when staged.
+// The file is already in place, so a failure is only logged.
+func (s *FilesService) syncDir(dir string) {
+	if err := s.fs.SyncDir(dir); err != nil {
+		slog.Warn("⚠️ Failed to sync directory after writing a file",
+			slog.String("dir", dir),
+			slog.String("error", err.Error()))
+	}
+}
+
+// committed caches the checksum of a committed file and returns the response reporting it
+func (s *FilesService) committed(relativePath string, created bool, staged stagedFile) FileWriteResponse {
+	s.cacheChecksum(relativePath, staged.info, staged.checksum)
+	return FileWriteResponse{
+		Status:  "ok",
+		Created: created,
+		Path:    relativePath,
+		Size:    staged.size,
+		SHA256:  staged.checksum,
+	}
+}
+
+// appendStaged stages the current file followed by the staged content
+func (s *FilesService) appendStaged(relativePath string, staged stagedFile) (stagedFile, error) {
+	current, err := s.fs.Open(relativePath)
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to read current file: %w", err)
+	}
+	defer current.Close()
+
+	addition, err := s.fs.Open(staged.path)
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to read staged file: %w", err)
+	}
+	defer addition.Close()
+
+	return s.stageFile(filepath.Dir(relativePath), io.MultiReader(current, addition))
+}
+
+// fileExists reports whether a file is at path
+func (s *FilesService) fileExists(path string) (bool, error) {
+	_, err := s.fs.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lockCommit locks relativePath for a commit or delete and returns the function that unlocks it.
+// A client with a quota also holds its quota lock, since all of its commits are checked against
+// the same usage; it is always taken before the path lock.
+func (s *FilesService) lockCommit(relativePath string) func() {
+	var unlockQuota func()
+	if s.scope.MaxBytes > 0 || s.scope.MaxFiles > 0 {
+		unlockQuota = s.locks.lock("quota:" + s.scope.ClientID)
+	}
+	unlockPath := s.locks.lock(filepath.Join(s.dir, relativePath))
+	return func() {
+		unlockPath()
+		if unlockQuota != nil {
+			unlockQuota()
+		}
+	}
+}
+
+// pathLocks is a set of mutexes by key, each existing only while it is held or waited for
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.Mutex
+	refs int // holders and waiters
+}
+
+// lock locks key and returns the function that unlocks it
+func (l *pathLocks) lock(key string) func() {
+	l.mu.Lock()
+	entry, ok := l.locks[key]
+	if !ok {
+		entry = &pathLock{}
+		l.locks[key] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// versionedPath returns the nth candidate name of the version mode: the path itself,
+// then "note (2).md", "note (3).md" and so on
+func versionedPath(path string, n int) string {
+	if n == 1 {
+		return path
+	}
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	if ext == name {
+		// Dotfiles such as ".env" have no extension to keep
+		ext = ""
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext))
+}
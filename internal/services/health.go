@@ -5,27 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"omnidrop/internal/config"
 )
 
-// DefaultAppleScriptExecutor provides the default implementation for AppleScript execution
-type DefaultAppleScriptExecutor struct{}
-
-func (e *DefaultAppleScriptExecutor) Execute(ctx context.Context, script string, args ...string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, "osascript", append([]string{script}, args...)...)
-	return cmd.CombinedOutput()
-}
-
-// ExecuteSimple executes a simple AppleScript command
-func (e *DefaultAppleScriptExecutor) ExecuteSimple(ctx context.Context, script string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, "osascript", "-e", script)
-	return cmd.CombinedOutput()
-}
-
 // HealthServiceImpl implements the HealthService interface
 type HealthServiceImpl struct {
 	config   *config.Config
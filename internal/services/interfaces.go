@@ -2,26 +2,151 @@ package services
 
 import (
 	"context"
+	"time"
 )
 
-// TaskCreateRequest represents a request to create a task in OmniFocus
+// TaskCreateRequest represents a request to create a task in a task backend
+// The JSON tags define the format persisted by the task outbox.
 type TaskCreateRequest struct {
-	Title   string
-	Note    string
-	Project string
-	Tags    []string
+	Backend          string              `json:"backend,omitempty"`     // registered backend name; empty selects the default
+	DeliveryID       string              `json:"delivery_id,omitempty"` // same for every attempt to create the task, so a backend can recognize a retry
+	Title            string              `json:"title"`
+	Note             string              `json:"note,omitempty"`
+	Project          string              `json:"project,omitempty"`
+	Tags             []string            `json:"tags,omitempty"`
+	DueDate          *time.Time          `json:"due_date,omitempty"`   // nil keeps the script default (today at 18:00)
+	DeferDate        *time.Time          `json:"defer_date,omitempty"` // nil leaves the task available immediately
+	EstimatedMinutes int                 `json:"estimated_minutes,omitempty"`
+	Flagged          bool                `json:"flagged,omitempty"`
+	Sequential       bool                `json:"sequential,omitempty"` // children must be completed in order
+	Children         []TaskCreateRequest `json:"children,omitempty"`   // subtasks; makes this task an action group
+}
+
+// TaskCount returns the number of tasks in the hierarchy rooted at r, including r itself
+func (r TaskCreateRequest) TaskCount() int {
+	count := 1
+	for _, child := range r.Children {
+		count += child.TaskCount()
+	}
+	return count
 }
 
 // TaskCreateResponse represents the response from creating a task
 type TaskCreateResponse struct {
-	Status  string
-	Created bool
-	Reason  string
+	Status   string
+	Created  bool
+	ID       string // backend's persistent ID for the created task, when it reports one
+	Reason   string
+	Warnings []string // problems that did not prevent creation, e.g. a tag that could not be assigned
+	Err      error    // typed cause of an "error" status when the backend reports one, e.g. *ScriptError
+}
+
+// TaskBackend defines a destination that tasks can be created in
+type TaskBackend interface {
+	CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse
+	CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse
+}
+
+// TaskQuery filters the tasks returned by TaskReader.ListTasks. Nil and empty fields match every task.
+type TaskQuery struct {
+	Project   string     // containing project name, case-insensitive
+	Tag       string     // tag name, case-insensitive
+	Flagged   *bool      // nil matches flagged and unflagged tasks
+	Completed *bool      // nil matches completed and remaining tasks
+	DueBefore *time.Time // tasks due strictly before this time
+	DueAfter  *time.Time // tasks due strictly after this time
+	Search    string     // case-insensitive substring of the title or note
+	Inbox     bool       // only inbox tasks
+	Limit     int        // maximum number of tasks; 0 returns all matches
+}
+
+// Task is a task read back from a task backend
+type Task struct {
+	ID               string     `json:"id"`
+	Title            string     `json:"title"`
+	Note             string     `json:"note,omitempty"`
+	Project          string     `json:"project,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	Flagged          bool       `json:"flagged"`
+	Completed        bool       `json:"completed"`
+	DueDate          *time.Time `json:"due_date,omitempty"`
+	DeferDate        *time.Time `json:"defer_date,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	EstimatedMinutes int        `json:"estimated_minutes,omitempty"`
+	ParentID         string     `json:"parent_id,omitempty"` // set for subtasks of an action group
+	InInbox          bool       `json:"in_inbox"`
+}
+
+// TaskListResponse represents the result of listing tasks
+type TaskListResponse struct {
+	Status string
+	Tasks  []Task
+	Reason string
+	Err    error // cause of an "error" status when there is one, e.g. ErrExecutorBusy
+}
+
+// TaskGetResponse represents the result of looking up a single task.
+// Found is false when the backend answered but has no task with the requested ID.
+type TaskGetResponse struct {
+	Status string
+	Found  bool
+	Task   Task
+	Reason string
+	Err    error // cause of an "error" status when there is one, e.g. ErrExecutorBusy
+}
+
+// TaskReader is implemented by task backends that can read tasks back
+type TaskReader interface {
+	ListTasks(ctx context.Context, query TaskQuery) TaskListResponse
+	GetTask(ctx context.Context, id string) TaskGetResponse
+}
+
+// TaskUpdateRequest changes fields of an existing task. Nil fields are left unchanged.
+type TaskUpdateRequest struct {
+	Title          *string
+	Note           *string
+	Project        *string    // moves the task to this project
+	Tags           *[]string  // replaces every tag; an empty slice removes them all
+	DueDate        *time.Time // new due date; see ClearDueDate
+	ClearDueDate   bool       // removes the due date; ignored when DueDate is set
+	DeferDate      *time.Time // new defer date; see ClearDeferDate
+	ClearDeferDate bool       // removes the defer date; ignored when DeferDate is set
+	Flagged        *bool
+}
+
+// TaskActionResponse represents the result of updating, completing or deleting a task.
+// Found is false when the backend answered but has no task with the requested ID.
+type TaskActionResponse struct {
+	Status   string
+	Found    bool
+	Reason   string
+	Warnings []string
+	Err      error // typed cause of an "error" status when the backend reports one, e.g. *ScriptError
+}
+
+// TaskUpdater is implemented by task backends that can change tasks after creating them
+type TaskUpdater interface {
+	UpdateTask(ctx context.Context, id string, req TaskUpdateRequest) TaskActionResponse
+	CompleteTask(ctx context.Context, id string) TaskActionResponse
+	DeleteTask(ctx context.Context, id string) TaskActionResponse
+}
+
+// CircuitReporter is implemented by task backends that may sit behind a circuit breaker.
+// CircuitState returns "" when the backend has none.
+type CircuitReporter interface {
+	CircuitState() CircuitState
 }
 
 // OmniFocusServiceInterface defines the interface for OmniFocus operations
 type OmniFocusServiceInterface interface {
-	CreateTask(ctx context.Context, req TaskCreateRequest) TaskCreateResponse
+	TaskBackend
+	TaskReader
+	TaskUpdater
+}
+
+// TaskQueue accepts tasks that could not be delivered immediately and retries them later
+type TaskQueue interface {
+	Enqueue(ctx context.Context, req TaskCreateRequest) (string, error)
 }
 
 // HealthService defines the interface for system health checks
@@ -30,6 +155,11 @@ type HealthService interface {
 	CheckOmniFocusStatus() bool
 }
 
+// ReadinessChecker reports whether the server and its dependencies can serve requests
+type ReadinessChecker interface {
+	Check() ReadinessReport
+}
+
 // AppleScriptExecutor defines the interface for AppleScript execution
 // This allows for easy mocking in tests
 type AppleScriptExecutor interface {
@@ -2,9 +2,9 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -13,19 +13,40 @@ import (
 )
 
 type OmniFocusService struct {
-	cfg *config.Config
+	cfg      *config.Config
+	executor AppleScriptExecutor
 }
 
 // Ensure OmniFocusService implements OmniFocusServiceInterface
 var _ OmniFocusServiceInterface = (*OmniFocusService)(nil)
 
+// NewOmniFocusService creates a new OmniFocus service with the default AppleScript executor
 func NewOmniFocusService(cfg *config.Config) *OmniFocusService {
+	return NewOmniFocusServiceWithExecutor(cfg, &DefaultAppleScriptExecutor{})
+}
+
+// NewOmniFocusServiceWithExecutor creates a new OmniFocus service with a custom executor (for testing)
+func NewOmniFocusServiceWithExecutor(cfg *config.Config, executor AppleScriptExecutor) *OmniFocusService {
 	return &OmniFocusService{
-		cfg: cfg,
+		cfg:      cfg,
+		executor: executor,
+	}
+}
+
+// CircuitState reports the state of the circuit breaker in front of the executor, or "" when there is none
+func (s *OmniFocusService) CircuitState() CircuitState {
+	if breaker, ok := s.executor.(*CircuitBreakerExecutor); ok {
+		return breaker.State()
 	}
+	return ""
 }
 
 func (s *OmniFocusService) CreateTask(ctx context.Context, req TaskCreateRequest) (resp TaskCreateResponse) {
+	// Task hierarchies need the batch protocol to link subtasks to their parent
+	if len(req.Children) > 0 {
+		return s.CreateTasks(ctx, []TaskCreateRequest{req})[0]
+	}
+
 	start := time.Now()
 	defer func() {
 		label := "success"
@@ -45,38 +66,29 @@ func (s *OmniFocusService) CreateTask(ctx context.Context, req TaskCreateRequest
 		}
 	}
 
-	// Sanitize inputs to prevent AppleScript injection via string terminators
-	title := sanitizeAppleScriptArg(req.Title)
-	note := sanitizeAppleScriptArg(req.Note)
-	project := sanitizeAppleScriptArg(req.Project)
-	tagsString := sanitizeAppleScriptArg(strings.Join(req.Tags, ","))
-
-	// Collect business metrics
-	if req.Project != "" {
-		observability.TasksWithProjectTotal.Inc()
-	}
-	if len(req.Tags) > 0 {
-		observability.TasksWithTagsTotal.Inc()
-	}
+	recordTaskFieldMetrics(req)
 
 	slog.Info("📝 Creating OmniFocus task",
 		slog.String("title", req.Title),
 		slog.String("script_path", scriptPath),
 		slog.String("note", req.Note),
 		slog.String("project", req.Project),
-		slog.String("tags", tagsString))
+		slog.String("tags", strings.Join(req.Tags, ",")),
+		slog.String("due_date", formatAppleScriptDate(req.DueDate)),
+		slog.String("defer_date", formatAppleScriptDate(req.DeferDate)),
+		slog.Int("estimated_minutes", req.EstimatedMinutes),
+		slog.Bool("flagged", req.Flagged))
 
-	// Execute AppleScript with direct arguments
+	// Pass the task to the script as a JSON payload
 	scriptStart := time.Now()
-	cmd := exec.CommandContext(ctx, "osascript", scriptPath, title, note, project, tagsString)
-	output, err := cmd.CombinedOutput()
+	output, err := s.executePayload(ctx, scriptPath, scriptPayload{
+		Mode:  payloadModeCreate,
+		Tasks: []payloadTask{newPayloadTask(req, 0)},
+	})
 	observability.AppleScriptExecutionDuration.Observe(time.Since(scriptStart).Seconds())
 
 	if err != nil {
-		errorType := "runtime"
-		if strings.Contains(err.Error(), "compile") {
-			errorType = "compilation"
-		}
+		errorType := classifyExecutionError(ctx, err)
 		observability.AppleScriptErrorsTotal.WithLabelValues(errorType).Inc()
 		observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
 
@@ -88,6 +100,7 @@ func (s *OmniFocusService) CreateTask(ctx context.Context, req TaskCreateRequest
 		return TaskCreateResponse{
 			Status: "error",
 			Reason: fmt.Sprintf("AppleScript execution failed for task '%s': %v - Output: %s", req.Title, err, string(output)),
+			Err:    executionError(ctx, err, false),
 		}
 	}
 
@@ -95,6 +108,11 @@ func (s *OmniFocusService) CreateTask(ctx context.Context, req TaskCreateRequest
 	result := strings.TrimSpace(string(output))
 	slog.Info("📋 AppleScript result", slog.String("result", result))
 
+	if scriptResult, ok := lastScriptResult(result); ok {
+		return createResponseFromResult(req, scriptResult)
+	}
+
+	// Scripts that predate the result protocol print free text
 	if s.isSuccessResult(result) {
 		observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
 		slog.Info("✅ Task created successfully", slog.String("task_title", req.Title))
@@ -118,6 +136,42 @@ func (s *OmniFocusService) CreateTask(ctx context.Context, req TaskCreateRequest
 	}
 }
 
+// createResponseFromResult converts a protocol result for one task into a response
+func createResponseFromResult(req TaskCreateRequest, result scriptResult) TaskCreateResponse {
+	if failure := result.failure(); failure != nil {
+		recordScriptFailure(failure)
+		slog.Error("❌ Task creation failed",
+			slog.String("task_title", req.Title),
+			slog.String("error_code", string(result.ErrorCode)),
+			slog.String("message", result.Message))
+		return TaskCreateResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("AppleScript failed to create task '%s': %v", req.Title, failure),
+			Err:    failure,
+		}
+	}
+
+	observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+	for _, warning := range result.Warnings {
+		slog.Warn("⚠️ Task created with warning", slog.String("task_title", req.Title), slog.String("warning", warning))
+	}
+	slog.Info("✅ Task created successfully", slog.String("task_title", req.Title), slog.String("task_id", result.TaskID))
+	return TaskCreateResponse{
+		Status:   "ok",
+		Created:  true,
+		ID:       result.TaskID,
+		Warnings: result.Warnings,
+	}
+}
+
+// recordScriptFailure counts a failure the script reported through the result protocol
+func recordScriptFailure(failure *ScriptError) {
+	observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
+	observability.AppleScriptErrorsTotal.WithLabelValues(string(failure.Code)).Inc()
+}
+
+// isSuccessResult guesses whether free-text output from a script that predates the
+// result protocol reports success. Current scripts are parsed with lastScriptResult.
 func (s *OmniFocusService) isSuccessResult(result string) bool {
 	// Define success patterns (case-insensitive)
 	successPatterns := []string{"true", "ok", "success", "created", "done"}
@@ -151,11 +205,52 @@ func (s *OmniFocusService) isSuccessResult(result string) bool {
 	return false
 }
 
-// sanitizeAppleScriptArg removes characters that could be used for AppleScript injection.
-// Since arguments are passed via osascript argv (not shell), the main risk is AppleScript
-// string terminators (double quotes and backslashes) that could break out of string context.
-func sanitizeAppleScriptArg(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "\"", "\\\"")
-	return s
+// executionError wraps an executor failure in ErrOutcomeUnknown when the script may already
+// have changed OmniFocus: osascript was killed at the deadline or on cancellation, or it
+// printed results (partial) before it failed. Scripts refused by the pool or the circuit
+// breaker never ran.
+func executionError(ctx context.Context, err error, partial bool) error {
+	if errors.Is(err, ErrExecutorBusy) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, errNotStarted) {
+		return err
+	}
+	if partial || ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", ErrOutcomeUnknown, err)
+	}
+	return err
+}
+
+// classifyExecutionError maps an executor failure to an AppleScriptErrorsTotal label
+func classifyExecutionError(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrExecutorBusy):
+		return "busy"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case strings.Contains(err.Error(), "compile"):
+		return "compilation"
+	default:
+		return "runtime"
+	}
+}
+
+// recordTaskFieldMetrics collects business metrics about the requested task fields
+func recordTaskFieldMetrics(req TaskCreateRequest) {
+	if req.Project != "" {
+		observability.TasksWithProjectTotal.Inc()
+	}
+	if len(req.Tags) > 0 {
+		observability.TasksWithTagsTotal.Inc()
+	}
+}
+
+// formatAppleScriptDate renders t in the host's local time as "YYYY-MM-DD HH:MM:SS",
+// the layout parseDateTime in omnidrop.applescript expects. OmniFocus runs on the
+// same machine, so local time is what its date objects use. Nil yields "".
+func formatAppleScriptDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(time.Local).Format("2006-01-02 15:04:05")
 }
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"omnidrop/internal/observability"
+)
+
+// batchNode is one task of a flattened task hierarchy
+type batchNode struct {
+	req    TaskCreateRequest
+	parent int // 1-based index of the parent node, 0 for top-level tasks
+	root   int // index of the top-level request this node belongs to
+}
+
+// CreateTasks creates several tasks, including any subtasks, with a single osascript invocation.
+// The returned slice has one response per top-level request, in the same order; a failure
+// of one task does not prevent the others from being created.
+func (s *OmniFocusService) CreateTasks(ctx context.Context, reqs []TaskCreateRequest) []TaskCreateResponse {
+	start := time.Now()
+	nodes := flattenTasks(reqs)
+	nodeResponses := make([]TaskCreateResponse, len(nodes))
+	defer func() {
+		for _, resp := range nodeResponses {
+			label := "success"
+			if resp.Status == "error" {
+				label = "failure"
+			}
+			observability.TaskCreationsTotal.WithLabelValues(label).Inc()
+		}
+		observability.TaskCreationDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	if len(reqs) == 0 {
+		return []TaskCreateResponse{}
+	}
+
+	// Get AppleScript path with environment-based resolution
+	scriptPath, err := s.cfg.GetAppleScriptPath()
+	if err != nil {
+		failAll(nodeResponses, fmt.Sprintf("failed to resolve AppleScript path: %v", err), nil)
+		return summarizeRoots(nodes, nodeResponses, len(reqs))
+	}
+
+	payload := scriptPayload{Mode: payloadModeBatch, Tasks: make([]payloadTask, 0, len(nodes))}
+	for _, node := range nodes {
+		payload.Tasks = append(payload.Tasks, newPayloadTask(node.req, node.parent))
+		recordTaskFieldMetrics(node.req)
+	}
+
+	slog.Info("📝 Creating OmniFocus task batch",
+		slog.Int("count", len(reqs)),
+		slog.Int("total_tasks", len(nodes)),
+		slog.String("script_path", scriptPath))
+
+	scriptStart := time.Now()
+	output, err := s.executePayload(ctx, scriptPath, payload)
+	observability.AppleScriptExecutionDuration.Observe(time.Since(scriptStart).Seconds())
+
+	if err != nil {
+		errorType := classifyExecutionError(ctx, err)
+		observability.AppleScriptErrorsTotal.WithLabelValues(errorType).Inc()
+		observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
+
+		slog.Error("❌ AppleScript batch execution failed",
+			slog.Int("count", len(reqs)),
+			slog.String("error", err.Error()),
+			slog.String("output", string(output)))
+
+		// Keep the results the script printed before it failed; the remaining tasks may or may not exist
+		reported := parseBatchOutput(string(output), nodeResponses)
+		fillUnreported(nodeResponses, reported, TaskCreateResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("AppleScript batch execution failed: %v - Output: %s", err, string(output)),
+			Err:    executionError(ctx, err, slices.Contains(reported, true)),
+		})
+		return summarizeRoots(nodes, nodeResponses, len(reqs))
+	}
+
+	observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+	reported := parseBatchOutput(string(output), nodeResponses)
+	fillUnreported(nodeResponses, reported, TaskCreateResponse{
+		Status: "error",
+		Reason: "AppleScript reported no result for this task",
+		Err:    ErrOutcomeUnknown,
+	})
+
+	for i, resp := range nodeResponses {
+		if resp.Status == "error" {
+			slog.Error("❌ Batch task creation failed",
+				slog.Int("index", i),
+				slog.String("task_title", nodes[i].req.Title),
+				slog.String("reason", resp.Reason))
+		}
+	}
+	slog.Info("✅ Task batch processed", slog.Int("count", len(reqs)), slog.Int("total_tasks", len(nodes)))
+
+	return summarizeRoots(nodes, nodeResponses, len(reqs))
+}
+
+// flattenTasks lists every task in pre-order so parents always precede their children
+func flattenTasks(reqs []TaskCreateRequest) []batchNode {
+	var nodes []batchNode
+	var walk func(req TaskCreateRequest, parent, root int)
+	walk = func(req TaskCreateRequest, parent, root int) {
+		nodes = append(nodes, batchNode{req: req, parent: parent, root: root})
+		self := len(nodes)
+		for _, child := range req.Children {
+			walk(child, self, root)
+		}
+	}
+	for i, req := range reqs {
+		walk(req, 0, i)
+	}
+	return nodes
+}
+
+// summarizeRoots folds per-node results into one response per top-level request.
+// A created parent whose subtasks partly failed is still reported as created, with
+// the failed subtasks listed in Reason, so it is not queued and duplicated.
+// Warnings of subtasks are added to the created parent's warnings.
+func summarizeRoots(nodes []batchNode, nodeResponses []TaskCreateResponse, rootCount int) []TaskCreateResponse {
+	responses := make([]TaskCreateResponse, rootCount)
+	failures := make([][]string, rootCount)
+	subtaskWarnings := make([][]string, rootCount)
+
+	for i, node := range nodes {
+		if node.parent == 0 {
+			responses[node.root] = nodeResponses[i]
+			continue
+		}
+		for _, warning := range nodeResponses[i].Warnings {
+			subtaskWarnings[node.root] = append(subtaskWarnings[node.root],
+				fmt.Sprintf("subtask '%s': %s", node.req.Title, warning))
+		}
+		if nodeResponses[i].Status == "error" {
+			failures[node.root] = append(failures[node.root],
+				fmt.Sprintf("subtask '%s': %s", node.req.Title, nodeResponses[i].Reason))
+		}
+	}
+
+	for i := range responses {
+		if !responses[i].Created {
+			continue
+		}
+		responses[i].Warnings = append(responses[i].Warnings, subtaskWarnings[i]...)
+		if len(failures[i]) > 0 {
+			responses[i].Reason = fmt.Sprintf("%d subtask(s) could not be created: %s",
+				len(failures[i]), strings.Join(failures[i], "; "))
+		}
+	}
+	return responses
+}
+
+// parseBatchOutput fills per-task responses from the result objects printed in batch mode,
+// ignoring any other (log) output, and reports which tasks had a result
+func parseBatchOutput(output string, responses []TaskCreateResponse) []bool {
+	reported := make([]bool, len(responses))
+
+	for _, line := range strings.Split(output, "\n") {
+		result, ok := parseScriptResult(line)
+		if !ok || result.Index < 1 || result.Index > len(responses) {
+			continue
+		}
+		reported[result.Index-1] = true
+		responses[result.Index-1] = batchResponseFromResult(result)
+	}
+
+	return reported
+}
+
+// fillUnreported sets the response of every task without a reported result to missing
+func fillUnreported(responses []TaskCreateResponse, reported []bool, missing TaskCreateResponse) {
+	for i := range responses {
+		if !reported[i] {
+			responses[i] = missing
+		}
+	}
+}
+
+func batchResponseFromResult(result scriptResult) TaskCreateResponse {
+	if failure := result.failure(); failure != nil {
+		observability.AppleScriptErrorsTotal.WithLabelValues(string(failure.Code)).Inc()
+		return TaskCreateResponse{Status: "error", Reason: failure.Message, Err: failure}
+	}
+	return TaskCreateResponse{Status: "ok", Created: true, ID: result.TaskID, Warnings: result.Warnings}
+}
+
+func failAll(responses []TaskCreateResponse, reason string, err error) {
+	for i := range responses {
+		responses[i] = TaskCreateResponse{Status: "error", Reason: reason, Err: err}
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"omnidrop/internal/observability"
+)
+
+const (
+	// queryTaskFieldCount is the number of tab-separated fields in a TASK line of omnidrop-query.applescript
+	queryTaskFieldCount = 14
+	// queryNotFound is printed by the query script's "get" mode when no task has the requested ID
+	queryNotFound = "NOTFOUND"
+	// queryTagSeparator separates tag names within the tags field (ASCII unit separator)
+	queryTagSeparator = "\x1f"
+	// queryDateLayout is the local-time layout the query script uses for dates
+	queryDateLayout = "2006-01-02 15:04:05"
+)
+
+// ListTasks runs the query script and returns the tasks matching query. The script narrows the
+// candidates by inbox, completion and flag; the remaining filters are applied here.
+func (s *OmniFocusService) ListTasks(ctx context.Context, query TaskQuery) TaskListResponse {
+	completed := "any"
+	if query.Completed != nil {
+		completed = strconv.FormatBool(*query.Completed)
+	}
+	flagged := "any"
+	if query.Flagged != nil && *query.Flagged {
+		flagged = "true"
+	}
+
+	output, err := s.runQueryScript(ctx, "list", strconv.FormatBool(query.Inbox), completed, flagged)
+	if err != nil {
+		return TaskListResponse{Status: "error", Reason: err.Error(), Err: err}
+	}
+
+	tasks, err := parseQueryOutput(output)
+	if err != nil {
+		observability.AppleScriptErrorsTotal.WithLabelValues("unknown").Inc()
+		return TaskListResponse{Status: "error", Reason: err.Error()}
+	}
+
+	matched := make([]Task, 0, len(tasks))
+	for _, task := range tasks {
+		if !query.Matches(task) {
+			continue
+		}
+		matched = append(matched, task)
+		if query.Limit > 0 && len(matched) == query.Limit {
+			break
+		}
+	}
+
+	slog.Info("🔎 Listed OmniFocus tasks",
+		slog.Int("candidates", len(tasks)),
+		slog.Int("matched", len(matched)))
+
+	return TaskListResponse{Status: "ok", Tasks: matched}
+}
+
+// GetTask looks up a single task by its OmniFocus ID
+func (s *OmniFocusService) GetTask(ctx context.Context, id string) TaskGetResponse {
+	output, err := s.runQueryScript(ctx, "get", id)
+	if err != nil {
+		return TaskGetResponse{Status: "error", Reason: err.Error(), Err: err}
+	}
+
+	if strings.TrimSpace(output) == queryNotFound {
+		return TaskGetResponse{Status: "ok", Found: false}
+	}
+
+	tasks, err := parseQueryOutput(output)
+	if err != nil {
+		observability.AppleScriptErrorsTotal.WithLabelValues("unknown").Inc()
+		return TaskGetResponse{Status: "error", Reason: err.Error()}
+	}
+	if len(tasks) != 1 {
+		return TaskGetResponse{Status: "error", Reason: fmt.Sprintf("query script returned %d tasks for id '%s'", len(tasks), id)}
+	}
+
+	return TaskGetResponse{Status: "ok", Found: true, Task: tasks[0]}
+}
+
+// runQueryScript executes omnidrop-query.applescript and returns its output
+func (s *OmniFocusService) runQueryScript(ctx context.Context, args ...string) (string, error) {
+	scriptPath, err := s.cfg.GetQueryScriptPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve query script path: %v", err)
+	}
+
+	scriptStart := time.Now()
+	output, err := s.executor.Execute(ctx, scriptPath, args...)
+	observability.AppleScriptExecutionDuration.Observe(time.Since(scriptStart).Seconds())
+
+	if err != nil {
+		observability.AppleScriptErrorsTotal.WithLabelValues(classifyExecutionError(ctx, err)).Inc()
+		observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
+
+		slog.Error("❌ Query script execution failed",
+			slog.String("mode", args[0]),
+			slog.String("error", err.Error()),
+			slog.String("output", string(output)))
+
+		return "", fmt.Errorf("query script execution failed: %w - Output: %s", err, string(output))
+	}
+
+	observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+	return string(output), nil
+}
+
+// Matches reports whether task satisfies every filter in the query. Limit is not considered.
+func (q TaskQuery) Matches(task Task) bool {
+	if q.Inbox && !task.InInbox {
+		return false
+	}
+	if q.Project != "" && !strings.EqualFold(task.Project, q.Project) {
+		return false
+	}
+	if q.Tag != "" && !containsFold(task.Tags, q.Tag) {
+		return false
+	}
+	if q.Flagged != nil && task.Flagged != *q.Flagged {
+		return false
+	}
+	if q.Completed != nil && task.Completed != *q.Completed {
+		return false
+	}
+	if q.DueBefore != nil && (task.DueDate == nil || !task.DueDate.Before(*q.DueBefore)) {
+		return false
+	}
+	if q.DueAfter != nil && (task.DueDate == nil || !task.DueDate.After(*q.DueAfter)) {
+		return false
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(task.Title), search) && !strings.Contains(strings.ToLower(task.Note), search) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQueryOutput parses the TASK lines printed by omnidrop-query.applescript.
+// Blank lines are ignored; any other line is an error.
+func parseQueryOutput(output string) ([]Task, error) {
+	var tasks []Task
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		task, err := parseQueryTask(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query script output on line %d: %v", i+1, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func parseQueryTask(line string) (Task, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != queryTaskFieldCount || fields[0] != "TASK" {
+		return Task{}, fmt.Errorf("expected %d TASK fields, got %d", queryTaskFieldCount, len(fields))
+	}
+
+	task := Task{
+		ID:        unescapeQueryField(fields[1]),
+		Title:     unescapeQueryField(fields[2]),
+		Note:      unescapeQueryField(fields[3]),
+		Project:   unescapeQueryField(fields[4]),
+		Flagged:   fields[6] == "true",
+		Completed: fields[7] == "true",
+		ParentID:  unescapeQueryField(fields[12]),
+		InInbox:   fields[13] == "true",
+	}
+
+	if fields[5] != "" {
+		for _, tag := range strings.Split(fields[5], queryTagSeparator) {
+			task.Tags = append(task.Tags, unescapeQueryField(tag))
+		}
+	}
+
+	var err error
+	if task.DueDate, err = parseQueryDate(fields[8]); err != nil {
+		return Task{}, fmt.Errorf("due date: %v", err)
+	}
+	if task.DeferDate, err = parseQueryDate(fields[9]); err != nil {
+		return Task{}, fmt.Errorf("defer date: %v", err)
+	}
+	if task.CompletedAt, err = parseQueryDate(fields[10]); err != nil {
+		return Task{}, fmt.Errorf("completion date: %v", err)
+	}
+
+	if fields[11] != "" {
+		minutes, err := strconv.Atoi(fields[11])
+		if err != nil {
+			return Task{}, fmt.Errorf("estimated minutes: %v", err)
+		}
+		task.EstimatedMinutes = minutes
+	}
+
+	return task, nil
+}
+
+// parseQueryDate parses a query script date, which is in the host's local time like the
+// dates formatAppleScriptDate produces. An empty field yields nil.
+func parseQueryDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(queryDateLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// unescapeQueryField reverses the query script's escaping of backslash, tab, line feed and return
+func unescapeQueryField(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+
+	var b strings.Builder
+	b.Grow(len(value))
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i == len(value)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,606 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/config"
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+func newTestOmniFocusConfig(t *testing.T) *config.Config {
+	t.Helper()
+	scriptPath := filepath.Join(t.TempDir(), "omnidrop.applescript")
+	require.NoError(t, os.WriteFile(scriptPath, []byte("return \"success\""), 0644))
+	return &config.Config{
+		Environment:     "test",
+		ScriptPath:      scriptPath,
+		AppleScriptFile: "omnidrop.applescript",
+	}
+}
+
+// testPayload is the JSON document the service passes to the script in payload mode
+type testPayload struct {
+	Version int              `json:"version"`
+	Mode    string           `json:"mode"`
+	Tasks   []map[string]any `json:"tasks"`
+	TaskID  string           `json:"task_id"`
+	Changes map[string]any   `json:"changes"`
+}
+
+// decodePayload checks that call ran the script in payload mode and decodes its payload
+func decodePayload(t *testing.T, call mocks.ExecutorCall) testPayload {
+	t.Helper()
+	require.Len(t, call.Args, 2)
+	require.Equal(t, "--payload", call.Args[0])
+	require.NotEmpty(t, call.Payload, "payload file was empty or unreadable during the call")
+
+	var payload testPayload
+	require.NoError(t, json.Unmarshal(call.Payload, &payload))
+	assert.Equal(t, services.ScriptPayloadVersion, payload.Version)
+	return payload
+}
+
+func TestOmniFocusService_CreateTask_Success(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success("success"))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:   "Buy milk",
+		Note:    "2 litres",
+		Project: "Home/Errands",
+		Tags:    []string{"shopping", "urgent"},
+	})
+
+	assert.Equal(t, "ok", resp.Status)
+	assert.True(t, resp.Created)
+
+	call := executor.LastCall()
+	assert.Equal(t, cfg.ScriptPath, call.Script)
+	payload := decodePayload(t, call)
+	assert.Equal(t, "create", payload.Mode)
+	require.Len(t, payload.Tasks, 1)
+	assert.Equal(t, map[string]any{
+		"title":             "Buy milk",
+		"note":              "2 litres",
+		"project":           "Home/Errands",
+		"tags":              []any{"shopping", "urgent"},
+		"due_date":          "",
+		"defer_date":        "",
+		"estimated_minutes": float64(0),
+		"flagged":           false,
+		"sequential":        false,
+		"parent":            float64(0),
+	}, payload.Tasks[0])
+
+	// The payload file only exists while the script runs
+	_, err := os.Stat(call.Args[1])
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOmniFocusService_CreateTask_SchedulingArguments(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor()
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	due := time.Date(2025, 10, 20, 18, 0, 0, 0, time.Local)
+	deferDate := time.Date(2025, 10, 19, 9, 30, 0, 0, time.Local)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:            "Ship release",
+		DueDate:          &due,
+		DeferDate:        &deferDate,
+		EstimatedMinutes: 45,
+		Flagged:          true,
+	})
+
+	assert.Equal(t, "ok", resp.Status)
+	task := decodePayload(t, executor.LastCall()).Tasks[0]
+	assert.Equal(t, "2025-10-20 18:00:00", task["due_date"])
+	assert.Equal(t, "2025-10-19 09:30:00", task["defer_date"])
+	assert.Equal(t, float64(45), task["estimated_minutes"])
+	assert.Equal(t, true, task["flagged"])
+}
+
+func TestOmniFocusService_CreateTask_PayloadKeepsValuesIntact(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor()
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	note := strings.Repeat("long note line\n", 20000)
+	service.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title: `say "hi" \ bye`,
+		Note:  note,
+		Tags:  []string{"Smith, John", "errands"},
+	})
+
+	// Quotes, backslashes and commas reach the script unchanged and big notes need no argv space
+	task := decodePayload(t, executor.LastCall()).Tasks[0]
+	assert.Equal(t, `say "hi" \ bye`, task["title"])
+	assert.Equal(t, note, task["note"])
+	assert.Equal(t, []any{"Smith, John", "errands"}, task["tags"])
+}
+
+func TestOmniFocusService_CreateTask_JavaScriptLanguage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "omnidrop.js"), []byte("function run(argv) {}"), 0644))
+	t.Chdir(dir)
+
+	cfg := &config.Config{
+		Environment:     "development",
+		ScriptLanguage:  config.ScriptLanguageJavaScript,
+		AppleScriptFile: "omnidrop.applescript",
+		JXAScriptFile:   "omnidrop.js",
+	}
+	executor := mocks.NewMockExecutor(mocks.Success(`{"version":1,"status":"ok","task_id":"abc","warnings":[]}`))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+
+	// The JXA script is resolved instead of the AppleScript and receives the same payload
+	assert.True(t, resp.Created, resp.Reason)
+	assert.Equal(t, "abc", resp.ID)
+	call := executor.LastCall()
+	assert.Equal(t, "omnidrop.js", call.Script)
+	assert.Equal(t, "create", decodePayload(t, call).Mode)
+}
+
+func TestOmniFocusService_CreateTask_ExecutionFailure(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Failure(errors.New("exit status 1")))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.False(t, resp.Created)
+	assert.Contains(t, resp.Reason, "exit status 1")
+}
+
+func TestOmniFocusService_CreateTask_PartialOutput(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.PartialOutput("Found existing tag: work\n", errors.New("signal: killed")))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "Found existing tag: work")
+}
+
+func TestOmniFocusService_CreateTask_Timeout(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Timeout())
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	resp := service.CreateTask(ctx, services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, context.DeadlineExceeded.Error())
+	// The script may have created the task before it was stopped
+	assert.ErrorIs(t, resp.Err, services.ErrOutcomeUnknown)
+	assert.False(t, services.Retryable(resp.Err))
+}
+
+func TestOmniFocusService_CreateTask_UnrecognizedOutput(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success("something unexpected"))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "something unexpected")
+}
+
+func TestOmniFocusService_CreateTask_ScriptNotFound(t *testing.T) {
+	cfg := &config.Config{
+		Environment: "test",
+		ScriptPath:  "/nonexistent/omnidrop.applescript",
+	}
+	executor := mocks.NewMockExecutor()
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Task"})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "failed to resolve AppleScript path")
+	assert.Equal(t, 0, executor.CallCount())
+}
+
+func TestOmniFocusService_CreateTasks_PartialFailure(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	output := "Found existing tag: work\n" +
+		`{"version":1,"index":1,"status":"ok"}` + "\n" +
+		`{"version":1,"index":2,"status":"error","error_code":"project_not_found","message":"Project not found: Nowhere"}` + "\n" +
+		`{"version":1,"index":3,"status":"ok"}`
+	executor := mocks.NewMockExecutor(mocks.Success(output))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	responses := service.CreateTasks(context.Background(), []services.TaskCreateRequest{
+		{Title: "One", Tags: []string{"work"}},
+		{Title: "Two", Project: "Nowhere"},
+		{Title: "Three"},
+	})
+
+	require.Len(t, responses, 3)
+	assert.True(t, responses[0].Created)
+	assert.Equal(t, "error", responses[1].Status)
+	assert.Equal(t, "Project not found: Nowhere", responses[1].Reason)
+	assert.True(t, responses[2].Created)
+
+	// One invocation carrying every task in a batch payload
+	assert.Equal(t, 1, executor.CallCount())
+	payload := decodePayload(t, executor.LastCall())
+	assert.Equal(t, "batch", payload.Mode)
+	require.Len(t, payload.Tasks, 3)
+	assert.Equal(t, "One", payload.Tasks[0]["title"])
+	assert.Equal(t, []any{"work"}, payload.Tasks[0]["tags"])
+	assert.Equal(t, "Two", payload.Tasks[1]["title"])
+	assert.Equal(t, "Three", payload.Tasks[2]["title"])
+}
+
+func TestOmniFocusService_CreateTask_WithSubtasks(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	output := `{"version":1,"index":1,"status":"ok"}` + "\n" +
+		`{"version":1,"index":2,"status":"ok"}` + "\n" +
+		`{"version":1,"index":3,"status":"error","error_code":"applescript_error","message":"Tag lookup failed"}` + "\n" +
+		`{"version":1,"index":4,"status":"ok"}`
+	executor := mocks.NewMockExecutor(mocks.Success(output))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:      "Plan trip",
+		Project:    "Travel",
+		Sequential: true,
+		Children: []services.TaskCreateRequest{
+			{Title: "Book flights", Children: []services.TaskCreateRequest{{Title: "Compare prices"}}},
+			{Title: "Pack"},
+		},
+	})
+
+	// The parent exists, so the task is reported as created with the failed subtask listed
+	assert.Equal(t, "ok", resp.Status)
+	assert.True(t, resp.Created)
+	assert.Contains(t, resp.Reason, "Compare prices")
+
+	// Tasks are sent in pre-order with 1-based parent indexes and the sequential flag
+	payload := decodePayload(t, executor.LastCall())
+	assert.Equal(t, "batch", payload.Mode)
+	require.Len(t, payload.Tasks, 4)
+	assert.Equal(t, []any{"Plan trip", float64(0), true}, []any{payload.Tasks[0]["title"], payload.Tasks[0]["parent"], payload.Tasks[0]["sequential"]})
+	assert.Equal(t, []any{"Book flights", float64(1), false}, []any{payload.Tasks[1]["title"], payload.Tasks[1]["parent"], payload.Tasks[1]["sequential"]})
+	assert.Equal(t, []any{"Compare prices", float64(2)}, []any{payload.Tasks[2]["title"], payload.Tasks[2]["parent"]})
+	assert.Equal(t, []any{"Pack", float64(1)}, []any{payload.Tasks[3]["title"], payload.Tasks[3]["parent"]})
+}
+
+func TestOmniFocusService_CreateTask_WithSubtasks_ParentFails(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	output := `{"version":1,"index":1,"status":"error","error_code":"project_not_found","message":"Project not found: Nowhere"}` + "\n" +
+		`{"version":1,"index":2,"status":"error","error_code":"parent_not_created","message":"Parent task was not created"}`
+	executor := mocks.NewMockExecutor(mocks.Success(output))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{
+		Title:    "Parent",
+		Project:  "Nowhere",
+		Children: []services.TaskCreateRequest{{Title: "Child"}},
+	})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.False(t, resp.Created)
+	assert.Equal(t, "Project not found: Nowhere", resp.Reason)
+}
+
+func TestOmniFocusService_CreateTasks_MissingResults(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success(`{"version":1,"index":1,"status":"ok"}`))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	responses := service.CreateTasks(context.Background(), []services.TaskCreateRequest{{Title: "One"}, {Title: "Two"}})
+
+	assert.True(t, responses[0].Created)
+	assert.Equal(t, "error", responses[1].Status)
+	assert.Contains(t, responses[1].Reason, "no result")
+	assert.ErrorIs(t, responses[1].Err, services.ErrOutcomeUnknown)
+}
+
+func TestOmniFocusService_CreateTasks_ExecutionFailure(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Failure(errors.New("exit status 1")))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	responses := service.CreateTasks(context.Background(), []services.TaskCreateRequest{{Title: "One"}, {Title: "Two"}})
+
+	for _, resp := range responses {
+		assert.Equal(t, "error", resp.Status)
+		assert.Contains(t, resp.Reason, "exit status 1")
+		// Nothing was reported, so the script failed before creating anything
+		assert.True(t, services.Retryable(resp.Err))
+	}
+}
+
+func TestOmniFocusService_CreateTasks_ExecutionFailureKeepsReportedResults(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	output := `{"version":1,"index":1,"status":"ok","task_id":"abc"}` + "\n" +
+		`{"version":1,"index":2,"status":"error","error_code":"project_not_found","message":"Project not found: Nowhere"}`
+	executor := mocks.NewMockExecutor(mocks.PartialOutput(output, errors.New("signal: killed")))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	responses := service.CreateTasks(context.Background(), []services.TaskCreateRequest{
+		{Title: "One"}, {Title: "Two", Project: "Nowhere"}, {Title: "Three"},
+	})
+
+	require.Len(t, responses, 3)
+	assert.True(t, responses[0].Created)
+	assert.Equal(t, "abc", responses[0].ID)
+	assert.Equal(t, "Project not found: Nowhere", responses[1].Reason)
+	assert.NotErrorIs(t, responses[1].Err, services.ErrOutcomeUnknown)
+
+	// The run stopped after reporting on some tasks; the rest may have been created
+	assert.Equal(t, "error", responses[2].Status)
+	assert.Contains(t, responses[2].Reason, "signal: killed")
+	assert.ErrorIs(t, responses[2].Err, services.ErrOutcomeUnknown)
+}
+
+func newTestQueryConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := newTestOmniFocusConfig(t)
+	cfg.QueryScriptFile = "omnidrop-query.applescript"
+	queryPath := filepath.Join(filepath.Dir(cfg.ScriptPath), cfg.QueryScriptFile)
+	require.NoError(t, os.WriteFile(queryPath, []byte("return \"\""), 0644))
+	return cfg
+}
+
+func TestOmniFocusService_ListTasks_ParsesAndFilters(t *testing.T) {
+	cfg := newTestQueryConfig(t)
+	output := "TASK\tid1\tBuy milk\t2 litres\\nsemi-skimmed\tErrands\tshopping\x1fUrgent\ttrue\tfalse\t2025-10-20 18:00:00\t\t\t15\t\tfalse\n" +
+		"TASK\tid2\tCall bank\t\tErrands\tphone\tfalse\tfalse\t2025-10-25 09:00:00\t2025-10-24 00:00:00\t\t\t\tfalse\n" +
+		"TASK\tid3\tTab\\there\t\t\t\tfalse\tfalse\t\t\t\t\tid1\ttrue\n"
+	executor := mocks.NewMockExecutor(mocks.Success(output))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.ListTasks(context.Background(), services.TaskQuery{})
+	require.Equal(t, "ok", resp.Status, resp.Reason)
+	require.Len(t, resp.Tasks, 3)
+
+	call := executor.LastCall()
+	assert.Equal(t, filepath.Join(filepath.Dir(cfg.ScriptPath), "omnidrop-query.applescript"), call.Script)
+	assert.Equal(t, []string{"list", "false", "any", "any"}, call.Args)
+
+	first := resp.Tasks[0]
+	assert.Equal(t, "id1", first.ID)
+	assert.Equal(t, "2 litres\nsemi-skimmed", first.Note)
+	assert.Equal(t, []string{"shopping", "Urgent"}, first.Tags)
+	assert.True(t, first.Flagged)
+	require.NotNil(t, first.DueDate)
+	assert.Equal(t, time.Date(2025, 10, 20, 18, 0, 0, 0, time.Local), *first.DueDate)
+	assert.Nil(t, first.DeferDate)
+	assert.Equal(t, 15, first.EstimatedMinutes)
+	assert.Equal(t, "Tab\there", resp.Tasks[2].Title)
+	assert.Equal(t, "id1", resp.Tasks[2].ParentID)
+	assert.True(t, resp.Tasks[2].InInbox)
+
+	// Filters the script does not apply are applied to its output
+	dueBefore := time.Date(2025, 10, 21, 0, 0, 0, 0, time.Local)
+	executor = mocks.NewMockExecutor(mocks.Success(output))
+	service = services.NewOmniFocusServiceWithExecutor(cfg, executor)
+	resp = service.ListTasks(context.Background(), services.TaskQuery{Project: "errands", Tag: "urgent", DueBefore: &dueBefore})
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, "id1", resp.Tasks[0].ID)
+
+	executor = mocks.NewMockExecutor(mocks.Success(output))
+	service = services.NewOmniFocusServiceWithExecutor(cfg, executor)
+	resp = service.ListTasks(context.Background(), services.TaskQuery{Search: "SEMI", Limit: 1})
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, "id1", resp.Tasks[0].ID)
+}
+
+func TestOmniFocusService_ListTasks_ScriptArguments(t *testing.T) {
+	cfg := newTestQueryConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success(""))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	completed := false
+	flagged := true
+	resp := service.ListTasks(context.Background(), services.TaskQuery{Inbox: true, Completed: &completed, Flagged: &flagged})
+
+	assert.Equal(t, "ok", resp.Status)
+	assert.Empty(t, resp.Tasks)
+	assert.Equal(t, []string{"list", "true", "false", "true"}, executor.LastCall().Args)
+}
+
+func TestOmniFocusService_ListTasks_InvalidOutput(t *testing.T) {
+	cfg := newTestQueryConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success("execution error: OmniFocus got an error"))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.ListTasks(context.Background(), services.TaskQuery{})
+
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "invalid query script output on line 1")
+}
+
+func TestOmniFocusService_GetTask(t *testing.T) {
+	cfg := newTestQueryConfig(t)
+	executor := mocks.NewMockExecutor(
+		mocks.Success("TASK\tabc\tWrite report\t\tWork\t\tfalse\ttrue\t\t\t2025-10-18 17:30:00\t\t\tfalse\n"),
+		mocks.Success("NOTFOUND\n"),
+		mocks.ExecutorResponse{Output: []byte("boom"), Err: errors.New("exit status 1")},
+	)
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.GetTask(context.Background(), "abc")
+	require.True(t, resp.Found, resp.Reason)
+	assert.Equal(t, "Write report", resp.Task.Title)
+	assert.True(t, resp.Task.Completed)
+	require.NotNil(t, resp.Task.CompletedAt)
+	assert.Equal(t, []string{"get", "abc"}, executor.LastCall().Args)
+
+	resp = service.GetTask(context.Background(), "missing")
+	assert.Equal(t, "ok", resp.Status)
+	assert.False(t, resp.Found)
+
+	resp = service.GetTask(context.Background(), "abc")
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "exit status 1")
+}
+
+func TestOmniFocusService_UpdateTask_Arguments(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor()
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	title := `Say "hi"`
+	project := "Work/Launch"
+	tags := []string{}
+	flagged := false
+	due := time.Date(2025, 10, 20, 18, 0, 0, 0, time.Local)
+	resp := service.UpdateTask(context.Background(), "abc", services.TaskUpdateRequest{
+		Title:          &title,
+		Project:        &project,
+		Tags:           &tags,
+		DueDate:        &due,
+		ClearDeferDate: true,
+		Flagged:        &flagged,
+	})
+
+	assert.Equal(t, "ok", resp.Status)
+	assert.True(t, resp.Found)
+	call := executor.LastCall()
+	assert.Equal(t, cfg.ScriptPath, call.Script)
+	payload := decodePayload(t, call)
+	assert.Equal(t, "update", payload.Mode)
+	assert.Equal(t, "abc", payload.TaskID)
+	// Only changed fields are present; an empty date removes it
+	assert.Equal(t, map[string]any{
+		"title":      `Say "hi"`,
+		"project":    "Work/Launch",
+		"tags":       []any{},
+		"due_date":   "2025-10-20 18:00:00",
+		"defer_date": "",
+		"flagged":    false,
+	}, payload.Changes)
+}
+
+func TestOmniFocusService_CompleteAndDeleteTask(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(
+		mocks.Success("success"),
+		mocks.Success("NOTFOUND"),
+		mocks.Success("Can't get task"),
+	)
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CompleteTask(context.Background(), "abc")
+	assert.True(t, resp.Found)
+	payload := decodePayload(t, executor.LastCall())
+	assert.Equal(t, []string{"complete", "abc"}, []string{payload.Mode, payload.TaskID})
+	assert.Nil(t, payload.Changes)
+
+	resp = service.DeleteTask(context.Background(), "missing")
+	assert.Equal(t, "ok", resp.Status)
+	assert.False(t, resp.Found)
+	payload = decodePayload(t, executor.LastCall())
+	assert.Equal(t, []string{"delete", "missing"}, []string{payload.Mode, payload.TaskID})
+
+	resp = service.DeleteTask(context.Background(), "abc")
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "Can't get task")
+}
+
+func TestOmniFocusService_CreateTask_ResultProtocol(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(
+		mocks.Success("Task created with 1 tags: shopping\n"+
+			`{"version":1,"status":"ok","task_id":"kXu2sF3vQ1a","warnings":["Tag 'urgent' could not be assigned"]}`),
+		// The message contains a success word, which the free-text fallback would have accepted
+		mocks.Success(`{"version":1,"status":"error","error_code":"invalid_argument","message":"Title is required; nothing was created","warnings":[]}`),
+	)
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	resp := service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+	assert.Equal(t, "ok", resp.Status)
+	assert.True(t, resp.Created)
+	assert.Equal(t, "kXu2sF3vQ1a", resp.ID)
+	assert.Equal(t, []string{"Tag 'urgent' could not be assigned"}, resp.Warnings)
+
+	resp = service.CreateTask(context.Background(), services.TaskCreateRequest{Title: "Buy milk"})
+	assert.Equal(t, "error", resp.Status)
+	assert.False(t, resp.Created)
+	assert.Contains(t, resp.Reason, "Title is required")
+	var scriptErr *services.ScriptError
+	require.ErrorAs(t, resp.Err, &scriptErr)
+	assert.Equal(t, services.ScriptErrorInvalidArgument, scriptErr.Code)
+	// Running the same request again cannot succeed
+	assert.False(t, services.Retryable(resp.Err))
+}
+
+func TestOmniFocusService_CreateTasks_ResultProtocol(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(mocks.Success(
+		`{"version":1,"index":1,"status":"ok","task_id":"root","warnings":["Project not found: Nowhere; task was added to the inbox"]}` + "\n" +
+			`{"version":1,"index":2,"status":"ok","task_id":"child","warnings":["Tag 'x' could not be assigned"]}` + "\n" +
+			`{"version":1,"index":3,"status":"error","error_code":"omnifocus_unavailable","message":"OmniFocus got an error: Connection is invalid.","warnings":[]}`))
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	responses := service.CreateTasks(context.Background(), []services.TaskCreateRequest{
+		{Title: "Root", Project: "Nowhere", Children: []services.TaskCreateRequest{{Title: "Child"}}},
+		{Title: "Other"},
+	})
+
+	require.Len(t, responses, 2)
+	assert.True(t, responses[0].Created)
+	assert.Equal(t, "root", responses[0].ID)
+	assert.Equal(t, []string{
+		"Project not found: Nowhere; task was added to the inbox",
+		"subtask 'Child': Tag 'x' could not be assigned",
+	}, responses[0].Warnings)
+
+	assert.Equal(t, "error", responses[1].Status)
+	assert.Equal(t, "OmniFocus got an error: Connection is invalid.", responses[1].Reason)
+	var scriptErr *services.ScriptError
+	require.ErrorAs(t, responses[1].Err, &scriptErr)
+	assert.Equal(t, services.ScriptErrorOmniFocusUnavailable, scriptErr.Code)
+}
+
+func TestOmniFocusService_ModifyTask_ResultProtocol(t *testing.T) {
+	cfg := newTestOmniFocusConfig(t)
+	executor := mocks.NewMockExecutor(
+		mocks.Success(`{"version":1,"status":"ok","task_id":"abc","warnings":["Tag 'x' could not be assigned"]}`),
+		mocks.Success(`{"version":1,"status":"error","error_code":"not_found","message":"Task not found: nope","warnings":[]}`),
+		mocks.Success(`{"version":1,"status":"error","error_code":"project_not_found","message":"Project not found: Nowhere","warnings":[]}`),
+	)
+	service := services.NewOmniFocusServiceWithExecutor(cfg, executor)
+
+	tags := []string{"x"}
+	resp := service.UpdateTask(context.Background(), "abc", services.TaskUpdateRequest{Tags: &tags})
+	assert.True(t, resp.Found)
+	assert.Equal(t, []string{"Tag 'x' could not be assigned"}, resp.Warnings)
+
+	resp = service.CompleteTask(context.Background(), "nope")
+	assert.Equal(t, "ok", resp.Status)
+	assert.False(t, resp.Found)
+
+	project := "Nowhere"
+	resp = service.UpdateTask(context.Background(), "abc", services.TaskUpdateRequest{Project: &project})
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Reason, "Project not found: Nowhere")
+	var scriptErr *services.ScriptError
+	require.ErrorAs(t, resp.Err, &scriptErr)
+	assert.Equal(t, services.ScriptErrorProjectNotFound, scriptErr.Code)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"omnidrop/internal/observability"
+)
+
+// UpdateTask changes the given fields of an existing OmniFocus task
+func (s *OmniFocusService) UpdateTask(ctx context.Context, id string, req TaskUpdateRequest) TaskActionResponse {
+	changes := &payloadChanges{
+		Title:   req.Title,
+		Note:    req.Note,
+		Project: req.Project,
+		Tags:    req.Tags,
+		Flagged: req.Flagged,
+	}
+	if req.DueDate != nil || req.ClearDueDate {
+		due := formatAppleScriptDate(req.DueDate)
+		changes.DueDate = &due
+	}
+	if req.DeferDate != nil || req.ClearDeferDate {
+		deferDate := formatAppleScriptDate(req.DeferDate)
+		changes.DeferDate = &deferDate
+	}
+
+	return s.modifyTask(ctx, "update", scriptPayload{Mode: payloadModeUpdate, TaskID: id, Changes: changes})
+}
+
+// CompleteTask marks an OmniFocus task as completed
+func (s *OmniFocusService) CompleteTask(ctx context.Context, id string) TaskActionResponse {
+	return s.modifyTask(ctx, "complete", scriptPayload{Mode: payloadModeComplete, TaskID: id})
+}
+
+// DeleteTask deletes an OmniFocus task, including any subtasks
+func (s *OmniFocusService) DeleteTask(ctx context.Context, id string) TaskActionResponse {
+	return s.modifyTask(ctx, "delete", scriptPayload{Mode: payloadModeDelete, TaskID: id})
+}
+
+// modifyTask runs one of the script's modification modes; each reports not_found for an unknown task ID
+func (s *OmniFocusService) modifyTask(ctx context.Context, operation string, payload scriptPayload) (resp TaskActionResponse) {
+	defer func() {
+		label := "success"
+		switch {
+		case resp.Status == "error":
+			label = "failure"
+		case !resp.Found:
+			label = "not_found"
+		}
+		observability.TaskModificationsTotal.WithLabelValues(operation, label).Inc()
+	}()
+
+	scriptPath, err := s.cfg.GetAppleScriptPath()
+	if err != nil {
+		return TaskActionResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("failed to resolve AppleScript path: %v", err),
+		}
+	}
+
+	slog.Info("✏️ Modifying OmniFocus task",
+		slog.String("operation", operation),
+		slog.String("task_id", payload.TaskID))
+
+	scriptStart := time.Now()
+	output, err := s.executePayload(ctx, scriptPath, payload)
+	observability.AppleScriptExecutionDuration.Observe(time.Since(scriptStart).Seconds())
+
+	if err != nil {
+		observability.AppleScriptErrorsTotal.WithLabelValues(classifyExecutionError(ctx, err)).Inc()
+		observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
+
+		slog.Error("❌ AppleScript execution failed",
+			slog.String("operation", operation),
+			slog.String("task_id", payload.TaskID),
+			slog.String("error", err.Error()),
+			slog.String("output", string(output)))
+
+		return TaskActionResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("AppleScript execution failed for task '%s': %v - Output: %s", payload.TaskID, err, string(output)),
+			Err:    err,
+		}
+	}
+
+	result := strings.TrimSpace(string(output))
+	if scriptResult, ok := lastScriptResult(result); ok {
+		return actionResponseFromResult(operation, payload.TaskID, scriptResult)
+	}
+
+	// Scripts that predate the result protocol print "NOTFOUND" or free text
+	if result != queryNotFound && !s.isSuccessResult(result) {
+		// AppleScript ran but returned failure
+		observability.AppleScriptExecutionsTotal.WithLabelValues("failure").Inc()
+		observability.AppleScriptErrorsTotal.WithLabelValues("unknown").Inc()
+		return TaskActionResponse{Status: "error", Reason: fmt.Sprintf("AppleScript returned: %s", result)}
+	}
+
+	observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+	if result == queryNotFound {
+		return TaskActionResponse{Status: "ok", Found: false}
+	}
+
+	slog.Info("✅ Task modified successfully", slog.String("operation", operation), slog.String("task_id", payload.TaskID))
+	return TaskActionResponse{Status: "ok", Found: true}
+}
+
+// actionResponseFromResult converts a protocol result of a modification mode into a response
+func actionResponseFromResult(operation, id string, result scriptResult) TaskActionResponse {
+	failure := result.failure()
+	switch {
+	case failure == nil:
+		observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+		for _, warning := range result.Warnings {
+			slog.Warn("⚠️ Task modified with warning", slog.String("task_id", id), slog.String("warning", warning))
+		}
+		slog.Info("✅ Task modified successfully", slog.String("operation", operation), slog.String("task_id", id))
+		return TaskActionResponse{Status: "ok", Found: true, Warnings: result.Warnings}
+	case failure.Code == ScriptErrorNotFound:
+		observability.AppleScriptExecutionsTotal.WithLabelValues("success").Inc()
+		return TaskActionResponse{Status: "ok", Found: false}
+	default:
+		recordScriptFailure(failure)
+		slog.Error("❌ Task modification failed",
+			slog.String("operation", operation),
+			slog.String("task_id", id),
+			slog.String("error_code", string(failure.Code)),
+			slog.String("message", failure.Message))
+		return TaskActionResponse{
+			Status: "error",
+			Reason: fmt.Sprintf("AppleScript failed to %s task '%s': %v", operation, id, failure),
+			Err:    failure,
+		}
+	}
+}
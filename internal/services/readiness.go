@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"omnidrop/internal/config"
+)
+
+// Component states reported by readiness checks
+const (
+	ComponentLoaded   = "loaded"   // OAuth clients were read successfully
+	ComponentEnabled  = "enabled"  // The task queue is running
+	ComponentDisabled = "disabled" // The component is turned off in the configuration
+	ComponentFailed   = "failed"   // The component is configured but could not be initialized
+)
+
+// QueueStats summarizes the task queue for readiness checks
+type QueueStats struct {
+	Depth     int
+	OldestAge time.Duration
+}
+
+// ReadinessOptions describes components initialized outside the services package
+type ReadinessOptions struct {
+	TTL          time.Duration     // How long a report is reused before the checks run again
+	OAuthClients string            // ComponentLoaded, ComponentDisabled or ComponentFailed
+	Queue        string            // ComponentEnabled, ComponentDisabled or ComponentFailed
+	QueueStats   func() QueueStats // Reports the queue state; only called when Queue is ComponentEnabled
+
+	// TaskBackends holds the registered task backends; nil treats OmniFocus as the only one in use
+	TaskBackends *TaskBackendRegistry
+	// ClientBackends returns the backends configured for OAuth clients; optional
+	ClientBackends func() []string
+}
+
+// ReadinessReport is the outcome of one run of the readiness checks
+type ReadinessReport struct {
+	Ready                 bool
+	CheckedAt             time.Time
+	TaskBackends          []string // Backends in use: the default and any configured for a client
+	AppleScriptAccessible bool
+	OmniFocusRunning      bool
+	FilesDirWritable      bool
+	OAuthClients          string
+	Queue                 string
+	QueueStats            QueueStats
+	Errors                []string
+}
+
+// ReadinessService checks whether the server can serve requests. Reports are cached for the
+// configured TTL so frequent probes do not spawn osascript on every call.
+type ReadinessService struct {
+	cfg    *config.Config
+	health HealthService
+	opts   ReadinessOptions
+	now    func() time.Time
+
+	mu     sync.Mutex // also serializes refreshes, so concurrent probes share one check
+	report *ReadinessReport
+}
+
+// NewReadinessService creates a readiness service using health for the AppleScript checks
+func NewReadinessService(cfg *config.Config, health HealthService, opts ReadinessOptions) *ReadinessService {
+	return &ReadinessService{
+		cfg:    cfg,
+		health: health,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Check returns the cached report, running the checks again once it is older than the TTL
+func (s *ReadinessService) Check() ReadinessReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil && s.now().Sub(s.report.CheckedAt) < s.opts.TTL {
+		return *s.report
+	}
+	report := s.run()
+	s.report = &report
+	return report
+}
+
+func (s *ReadinessService) run() ReadinessReport {
+	report := ReadinessReport{
+		CheckedAt:    s.now(),
+		OAuthClients: s.opts.OAuthClients,
+		Queue:        s.opts.Queue,
+		Errors:       []string{},
+	}
+
+	// AppleScript and OmniFocus only matter when a request can end up in OmniFocus
+	report.TaskBackends = s.backendsInUse()
+	for _, name := range report.TaskBackends {
+		if s.opts.TaskBackends != nil && !s.opts.TaskBackends.Has(name) {
+			report.Errors = append(report.Errors, fmt.Sprintf("task backend '%s' is not configured", name))
+		}
+	}
+	if slices.Contains(report.TaskBackends, BackendOmniFocus) {
+		health := s.health.CheckAppleScriptHealth()
+		report.AppleScriptAccessible = health.AppleScriptAccessible
+		report.OmniFocusRunning = health.OmniFocusRunning
+		report.Errors = append(report.Errors, health.Errors...)
+		if health.AppleScriptAccessible && !health.OmniFocusRunning {
+			report.Errors = append(report.Errors, "OmniFocus is not running")
+		}
+	}
+
+	if err := checkDirWritable(s.cfg.FilesDir); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("files directory is not writable: %v", err))
+	} else {
+		report.FilesDirWritable = true
+	}
+
+	if report.OAuthClients == ComponentFailed {
+		report.Errors = append(report.Errors, "OAuth clients file could not be loaded")
+	}
+	switch report.Queue {
+	case ComponentEnabled:
+		if s.opts.QueueStats != nil {
+			report.QueueStats = s.opts.QueueStats()
+		}
+	case ComponentFailed:
+		report.Errors = append(report.Errors, "task queue could not be initialized")
+	}
+
+	report.Ready = len(report.Errors) == 0
+	return report
+}
+
+// backendsInUse returns the sorted names of the default backend and the backends configured for
+// clients. The markdown and todotxt backends write under the files directory, which is always checked.
+func (s *ReadinessService) backendsInUse() []string {
+	if s.opts.TaskBackends == nil {
+		return []string{BackendOmniFocus}
+	}
+	names := []string{s.opts.TaskBackends.Default()}
+	if s.opts.ClientBackends != nil {
+		for _, name := range s.opts.ClientBackends() {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// checkDirWritable creates and removes a temporary file in dir
+func checkDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".omnidrop-ready-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
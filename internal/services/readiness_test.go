@@ -0,0 +1,174 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"omnidrop/internal/config"
+	"omnidrop/internal/services"
+	"omnidrop/test/mocks"
+)
+
+func healthyResult() services.HealthResult {
+	return services.HealthResult{AppleScriptAccessible: true, OmniFocusRunning: true}
+}
+
+func TestReadinessService_Check(t *testing.T) {
+	notADir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0644))
+
+	tests := []struct {
+		name      string
+		health    services.HealthResult
+		filesDir  string
+		opts      services.ReadinessOptions
+		wantReady bool
+		wantError string
+	}{
+		{
+			name:      "all dependencies available",
+			health:    healthyResult(),
+			opts:      services.ReadinessOptions{OAuthClients: services.ComponentLoaded, Queue: services.ComponentDisabled},
+			wantReady: true,
+		},
+		{
+			name:      "OmniFocus not running",
+			health:    services.HealthResult{AppleScriptAccessible: true},
+			wantError: "OmniFocus is not running",
+		},
+		{
+			name:      "AppleScript inaccessible",
+			health:    services.HealthResult{Errors: []string{"AppleScript test failed"}},
+			wantError: "AppleScript test failed",
+		},
+		{
+			name:      "files directory not writable",
+			health:    healthyResult(),
+			filesDir:  filepath.Join(notADir, "files"),
+			wantError: "files directory is not writable",
+		},
+		{
+			name:      "OAuth clients failed to load",
+			health:    healthyResult(),
+			opts:      services.ReadinessOptions{OAuthClients: services.ComponentFailed},
+			wantError: "OAuth clients file could not be loaded",
+		},
+		{
+			name:      "task queue failed to initialize",
+			health:    healthyResult(),
+			opts:      services.ReadinessOptions{Queue: services.ComponentFailed},
+			wantError: "task queue could not be initialized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesDir := tt.filesDir
+			if filesDir == "" {
+				filesDir = t.TempDir()
+			}
+			service := services.NewReadinessService(&config.Config{FilesDir: filesDir}, &mocks.MockHealthService{Result: tt.health}, tt.opts)
+
+			report := service.Check()
+
+			assert.Equal(t, tt.wantReady, report.Ready)
+			if tt.wantError == "" {
+				assert.Empty(t, report.Errors)
+			} else {
+				require.NotEmpty(t, report.Errors)
+				assert.Contains(t, report.Errors[len(report.Errors)-1], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestReadinessService_Check_QueueStats(t *testing.T) {
+	service := services.NewReadinessService(&config.Config{FilesDir: t.TempDir()}, &mocks.MockHealthService{Result: healthyResult()}, services.ReadinessOptions{
+		Queue: services.ComponentEnabled,
+		QueueStats: func() services.QueueStats {
+			return services.QueueStats{Depth: 3, OldestAge: time.Minute}
+		},
+	})
+
+	report := service.Check()
+
+	assert.True(t, report.Ready)
+	assert.Equal(t, services.ComponentEnabled, report.Queue)
+	assert.Equal(t, services.QueueStats{Depth: 3, OldestAge: time.Minute}, report.QueueStats)
+}
+
+func TestReadinessService_Check_CachesForTTL(t *testing.T) {
+	cfg := &config.Config{FilesDir: t.TempDir()}
+
+	cached := &mocks.MockHealthService{Result: healthyResult()}
+	service := services.NewReadinessService(cfg, cached, services.ReadinessOptions{TTL: time.Hour})
+	first := service.Check()
+	second := service.Check()
+	assert.Equal(t, 1, cached.Checks)
+	assert.Equal(t, first.CheckedAt, second.CheckedAt)
+
+	uncached := &mocks.MockHealthService{Result: healthyResult()}
+	service = services.NewReadinessService(cfg, uncached, services.ReadinessOptions{})
+	service.Check()
+	service.Check()
+	assert.Equal(t, 2, uncached.Checks)
+}
+
+func TestReadinessService_Check_BackendsInUse(t *testing.T) {
+	backends := services.NewTaskBackendRegistry(services.BackendMarkdown)
+	backends.Register(services.BackendOmniFocus, &mocks.MockOmniFocusService{})
+	backends.Register(services.BackendMarkdown, &mocks.MockOmniFocusService{})
+
+	tests := []struct {
+		name             string
+		clientBackends   []string
+		wantBackends     []string
+		wantHealthChecks int
+		wantError        string
+	}{
+		{
+			name:         "OmniFocus not in use",
+			wantBackends: []string{services.BackendMarkdown},
+		},
+		{
+			name:             "OmniFocus configured for a client",
+			clientBackends:   []string{services.BackendOmniFocus},
+			wantBackends:     []string{services.BackendMarkdown, services.BackendOmniFocus},
+			wantHealthChecks: 1,
+			wantError:        "OmniFocus is not running",
+		},
+		{
+			name:           "unconfigured backend for a client",
+			clientBackends: []string{services.BackendCalDAV, services.BackendMarkdown},
+			wantBackends:   []string{services.BackendCalDAV, services.BackendMarkdown},
+			wantError:      "task backend 'caldav' is not configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &mocks.MockHealthService{Result: services.HealthResult{AppleScriptAccessible: true}}
+			service := services.NewReadinessService(&config.Config{FilesDir: t.TempDir()}, health, services.ReadinessOptions{
+				TaskBackends:   backends,
+				ClientBackends: func() []string { return tt.clientBackends },
+			})
+
+			report := service.Check()
+
+			assert.Equal(t, tt.wantBackends, report.TaskBackends)
+			assert.Equal(t, tt.wantHealthChecks, health.Checks)
+			if tt.wantError == "" {
+				assert.True(t, report.Ready)
+				assert.Empty(t, report.Errors)
+			} else {
+				assert.False(t, report.Ready)
+				assert.Contains(t, report.Errors, tt.wantError)
+			}
+		})
+	}
+}
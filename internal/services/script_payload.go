@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	// ScriptPayloadVersion is the version of the JSON request document passed to omnidrop.applescript
+	ScriptPayloadVersion = 1
+	// payloadFlag tells omnidrop.applescript to read its request from the JSON file that follows
+	payloadFlag = "--payload"
+)
+
+// Request modes of a script payload
+const (
+	payloadModeCreate   = "create"
+	payloadModeBatch    = "batch"
+	payloadModeUpdate   = "update"
+	payloadModeComplete = "complete"
+	payloadModeDelete   = "delete"
+)
+
+// scriptPayload is the JSON request document omnidrop.applescript decodes in payload mode.
+// Passing one document instead of positional arguments keeps values such as tags containing
+// commas intact, avoids argv length limits and lets fields be added without reordering.
+type scriptPayload struct {
+	Version int             `json:"version"`
+	Mode    string          `json:"mode"`
+	Tasks   []payloadTask   `json:"tasks,omitempty"`   // create and batch
+	TaskID  string          `json:"task_id,omitempty"` // update, complete and delete
+	Changes *payloadChanges `json:"changes,omitempty"` // update
+}
+
+// payloadTask is one task to create. Every field is always present so the script can read it
+// directly; empty strings and zero values mean "not set".
+type payloadTask struct {
+	Title            string   `json:"title"`
+	Note             string   `json:"note"`
+	Project          string   `json:"project"`
+	Tags             []string `json:"tags"`
+	DueDate          string   `json:"due_date"`   // local "YYYY-MM-DD HH:MM:SS"
+	DeferDate        string   `json:"defer_date"` // local "YYYY-MM-DD HH:MM:SS"
+	EstimatedMinutes int      `json:"estimated_minutes"`
+	Flagged          bool     `json:"flagged"`
+	Sequential       bool     `json:"sequential"`
+	Parent           int      `json:"parent"` // 1-based index of the parent task, 0 for top-level tasks
+}
+
+// payloadChanges lists the fields an update changes; absent fields are left unchanged.
+// An empty due or defer date removes the date.
+type payloadChanges struct {
+	Title     *string   `json:"title,omitempty"`
+	Note      *string   `json:"note,omitempty"`
+	Project   *string   `json:"project,omitempty"`
+	Tags      *[]string `json:"tags,omitempty"`
+	DueDate   *string   `json:"due_date,omitempty"`
+	DeferDate *string   `json:"defer_date,omitempty"`
+	Flagged   *bool     `json:"flagged,omitempty"`
+}
+
+// newPayloadTask converts a create request; subtasks are linked through parent instead of nesting
+func newPayloadTask(req TaskCreateRequest, parent int) payloadTask {
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return payloadTask{
+		Title:            req.Title,
+		Note:             req.Note,
+		Project:          req.Project,
+		Tags:             tags,
+		DueDate:          formatAppleScriptDate(req.DueDate),
+		DeferDate:        formatAppleScriptDate(req.DeferDate),
+		EstimatedMinutes: req.EstimatedMinutes,
+		Flagged:          req.Flagged,
+		Sequential:       req.Sequential,
+		Parent:           parent,
+	}
+}
+
+// executePayload writes payload to a private temporary file and runs scriptPath in payload mode.
+// The file is removed once the script has finished.
+func (s *OmniFocusService) executePayload(ctx context.Context, scriptPath string, payload scriptPayload) ([]byte, error) {
+	payload.Version = ScriptPayloadVersion
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode script payload: %v", err)
+	}
+
+	// CreateTemp opens the file with mode 0600, so other users cannot read task contents
+	file, err := os.CreateTemp("", "omnidrop-payload-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create script payload file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write script payload file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write script payload file: %v", err)
+	}
+
+	return s.executor.Execute(ctx, scriptPath, payloadFlag, file.Name())
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ScriptProtocolVersion is the newest omnidrop.applescript result protocol this server understands.
+// Newer versions may add fields; results are still parsed with the fields known here.
+const ScriptProtocolVersion = 1
+
+// ScriptErrorCode classifies a failure reported by omnidrop.applescript
+type ScriptErrorCode string
+
+const (
+	ScriptErrorInvalidArgument      ScriptErrorCode = "invalid_argument"
+	ScriptErrorProjectNotFound      ScriptErrorCode = "project_not_found"
+	ScriptErrorParentNotCreated     ScriptErrorCode = "parent_not_created"
+	ScriptErrorNotFound             ScriptErrorCode = "not_found"
+	ScriptErrorOmniFocusUnavailable ScriptErrorCode = "omnifocus_unavailable"
+	ScriptErrorTimeout              ScriptErrorCode = "timeout"
+	ScriptErrorAppleScript          ScriptErrorCode = "applescript_error"
+)
+
+// Permanent reports whether the failure is caused by the request itself, so that running
+// the same request again cannot succeed
+func (c ScriptErrorCode) Permanent() bool {
+	switch c {
+	case ScriptErrorInvalidArgument, ScriptErrorProjectNotFound, ScriptErrorParentNotCreated, ScriptErrorNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// ScriptError is a failure the script reported through the result protocol
+type ScriptError struct {
+	Code    ScriptErrorCode
+	Message string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is lets errors.Is(err, ErrOutcomeUnknown) match a timed-out AppleEvent, which OmniFocus
+// may still have carried out after the script stopped waiting for the reply
+func (e *ScriptError) Is(target error) bool {
+	return target == ErrOutcomeUnknown && e.Code == ScriptErrorTimeout
+}
+
+// scriptResult is one result object printed by omnidrop.applescript:
+// {"version":1,"status":"ok","task_id":"...","warnings":[...]} or
+// {"version":1,"status":"error","error_code":"...","message":"..."}.
+// Batch mode adds the 1-based index of the task.
+type scriptResult struct {
+	Version   int             `json:"version"`
+	Index     int             `json:"index,omitempty"`
+	Status    string          `json:"status"`
+	TaskID    string          `json:"task_id,omitempty"`
+	Warnings  []string        `json:"warnings,omitempty"`
+	ErrorCode ScriptErrorCode `json:"error_code,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// failure returns the reported failure, or nil when the script succeeded
+func (r scriptResult) failure() *ScriptError {
+	if r.Status == "ok" {
+		return nil
+	}
+	code := r.ErrorCode
+	if code == "" {
+		code = ScriptErrorAppleScript
+	}
+	message := r.Message
+	if message == "" {
+		message = "AppleScript reported an error"
+	}
+	return &ScriptError{Code: code, Message: message}
+}
+
+// parseScriptResult decodes a single line as a result object. ok is false for any other
+// line, such as log output or the free text printed by scripts that predate the protocol.
+func parseScriptResult(line string) (scriptResult, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return scriptResult{}, false
+	}
+
+	var result scriptResult
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		return scriptResult{}, false
+	}
+	if result.Version < 1 || (result.Status != "ok" && result.Status != "error") {
+		return scriptResult{}, false
+	}
+	return result, true
+}
+
+// lastScriptResult returns the result object of a single-result mode, which the script
+// prints as the last non-empty line of its output
+func lastScriptResult(output string) (scriptResult, bool) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return parseScriptResult(lines[len(lines)-1])
+}
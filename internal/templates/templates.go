@@ -0,0 +1,233 @@
+// Package templates loads named task templates from YAML and renders them
+// with {{var}} placeholder substitution.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrTemplateNotFound is returned when no template has the requested name
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// placeholderPattern matches {{var}} and {{ var }}
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+	// namePattern restricts template names to values that are safe in URLs and scopes
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// ScopePrefix prefixes the default per-template OAuth scope
+const ScopePrefix = "templates:"
+
+// Task is a templated task; every string field may contain {{var}} placeholders
+type Task struct {
+	Title            string   `yaml:"title"`
+	Note             string   `yaml:"note,omitempty"`
+	Project          string   `yaml:"project,omitempty"`
+	Tags             []string `yaml:"tags,omitempty"`
+	DueDate          string   `yaml:"due_date,omitempty"`
+	DeferDate        string   `yaml:"defer_date,omitempty"`
+	EstimatedMinutes int      `yaml:"estimated_minutes,omitempty"`
+	Flagged          bool     `yaml:"flagged,omitempty"`
+	Sequential       bool     `yaml:"sequential,omitempty"`
+	Children         []Task   `yaml:"children,omitempty"`
+}
+
+// Template is a named task template
+type Template struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Scope       string `yaml:"scope,omitempty"` // defaults to "templates:<name>"
+	Task        `yaml:",inline"`
+}
+
+// Config represents the templates file structure
+type Config struct {
+	Templates []Template `yaml:"templates"`
+}
+
+// RequiredScope returns the OAuth scope a client needs to use the template
+func (t *Template) RequiredScope() string {
+	if t.Scope != "" {
+		return t.Scope
+	}
+	return ScopePrefix + t.Name
+}
+
+// Render substitutes vars into every placeholder of the template's task tree.
+// Variables the template does not use are ignored; missing ones are an error.
+func (t *Template) Render(vars map[string]string) (Task, error) {
+	missing := make(map[string]struct{})
+	rendered := t.Task.render(vars, missing)
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Task{}, fmt.Errorf("missing template variables: %s", strings.Join(names, ", "))
+	}
+	return rendered, nil
+}
+
+func (t Task) render(vars map[string]string, missing map[string]struct{}) Task {
+	sub := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+			name := placeholderPattern.FindStringSubmatch(match)[1]
+			value, ok := vars[name]
+			if !ok {
+				missing[name] = struct{}{}
+			}
+			return value
+		})
+	}
+
+	out := t
+	out.Title = sub(t.Title)
+	out.Note = sub(t.Note)
+	out.Project = sub(t.Project)
+	out.DueDate = sub(t.DueDate)
+	out.DeferDate = sub(t.DeferDate)
+
+	out.Tags = nil
+	for _, tag := range t.Tags {
+		if tag = sub(tag); tag != "" {
+			out.Tags = append(out.Tags, tag)
+		}
+	}
+
+	out.Children = nil
+	for _, child := range t.Children {
+		out.Children = append(out.Children, child.render(vars, missing))
+	}
+	return out
+}
+
+// Repository serves templates from a YAML file, reloading it when it changes
+type Repository struct {
+	mu           sync.RWMutex
+	configPath   string
+	templates    map[string]*Template // name -> template
+	lastModified int64
+}
+
+// NewRepository creates a template repository. A missing file is not an error;
+// templates become available as soon as it is created.
+func NewRepository(configPath string) (*Repository, error) {
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+
+	repo := &Repository{
+		configPath: configPath,
+		templates:  make(map[string]*Template),
+	}
+
+	if err := repo.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	return repo, nil
+}
+
+// defaultConfigPath returns the default templates path, next to oauth-clients.yaml
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".omnidrop/task-templates.yaml"
+	}
+	return filepath.Join(home, ".local/share/omnidrop/task-templates.yaml")
+}
+
+// Load reads the templates file if its modification time changed since the last load.
+// If the new version is invalid the previously loaded templates are kept.
+func (r *Repository) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check file modification time
+	fileInfo, err := os.Stat(r.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The file was removed: no templates are available
+			r.templates = make(map[string]*Template)
+			r.lastModified = 0
+		}
+		return err
+	}
+
+	modTime := fileInfo.ModTime().UnixNano()
+	if modTime == r.lastModified {
+		// File hasn't changed, no need to reload
+		return nil
+	}
+
+	data, err := os.ReadFile(r.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read templates file: %w", err)
+	}
+
+	// Remember the version even when it is invalid so an unchanged broken file is reported once
+	r.lastModified = modTime
+
+	templates, err := parseTemplates(data)
+	if err != nil {
+		return err
+	}
+	r.templates = templates
+
+	return nil
+}
+
+func parseTemplates(data []byte) (map[string]*Template, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse templates file: %w", err)
+	}
+
+	templates := make(map[string]*Template, len(config.Templates))
+	for i := range config.Templates {
+		tmpl := &config.Templates[i]
+		if !namePattern.MatchString(tmpl.Name) {
+			return nil, fmt.Errorf("templates[%d]: invalid template name %q", i, tmpl.Name)
+		}
+		if _, exists := templates[tmpl.Name]; exists {
+			return nil, fmt.Errorf("templates[%d]: duplicate template name %q", i, tmpl.Name)
+		}
+		if tmpl.Title == "" {
+			return nil, fmt.Errorf("template %q: title is required", tmpl.Name)
+		}
+		templates[tmpl.Name] = tmpl
+	}
+	return templates, nil
+}
+
+// Get returns the named template, picking up any changes to the templates file first
+func (r *Repository) Get(name string) (*Template, error) {
+	// A failed reload keeps serving the last good templates
+	if err := r.Load(); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to reload task templates; using previous version",
+			slog.String("templates_file", r.configPath),
+			slog.String("error", err.Error()))
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,140 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const releaseTemplates = `templates:
+  - name: release-checklist
+    description: Ship a release
+    title: "Release {{version}}"
+    project: Engineering
+    tags: [release, "{{ team }}"]
+    sequential: true
+    children:
+      - title: "Tag v{{version}}"
+      - title: Announce
+        note: "Release notes for {{version}}"
+  - name: onboarding
+    scope: "hr:onboarding"
+    title: "Onboard {{name}}"
+`
+
+func writeTemplates(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestTemplate_Render(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task-templates.yaml")
+	writeTemplates(t, path, releaseTemplates)
+	repo, err := NewRepository(path)
+	require.NoError(t, err)
+
+	tmpl, err := repo.Get("release-checklist")
+	require.NoError(t, err)
+
+	task, err := tmpl.Render(map[string]string{"version": "1.2.0", "team": "platform", "unused": "x"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Release 1.2.0", task.Title)
+	assert.Equal(t, "Engineering", task.Project)
+	assert.Equal(t, []string{"release", "platform"}, task.Tags)
+	assert.True(t, task.Sequential)
+	require.Len(t, task.Children, 2)
+	assert.Equal(t, "Tag v1.2.0", task.Children[0].Title)
+	assert.Equal(t, "Release notes for 1.2.0", task.Children[1].Note)
+
+	// The template itself is left untouched
+	assert.Equal(t, "Release {{version}}", tmpl.Title)
+}
+
+func TestTemplate_RenderMissingVariables(t *testing.T) {
+	tmpl := &Template{Name: "t", Task: Task{
+		Title:    "{{b}} {{a}}",
+		Children: []Task{{Title: "{{c}}"}},
+	}}
+
+	_, err := tmpl.Render(map[string]string{"a": "1"})
+
+	require.Error(t, err)
+	assert.Equal(t, "missing template variables: b, c", err.Error())
+}
+
+func TestTemplate_RequiredScope(t *testing.T) {
+	assert.Equal(t, "templates:release", (&Template{Name: "release"}).RequiredScope())
+	assert.Equal(t, "hr:onboarding", (&Template{Name: "onboarding", Scope: "hr:onboarding"}).RequiredScope())
+}
+
+func TestRepository_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task-templates.yaml")
+
+	// A missing file yields an empty repository
+	repo, err := NewRepository(path)
+	require.NoError(t, err)
+	_, err = repo.Get("onboarding")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	writeTemplates(t, path, releaseTemplates)
+	tmpl, err := repo.Get("onboarding")
+	require.NoError(t, err)
+	assert.Equal(t, "Onboard {{name}}", tmpl.Title)
+
+	// An invalid edit keeps the previous templates
+	writeTemplates(t, path, "templates: [")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+	_, err = repo.Get("onboarding")
+	assert.NoError(t, err)
+
+	// A valid edit replaces them
+	writeTemplates(t, path, "templates:\n  - name: weekly-review\n    title: Weekly review\n")
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+	_, err = repo.Get("onboarding")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = repo.Get("weekly-review")
+	assert.NoError(t, err)
+}
+
+func TestRepository_RejectsInvalidTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name:    "duplicate name",
+			content: "templates:\n  - name: a\n    title: A\n  - name: a\n    title: B\n",
+			errMsg:  "duplicate template name",
+		},
+		{
+			name:    "invalid name",
+			content: "templates:\n  - name: \"a/b\"\n    title: A\n",
+			errMsg:  "invalid template name",
+		},
+		{
+			name:    "missing title",
+			content: "templates:\n  - name: a\n",
+			errMsg:  "title is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "task-templates.yaml")
+			writeTemplates(t, path, tt.content)
+
+			_, err := NewRepository(path)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+// omnidrop-script-version: 1
+// omnidrop.js - JavaScript for Automation (JXA) equivalent of omnidrop.applescript
+//
+// Selected with OMNIDROP_SCRIPT_LANGUAGE=javascript and run as
+//   osascript -l JavaScript omnidrop.js --payload <file>
+// It reads the same JSON payloads and prints the same versioned JSON results as
+// omnidrop.applescript; the payload and result protocols are described there.
+// Without "--payload" a single task is created from positional arguments:
+// title, note, project, comma-separated tags, then optionally due date, defer date,
+// estimated minutes and flagged.
+
+ObjC.import('Foundation');
+
+const PAYLOAD_VERSION = 1;
+const RESULT_VERSION = 1;
+
+const OmniFocus = Application('OmniFocus');
+
+// Main handler
+function run(argv) {
+    if (argv.length === 2 && argv[0] === '--payload') {
+        return runPayload(argv[1]);
+    }
+
+    try {
+        if (argv.length < 4) {
+            throw scriptError('invalid_argument', 'Expected at least 4 arguments: title, note, project, tags');
+        }
+
+        const spec = {
+            title: argv[0],
+            note: argv[1],
+            project: argv[2],
+            tags: argv[3] === '' ? [] : argv[3].split(','),
+            due_date: '',
+            defer_date: '',
+            estimated_minutes: 0,
+            flagged: false,
+            sequential: false,
+        };
+        // Optional scheduling arguments
+        if (argv.length >= 8) {
+            spec.due_date = argv[4];
+            spec.defer_date = argv[5];
+            spec.estimated_minutes = argv[6] === '' ? 0 : parseInt(argv[6], 10);
+            spec.flagged = argv[7] === 'true';
+        }
+
+        const creation = createTask(spec, null);
+        return okResult(null, creation.task.id(), creation.warnings);
+    } catch (err) {
+        return errorResult(null, err);
+    }
+}
+
+// Payload handler: dispatches on the payload mode. Batch mode reports one result per task;
+// every other mode reports a single result.
+function runPayload(payloadPath) {
+    try {
+        const payload = readPayload(payloadPath);
+        if (typeof payload.version !== 'number') {
+            throw scriptError('invalid_argument', 'Payload has no version');
+        }
+        if (payload.version > PAYLOAD_VERSION) {
+            throw scriptError('invalid_argument', 'Unsupported payload version: ' + payload.version);
+        }
+
+        switch (payload.mode) {
+        case 'batch':
+            return runBatch(payload.tasks || []);
+        case 'create': {
+            if (!Array.isArray(payload.tasks) || payload.tasks.length !== 1) {
+                throw scriptError('invalid_argument', 'create expects exactly one task');
+            }
+            const creation = createTask(payload.tasks[0], null);
+            return okResult(null, creation.task.id(), creation.warnings);
+        }
+        case 'update':
+        case 'complete':
+        case 'delete': {
+            if (!payload.task_id) {
+                throw scriptError('invalid_argument', payload.mode + ' requires a task id');
+            }
+            const warnings = modifyTask(payload.mode, payload.task_id, payload.changes || {});
+            return okResult(null, payload.task_id, warnings);
+        }
+        }
+        throw scriptError('invalid_argument', 'Unknown payload mode: ' + payload.mode);
+    } catch (err) {
+        return errorResult(null, err);
+    }
+}
+
+function readPayload(payloadPath) {
+    const data = $.NSData.dataWithContentsOfFile(payloadPath);
+    if (data.isNil()) {
+        throw scriptError('invalid_argument', 'Cannot read payload file: ' + payloadPath);
+    }
+    const text = $.NSString.alloc.initWithDataEncoding(data, $.NSUTF8StringEncoding).js;
+    try {
+        return JSON.parse(text);
+    } catch (err) {
+        throw scriptError('invalid_argument', 'Invalid payload: ' + err.message);
+    }
+}
+
+// Batch handler: creates each task independently and reports one result object per task.
+// Parents always precede their children.
+function runBatch(tasks) {
+    const resultLines = [];
+    const createdTasks = [];
+
+    tasks.forEach((spec, i) => {
+        let newTask = null;
+        try {
+            let parentTask = null;
+            if (spec.parent > 0) {
+                parentTask = createdTasks[spec.parent - 1] || null;
+                if (parentTask === null) {
+                    throw scriptError('parent_not_created', 'Parent task was not created');
+                }
+            }
+            const creation = createTask(spec, parentTask);
+            newTask = creation.task;
+            resultLines.push(okResult(i + 1, newTask.id(), creation.warnings));
+        } catch (err) {
+            resultLines.push(errorResult(i + 1, err));
+        }
+        createdTasks.push(newTask);
+    });
+
+    return resultLines.join('\n');
+}
+
+// Task creation shared by single and batch modes. When parentTask is given the task is created
+// inside it (making the parent an action group) and the project is ignored.
+function createTask(spec, parentTask) {
+    const warnings = [];
+    if (!spec.title) {
+        throw scriptError('invalid_argument', 'Title is required');
+    }
+
+    const doc = OmniFocus.defaultDocument;
+
+    // Resolve the project; a project that cannot be found sends the task to the inbox
+    let targetProject = null;
+    if (parentTask === null && spec.project) {
+        try {
+            targetProject = resolveProjectReference(spec.project, doc);
+        } catch (err) {
+            console.log('Project resolution error: ' + err.message);
+            warnings.push(err.message + '; task was added to the inbox');
+        }
+    }
+
+    let task;
+    if (parentTask !== null) {
+        task = OmniFocus.Task({ name: spec.title });
+        parentTask.tasks.push(task);
+    } else if (targetProject !== null) {
+        task = OmniFocus.Task({ name: spec.title });
+        targetProject.tasks.push(task);
+    } else {
+        task = OmniFocus.InboxTask({ name: spec.title });
+        doc.inboxTasks.push(task);
+    }
+
+    if (spec.note) {
+        task.note = spec.note;
+    }
+
+    // Due date defaults to today at 18:00:00
+    if (spec.due_date) {
+        task.dueDate = parseDateTime(spec.due_date);
+    } else {
+        const today = new Date();
+        today.setHours(18, 0, 0, 0);
+        task.dueDate = today;
+    }
+
+    if (spec.defer_date) {
+        task.deferDate = parseDateTime(spec.defer_date);
+    }
+    if (spec.estimated_minutes > 0) {
+        task.estimatedMinutes = spec.estimated_minutes;
+    }
+    if (spec.flagged) {
+        task.flagged = true;
+    }
+    // Only meaningful for action groups
+    if (spec.sequential) {
+        task.sequential = true;
+    }
+
+    const tags = spec.tags || [];
+    if (tags.length > 0) {
+        const tagResults = assignTags(task, tags, doc);
+        if (tagResults.assigned.length > 0) {
+            console.log('Task created with ' + tagResults.assigned.length + ' tags: ' + tagResults.assigned.join(', '));
+        }
+        tagResults.failed.forEach((tagName) => {
+            warnings.push("Tag '" + tagName + "' could not be assigned");
+        });
+    }
+
+    return { task: task, warnings: warnings };
+}
+
+// Changes an existing task, looked up by its persistent id. For "update", changes holds only the
+// fields to change: title, note, project (moves the task), tags (replaces all tags), due_date and
+// defer_date ("" clears the date) and flagged. Returns the list of warnings.
+function modifyTask(mode, taskId, changes) {
+    const warnings = [];
+    const doc = OmniFocus.defaultDocument;
+
+    const task = doc.flattenedTasks.byId(taskId);
+    try {
+        task.name();
+    } catch (err) {
+        throw scriptError('not_found', 'Task not found: ' + taskId);
+    }
+
+    if (mode === 'complete') {
+        OmniFocus.markComplete(task);
+        return warnings;
+    }
+    if (mode === 'delete') {
+        OmniFocus.delete(task);
+        return warnings;
+    }
+
+    if ('title' in changes) {
+        if (changes.title === '') {
+            throw scriptError('invalid_argument', 'Title is required');
+        }
+        task.name = changes.title;
+    }
+
+    if ('note' in changes) {
+        task.note = changes.note;
+    }
+
+    if ('project' in changes) {
+        // Unlike creation, a project that cannot be resolved fails the update
+        let targetProject;
+        try {
+            targetProject = resolveProjectReference(changes.project, doc);
+        } catch (err) {
+            throw scriptError('project_not_found', err.message);
+        }
+        OmniFocus.move(task, { to: targetProject.tasks.end });
+    }
+
+    if ('tags' in changes) {
+        task.tags().forEach((existingTag) => {
+            OmniFocus.remove(existingTag, { from: task.tags });
+        });
+        if (changes.tags.length > 0) {
+            assignTags(task, changes.tags, doc).failed.forEach((tagName) => {
+                warnings.push("Tag '" + tagName + "' could not be assigned");
+            });
+        }
+    }
+
+    if ('due_date' in changes) {
+        task.dueDate = changes.due_date === '' ? null : parseDateTime(changes.due_date);
+    }
+
+    if ('defer_date' in changes) {
+        task.deferDate = changes.defer_date === '' ? null : parseDateTime(changes.defer_date);
+    }
+
+    if ('flagged' in changes) {
+        task.flagged = changes.flagged;
+    }
+
+    return warnings;
+}
+
+// Project resolution: "Folder/Subfolder/Project" walks the folder hierarchy, a plain name
+// matches a project in any folder
+function resolveProjectReference(projectPath, doc) {
+    const components = projectPath.split('/').map((component) => component.trim());
+    const projectName = components.pop();
+
+    if (components.length === 0) {
+        const projects = doc.flattenedProjects.whose({ name: projectName })();
+        if (projects.length === 0) {
+            throw scriptError('project_not_found', 'Project resolution failed: Project not found: ' + projectName);
+        }
+        return projects[0];
+    }
+
+    let container = doc;
+    components.forEach((folderName) => {
+        const folders = container.folders.whose({ name: folderName })();
+        if (folders.length === 0) {
+            throw scriptError('project_not_found', 'Project resolution failed: Folder navigation failed: Folder not found: ' + folderName);
+        }
+        container = folders[0];
+    });
+
+    const projects = container.projects.whose({ name: projectName })();
+    if (projects.length === 0) {
+        throw scriptError('project_not_found', "Project resolution failed: Project '" + projectName + "' not found in folder hierarchy");
+    }
+    return projects[0];
+}
+
+// Tag assignment: each tag is looked up or created, then added to the task. If none could be
+// added, the first tag is set as the primary tag as a last resort.
+function assignTags(task, tagNames, doc) {
+    const assigned = [];
+    const failed = [];
+
+    tagNames.map((tagName) => String(tagName).trim()).filter((tagName) => tagName !== '').forEach((tagName) => {
+        const tag = getOrCreateTag(tagName, doc);
+        if (tag === null) {
+            failed.push(tagName);
+            return;
+        }
+        try {
+            OmniFocus.add(tag, { to: task.tags });
+            assigned.push(tagName);
+        } catch (err) {
+            console.log("Tag assignment failed for '" + tagName + "': " + err.message);
+            failed.push(tagName);
+        }
+    });
+
+    if (assigned.length === 0 && failed.length > 0) {
+        try {
+            const tag = getOrCreateTag(failed[0], doc);
+            if (tag !== null) {
+                task.primaryTag = tag;
+                assigned.push(failed[0]);
+                console.log('Primary tag assignment succeeded for: ' + failed[0]);
+            }
+        } catch (err) {
+            console.log("Primary tag assignment failed for '" + failed[0] + "': " + err.message);
+        }
+    }
+
+    return { assigned: assigned, failed: failed };
+}
+
+function getOrCreateTag(tagName, doc) {
+    try {
+        const tags = doc.tags.whose({ name: tagName })();
+        if (tags.length > 0) {
+            return tags[0];
+        }
+        const tag = OmniFocus.Tag({ name: tagName });
+        doc.tags.push(tag);
+        console.log('Created new tag: ' + tagName);
+        return tag;
+    } catch (err) {
+        console.log("Error looking up tag '" + tagName + "': " + err.message);
+        return null;
+    }
+}
+
+// Builds a date from "YYYY-MM-DD HH:MM:SS" (local time)
+function parseDateTime(value) {
+    const match = /^(\d{4})-(\d{2})-(\d{2}) (\d{2}):(\d{2}):(\d{2})$/.exec(value);
+    if (match === null) {
+        throw scriptError('invalid_argument', 'Invalid date: ' + value);
+    }
+    return new Date(+match[1], +match[2] - 1, +match[3], +match[4], +match[5], +match[6]);
+}
+
+// Result protocol (version 1), see omnidrop.applescript
+
+function scriptError(code, message) {
+    const err = new Error(message);
+    err.code = code;
+    return err;
+}
+
+// Maps a thrown error to a result error code. Apple event errors carry the same
+// numbers omnidrop.applescript maps.
+function errorCodeFor(err) {
+    if (err.code) {
+        return err.code;
+    }
+    switch (err.errorNumber) {
+    case -600:
+    case -609:
+        return 'omnifocus_unavailable';
+    case -1712:
+        return 'timeout';
+    }
+    return 'applescript_error';
+}
+
+function okResult(index, taskId, warnings) {
+    const result = { version: RESULT_VERSION };
+    if (index !== null) {
+        result.index = index;
+    }
+    result.status = 'ok';
+    result.task_id = String(taskId);
+    result.warnings = warnings;
+    return JSON.stringify(result);
+}
+
+function errorResult(index, err) {
+    const result = { version: RESULT_VERSION };
+    if (index !== null) {
+        result.index = index;
+    }
+    result.status = 'error';
+    result.error_code = errorCodeFor(err);
+    result.message = err.message;
+    result.warnings = [];
+    return JSON.stringify(result);
+}
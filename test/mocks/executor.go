@@ -0,0 +1,118 @@
+package mocks
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"omnidrop/internal/services"
+)
+
+// Ensure MockAppleScriptExecutor implements services.AppleScriptExecutor
+var _ services.AppleScriptExecutor = (*MockAppleScriptExecutor)(nil)
+
+// ExecutorResponse is a single scripted result returned by MockAppleScriptExecutor
+type ExecutorResponse struct {
+	Output []byte
+	Err    error
+	// Block makes the call wait until its context is done and return ctx.Err(),
+	// simulating an osascript process that hangs until the timeout fires.
+	Block bool
+}
+
+// ExecutorCall records the arguments of a single executor invocation
+type ExecutorCall struct {
+	Script string
+	Args   []string
+	// Payload holds the contents of the JSON payload file passed with "--payload",
+	// read during the call because the service removes the file afterwards
+	Payload []byte
+}
+
+// MockAppleScriptExecutor is a scriptable AppleScriptExecutor for tests.
+// Responses are consumed in order; once exhausted, Default is returned.
+type MockAppleScriptExecutor struct {
+	mu        sync.Mutex
+	Responses []ExecutorResponse
+	Default   ExecutorResponse
+	Calls     []ExecutorCall
+}
+
+// NewMockExecutor creates a mock executor that returns the given responses in order
+// and "success" once they are exhausted
+func NewMockExecutor(responses ...ExecutorResponse) *MockAppleScriptExecutor {
+	return &MockAppleScriptExecutor{
+		Responses: responses,
+		Default:   Success("success"),
+	}
+}
+
+// Success returns a response that completes with the given output
+func Success(output string) ExecutorResponse {
+	return ExecutorResponse{Output: []byte(output)}
+}
+
+// Failure returns a response that fails with the given error and no output
+func Failure(err error) ExecutorResponse {
+	return ExecutorResponse{Err: err}
+}
+
+// PartialOutput returns a response whose output was cut off by a failure,
+// as when osascript is killed after writing some log lines
+func PartialOutput(output string, err error) ExecutorResponse {
+	return ExecutorResponse{Output: []byte(output), Err: err}
+}
+
+// Timeout returns a response that blocks until the caller's context expires
+func Timeout() ExecutorResponse {
+	return ExecutorResponse{Block: true}
+}
+
+// Execute returns the next scripted response for a file-based script
+func (m *MockAppleScriptExecutor) Execute(ctx context.Context, script string, args ...string) ([]byte, error) {
+	return m.respond(ctx, script, args)
+}
+
+// ExecuteSimple returns the next scripted response for an inline script
+func (m *MockAppleScriptExecutor) ExecuteSimple(ctx context.Context, script string) ([]byte, error) {
+	return m.respond(ctx, script, nil)
+}
+
+// CallCount returns the number of recorded invocations
+func (m *MockAppleScriptExecutor) CallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Calls)
+}
+
+// LastCall returns the most recent invocation, or a zero value if none were made
+func (m *MockAppleScriptExecutor) LastCall() ExecutorCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Calls) == 0 {
+		return ExecutorCall{}
+	}
+	return m.Calls[len(m.Calls)-1]
+}
+
+func (m *MockAppleScriptExecutor) respond(ctx context.Context, script string, args []string) ([]byte, error) {
+	call := ExecutorCall{Script: script, Args: append([]string(nil), args...)}
+	if len(args) == 2 && args[0] == "--payload" {
+		call.Payload, _ = os.ReadFile(args[1])
+	}
+
+	m.mu.Lock()
+	m.Calls = append(m.Calls, call)
+	resp := m.Default
+	if len(m.Responses) > 0 {
+		resp = m.Responses[0]
+		m.Responses = m.Responses[1:]
+	}
+	m.mu.Unlock()
+
+	if resp.Block {
+		<-ctx.Done()
+		return resp.Output, ctx.Err()
+	}
+	return resp.Output, resp.Err
+}
@@ -2,13 +2,19 @@ package mocks
 
 import (
 	"context"
+	"sync"
 
 	"omnidrop/internal/services"
 )
 
 // MockOmniFocusService provides a mock implementation for testing
 type MockOmniFocusService struct {
-	CreateTaskFunc func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse
+	CreateTaskFunc  func(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse
+	CreateTasksFunc func(ctx context.Context, reqs []services.TaskCreateRequest) []services.TaskCreateResponse
+	ListTasksFunc   func(ctx context.Context, query services.TaskQuery) services.TaskListResponse
+	GetTaskFunc     func(ctx context.Context, id string) services.TaskGetResponse
+	UpdateTaskFunc  func(ctx context.Context, id string, req services.TaskUpdateRequest) services.TaskActionResponse
+	ActionFunc      func(ctx context.Context, action, id string) services.TaskActionResponse // complete and delete
 }
 
 func (m *MockOmniFocusService) CreateTask(ctx context.Context, req services.TaskCreateRequest) services.TaskCreateResponse {
@@ -21,9 +27,59 @@ func (m *MockOmniFocusService) CreateTask(ctx context.Context, req services.Task
 	}
 }
 
+// CreateTasks falls back to calling CreateTask for each request when CreateTasksFunc is unset
+func (m *MockOmniFocusService) CreateTasks(ctx context.Context, reqs []services.TaskCreateRequest) []services.TaskCreateResponse {
+	if m.CreateTasksFunc != nil {
+		return m.CreateTasksFunc(ctx, reqs)
+	}
+	responses := make([]services.TaskCreateResponse, len(reqs))
+	for i, req := range reqs {
+		responses[i] = m.CreateTask(ctx, req)
+	}
+	return responses
+}
+
+func (m *MockOmniFocusService) ListTasks(ctx context.Context, query services.TaskQuery) services.TaskListResponse {
+	if m.ListTasksFunc != nil {
+		return m.ListTasksFunc(ctx, query)
+	}
+	return services.TaskListResponse{Status: "ok"}
+}
+
+func (m *MockOmniFocusService) GetTask(ctx context.Context, id string) services.TaskGetResponse {
+	if m.GetTaskFunc != nil {
+		return m.GetTaskFunc(ctx, id)
+	}
+	return services.TaskGetResponse{Status: "ok"}
+}
+
+func (m *MockOmniFocusService) UpdateTask(ctx context.Context, id string, req services.TaskUpdateRequest) services.TaskActionResponse {
+	if m.UpdateTaskFunc != nil {
+		return m.UpdateTaskFunc(ctx, id, req)
+	}
+	return services.TaskActionResponse{Status: "ok", Found: true}
+}
+
+func (m *MockOmniFocusService) CompleteTask(ctx context.Context, id string) services.TaskActionResponse {
+	if m.ActionFunc != nil {
+		return m.ActionFunc(ctx, "complete", id)
+	}
+	return services.TaskActionResponse{Status: "ok", Found: true}
+}
+
+func (m *MockOmniFocusService) DeleteTask(ctx context.Context, id string) services.TaskActionResponse {
+	if m.ActionFunc != nil {
+		return m.ActionFunc(ctx, "delete", id)
+	}
+	return services.TaskActionResponse{Status: "ok", Found: true}
+}
+
 // MockFilesService provides a mock implementation for testing
 type MockFilesService struct {
-	WriteFileFunc func(ctx context.Context, req services.FileWriteRequest) services.FileWriteResponse
+	WriteFileFunc  func(ctx context.Context, req services.FileWriteRequest) services.FileWriteResponse
+	ListFilesFunc  func(ctx context.Context, directory string) services.FileListResponse
+	OpenFileFunc   func(ctx context.Context, path string, hash bool) services.FileOpenResponse
+	DeleteFileFunc func(ctx context.Context, path string) services.FileDeleteResponse
 }
 
 func (m *MockFilesService) WriteFile(ctx context.Context, req services.FileWriteRequest) services.FileWriteResponse {
@@ -36,3 +92,75 @@ func (m *MockFilesService) WriteFile(ctx context.Context, req services.FileWrite
 		Path:    req.Filename,
 	}
 }
+
+func (m *MockFilesService) ListFiles(ctx context.Context, directory string) services.FileListResponse {
+	if m.ListFilesFunc != nil {
+		return m.ListFilesFunc(ctx, directory)
+	}
+	return services.FileListResponse{Status: "ok", Directory: directory, Entries: []services.FileEntry{}}
+}
+
+func (m *MockFilesService) OpenFile(ctx context.Context, path string, hash bool) services.FileOpenResponse {
+	if m.OpenFileFunc != nil {
+		return m.OpenFileFunc(ctx, path, hash)
+	}
+	return services.FileOpenResponse{Status: "error", Reason: "file not found", ErrorKind: "not_found"}
+}
+
+func (m *MockFilesService) DeleteFile(ctx context.Context, path string) services.FileDeleteResponse {
+	if m.DeleteFileFunc != nil {
+		return m.DeleteFileFunc(ctx, path)
+	}
+	return services.FileDeleteResponse{Status: "ok", Path: path}
+}
+
+// MockTaskQueue provides a mock implementation for testing
+type MockTaskQueue struct {
+	EnqueueFunc func(ctx context.Context, req services.TaskCreateRequest) (string, error)
+	Enqueued    []services.TaskCreateRequest
+}
+
+func (m *MockTaskQueue) Enqueue(ctx context.Context, req services.TaskCreateRequest) (string, error) {
+	m.Enqueued = append(m.Enqueued, req)
+	if m.EnqueueFunc != nil {
+		return m.EnqueueFunc(ctx, req)
+	}
+	return "queued-task-id", nil
+}
+
+// Ensure MockHealthService implements services.HealthService
+var _ services.HealthService = (*MockHealthService)(nil)
+
+// MockHealthService is a HealthService returning a fixed result and counting checks
+type MockHealthService struct {
+	mu     sync.Mutex
+	Result services.HealthResult
+	Checks int
+}
+
+func (m *MockHealthService) CheckAppleScriptHealth() services.HealthResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Checks++
+	return m.Result
+}
+
+// CheckCount returns the number of CheckAppleScriptHealth calls
+func (m *MockHealthService) CheckCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Checks
+}
+
+func (m *MockHealthService) CheckOmniFocusStatus() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Result.OmniFocusRunning
+}
+
+// NewTaskBackends wraps a mock backend in a registry as the default "omnifocus" backend
+func NewTaskBackends(backend services.TaskBackend) *services.TaskBackendRegistry {
+	registry := services.NewTaskBackendRegistry(services.BackendOmniFocus)
+	registry.Register(services.BackendOmniFocus, backend)
+	return registry
+}